@@ -0,0 +1,158 @@
+package nekomimi
+
+import (
+	"io"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SamplingReporter is implemented by handlers that sample log messages
+type SamplingReporter interface {
+	// SamplingRatio returns the current ratio of the regular log messages
+	// kept, 1 means all messages are kept
+	SamplingRatio() float64
+}
+
+// adaptiveHandler is the LogHandler returned by NewAdaptiveLimitLogHandler
+type adaptiveHandler struct {
+	wrap   LogHandler
+	target int
+	clock  Clock
+
+	mtx    sync.Mutex
+	window time.Time // start of the measuring window
+	count  int       // messages received in the window
+	ratio  float64
+	credit [ERROR - TRACE]float64 // sampling credit of each level from TRACE
+
+	skipped atomic.Uint64
+}
+
+// NewAdaptiveLimitLogHandler creates a LogHandler which collapses log
+// storms. the rate of the incoming regular log messages is measured every
+// second, if it exceeds targetPerSec the sampling ratio is lowered to bring
+// the rate back to the target, otherwise the ratio is doubled until all
+// messages are kept again.
+//
+// lower levels are dropped first: WARN keeps 4 times and INFO keeps 2 times
+// the ratio of DEBUG and TRACE. ERROR and above always pass. the dropped
+// messages are counted, see DropCounter. the current ratio is reported by
+// SamplingReporter.
+func NewAdaptiveLimitLogHandler(wrap LogHandler, targetPerSec int) LogHandler {
+	return &adaptiveHandler{
+		wrap:   wrap,
+		target: max(targetPerSec, 1),
+		clock:  RealClock,
+		ratio:  1,
+	}
+}
+
+// levelRatio returns the sampling ratio of the level
+func levelRatio(level LogLevel, ratio float64) float64 {
+	switch level {
+	case WARN:
+		return min(ratio*4, 1)
+	case INFO:
+		return min(ratio*2, 1)
+	default:
+		return ratio
+	}
+}
+
+// measure counts a message in the window, adjusts the ratio when the window
+// expires. must be called with mtx held.
+func (a *adaptiveHandler) measure() {
+	now := a.clock.Now()
+	if a.window.IsZero() {
+		a.window = now
+	}
+	if elapsed := now.Sub(a.window); elapsed >= time.Second {
+		rate := float64(a.count) / elapsed.Seconds()
+		if rate > float64(a.target) {
+			a.ratio = float64(a.target) / rate
+		} else {
+			a.ratio = min(a.ratio*2, 1)
+		}
+		a.window = now
+		a.count = 0
+	}
+	a.count++
+}
+
+// allow reports whether a message of the level should be kept. messages are
+// kept evenly by accumulating the ratio as credit.
+func (a *adaptiveHandler) allow(level LogLevel) bool {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.measure()
+	if level >= ERROR {
+		return true
+	}
+	r := levelRatio(level, a.ratio)
+	if r >= 1 {
+		return true
+	}
+	i := level - TRACE
+	a.credit[i] = math.Min(a.credit[i]+r, 1)
+	if a.credit[i] < 1 {
+		return false
+	}
+	a.credit[i]--
+	return true
+}
+
+// SamplingRatio returns the current ratio of DEBUG and TRACE messages kept
+func (a *adaptiveHandler) SamplingRatio() float64 {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	return a.ratio
+}
+
+// Dropped returns the number of messages dropped by sampling
+func (a *adaptiveHandler) Dropped() uint64 {
+	return a.skipped.Load()
+}
+
+func (a *adaptiveHandler) IsShutdown() bool {
+	return a.wrap.IsShutdown()
+}
+
+func (a *adaptiveHandler) RegularWriter(
+	level LogLevel, pnt func(io.StringWriter),
+) {
+	if !a.allow(level) {
+		a.skipped.Add(1)
+		return
+	}
+	a.wrap.RegularWriter(level, pnt)
+}
+
+func (a *adaptiveHandler) RegularLog(
+	level LogLevel, header string, message ...any,
+) {
+	if !a.allow(level) {
+		a.skipped.Add(1)
+		return
+	}
+	a.wrap.RegularLog(level, header, message...)
+}
+
+func (a *adaptiveHandler) PanicLog(header string, message ...any) {
+	a.wrap.PanicLog(header, message...)
+}
+
+func (a *adaptiveHandler) FatalLog(header string, message ...any) {
+	a.wrap.FatalLog(header, message...)
+}
+
+// Flush flushes the wrapped handler
+func (a *adaptiveHandler) Flush() error {
+	return flushHandler(a.wrap)
+}
+
+// Close closes the wrapped handler
+func (a *adaptiveHandler) Close() error {
+	return closeHandler(a.wrap)
+}
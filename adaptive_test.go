@@ -0,0 +1,83 @@
+package nekomimi
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAdaptiveLimit(t *testing.T) {
+	Convey("Adaptive limit tests", t, func() {
+		sink := &captureLogHandler{}
+		clock := NewMockClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+		h := NewAdaptiveLimitLogHandler(sink.handler(), 100)
+		h.(*adaptiveHandler).clock = clock
+		sr := h.(SamplingReporter)
+		dc := h.(DropCounter)
+
+		// burst emits n messages of the level evenly within one second
+		burst := func(level LogLevel, n int) {
+			step := time.Second / time.Duration(n)
+			for range n {
+				h.RegularLog(level, "h - ", "msg")
+				clock.Advance(step)
+			}
+		}
+
+		Convey("Below the target everything passes", func() {
+			burst(DEBUG, 50)
+			burst(DEBUG, 50)
+			So(sr.SamplingRatio(), ShouldEqual, 1)
+			So(sink.count(), ShouldEqual, 100)
+			So(dc.Dropped(), ShouldEqual, 0)
+		})
+
+		Convey("Ratio adapts to a burst and relaxes", func() {
+			burst(DEBUG, 1000)
+			burst(DEBUG, 1000) // ratio is lowered at the first message
+			So(sr.SamplingRatio(), ShouldAlmostEqual, 0.1, 0.01)
+			sink.reset()
+			burst(DEBUG, 1000)
+			So(sink.count(), ShouldBeBetweenOrEqual, 90, 110)
+			So(dc.Dropped(), ShouldBeGreaterThan, 1500)
+
+			// lower severities are dropped first, ERROR always passes
+			sink.reset()
+			burst(INFO, 1000)
+			So(sink.count(), ShouldBeBetweenOrEqual, 190, 210)
+			sink.reset()
+			for range 500 {
+				h.RegularLog(ERROR, "h - ", "msg")
+				h.RegularLog(WARN, "h - ", "msg")
+				clock.Advance(time.Millisecond)
+			}
+			errors := 0
+			for _, lv := range sink.levels {
+				if lv == ERROR {
+					errors++
+				}
+			}
+			So(errors, ShouldEqual, 500)
+			So(sink.count(), ShouldBeBetween, 500, 1000)
+
+			// storm subsides, ratio doubles each second back to 1
+			for range 5 {
+				burst(DEBUG, 10)
+			}
+			So(sr.SamplingRatio(), ShouldEqual, 1)
+			sink.reset()
+			burst(DEBUG, 10)
+			So(sink.count(), ShouldEqual, 10)
+		})
+
+		Convey("Panic and fatal are forwarded", func() {
+			burst(DEBUG, 1000)
+			burst(DEBUG, 1000)
+			sink.reset()
+			h.PanicLog("h - ", "boom")
+			h.FatalLog("h - ", "bye")
+			So(sink.count(), ShouldEqual, 2)
+		})
+	})
+}
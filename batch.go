@@ -0,0 +1,66 @@
+package nekomimi
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// LogBatch accumulates message fragments and emits them as a single log
+// record on Commit, so the fragments are not interleaved with other log
+// messages. it's safe for concurrent use.
+//
+// the batch of a disabled level is a no-op.
+type LogBatch struct {
+	mtx       sync.Mutex
+	parent    *logger
+	level     LogLevel
+	fragments []string
+}
+
+// Batch creates a LogBatch for the given level. returns a no-op batch if
+// the level is not enabled.
+func (l *logger) Batch(level LogLevel) *LogBatch {
+	if !l.enabled(level) {
+		return &LogBatch{}
+	}
+	return &LogBatch{parent: l, level: level}
+}
+
+// Add appends a fragment to the batch. the message arguments are formatted
+// like Inf, fragments are joined with a space.
+func (b *LogBatch) Add(message ...any) {
+	if b.parent == nil {
+		return
+	}
+	s := strings.TrimSuffix(fmt.Sprintln(b.parent.args(message)...), "\n")
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.fragments = append(b.fragments, s)
+}
+
+// Commit emits the added fragments as one log record, then resets the
+// batch. nothing is emitted if no fragment was added. the call trace
+// reports the caller of Commit. committing a PANIC or FATAL batch raises
+// panic or terminates the program like Panic and Fatal.
+func (b *LogBatch) Commit() {
+	if b.parent == nil {
+		return
+	}
+	b.mtx.Lock()
+	fragments := b.fragments
+	b.fragments = nil
+	b.mtx.Unlock()
+	if len(fragments) == 0 {
+		return
+	}
+	msg := strings.Join(fragments, " ")
+	switch {
+	case b.level == PANIC:
+		b.parent.outputPanicLog(msg)
+	case b.level >= FATAL:
+		b.parent.outputFatalLog(msg)
+	default:
+		b.parent.outputRegularLog(b.level, msg)
+	}
+}
@@ -0,0 +1,53 @@
+package nekomimi
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLogBatch(t *testing.T) {
+	Convey("LogBatch tests", t, func() {
+		rec := &captureLogHandler{}
+		l := New("Test", LogConfig{
+			Handler:        rec.handler(),
+			LevelWithTrace: ERROR,
+		})
+
+		Convey("Fragments are emitted as a single record", func() {
+			b := l.Batch(INFO)
+			b.Add("progress:", 1)
+			b.Add("step", "two")
+			b.Add(3)
+			So(rec.count(), ShouldEqual, 0)
+			b.Commit()
+			So(rec.count(), ShouldEqual, 1)
+			So(rec.last(), ShouldEndWith,
+				"[INFO], Test - progress: 1 step two 3\n")
+			// batch is reset after commit
+			b.Commit()
+			So(rec.count(), ShouldEqual, 1)
+			b.Add("again")
+			b.Commit()
+			So(rec.count(), ShouldEqual, 2)
+			So(rec.last(), ShouldEndWith, "[INFO], Test - again\n")
+		})
+
+		Convey("Disabled level returns a no-op batch", func() {
+			l.SetLevel(WARN)
+			b := l.Batch(INFO)
+			So(b, ShouldNotBeNil)
+			b.Add("dropped")
+			b.Commit()
+			So(rec.count(), ShouldEqual, 0)
+		})
+
+		Convey("Call trace reports the caller of Commit", func() {
+			b := l.Batch(ERROR)
+			b.Add("failed")
+			b.Commit()
+			So(rec.last(), ShouldContainSubstring, "batch_test.go:")
+			So(rec.last(), ShouldContainSubstring, "TestLogBatch")
+		})
+	})
+}
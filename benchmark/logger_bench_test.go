@@ -0,0 +1,47 @@
+package benchmark_test
+
+import (
+	"io"
+	"testing"
+
+	"github.com/fiathux/nekomimi"
+)
+
+// discardHandler formats the log line and drops it
+var discardHandler = nekomimi.TinyLogHandlerFunc(
+	func(level nekomimi.LogLevel, pnt func(io.StringWriter)) {
+		pnt(discardWriter{})
+	})
+
+// discardWriter is an io.StringWriter which drops everything
+type discardWriter struct{}
+
+func (discardWriter) WriteString(s string) (int, error) {
+	return len(s), nil
+}
+
+// benchCallTrace logs from one hot call site with call trace enabled
+func benchCallTrace(b *testing.B, cache bool) {
+	logger := nekomimi.New("Bench", nekomimi.LogConfig{
+		Handler:        discardHandler,
+		LevelWithTrace: nekomimi.DEBUG,
+		CallerCache:    cache,
+	})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Inf("hot call site")
+	}
+}
+
+// BenchmarkLogger_CallTrace measures a call-trace-enabled call site
+// resolving the caller on every call.
+func BenchmarkLogger_CallTrace(b *testing.B) {
+	benchCallTrace(b, false)
+}
+
+// BenchmarkLogger_CallTrace_Cached measures the same call site with the
+// caller cache, only the PC is captured on every call.
+func BenchmarkLogger_CallTrace_Cached(b *testing.B) {
+	benchCallTrace(b, true)
+}
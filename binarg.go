@@ -0,0 +1,104 @@
+package nekomimi
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// BinaryEncoding selects how []byte message arguments are rendered, see
+// LogConfig.BinaryEncoding
+type BinaryEncoding int
+
+const (
+	// BinaryDefault renders []byte by fmt, as a decimal byte slice
+	BinaryDefault BinaryEncoding = iota
+	// BinaryHexPreview renders []byte as a hex preview of the first bytes
+	// in the console output, e.g. `0x0a1b2c...(40 bytes)`. structured
+	// outputs (RecordLogHandlerFunc) receive the full value in base64.
+	BinaryHexPreview
+	// BinaryBase64 renders []byte in standard base64 everywhere
+	BinaryBase64
+)
+
+// binaryPreviewBytes is the number of bytes shown in the hex preview
+const binaryPreviewBytes = 16
+
+// binaryArg wraps a []byte message argument to render it by the encoding
+type binaryArg struct {
+	data []byte
+	enc  BinaryEncoding
+}
+
+// Format renders the bytes by the encoding for the verbs 'v' and 's',
+// other verbs (e.g. %x) are passed through
+func (ba binaryArg) Format(f fmt.State, verb rune) {
+	switch verb {
+	case 'v', 's':
+		if ba.enc == BinaryBase64 {
+			f.Write([]byte(ba.base64()))
+			return
+		}
+		f.Write([]byte(ba.hexPreview()))
+	default:
+		fmt.Fprintf(f, fmt.FormatString(f, verb), ba.data)
+	}
+}
+
+// base64 returns the data in standard base64
+func (ba binaryArg) base64() string {
+	return base64.StdEncoding.EncodeToString(ba.data)
+}
+
+// hexPreview returns the hex form of the data, long data is truncated with
+// the total length
+func (ba binaryArg) hexPreview() string {
+	if len(ba.data) <= binaryPreviewBytes {
+		return "0x" + hex.EncodeToString(ba.data)
+	}
+	return fmt.Sprintf("0x%s...(%d bytes)",
+		hex.EncodeToString(ba.data[:binaryPreviewBytes]), len(ba.data))
+}
+
+// binaryArgs returns a copy of args with the []byte values wrapped as
+// binaryArg. args is returned as is if there is no []byte.
+func binaryArgs(args []any, enc BinaryEncoding) []any {
+	var out []any
+	for i, a := range args {
+		data, ok := a.([]byte)
+		if !ok {
+			continue
+		}
+		if out == nil {
+			out = make([]any, len(args))
+			copy(out, args)
+		}
+		out[i] = binaryArg{data: data, enc: enc}
+	}
+	if out == nil {
+		return args
+	}
+	return out
+}
+
+// structuredBinaryArgs replaces the binaryArg values with their base64
+// form for structured outputs. args is returned as is if there is no
+// binaryArg.
+func structuredBinaryArgs(args []any) []any {
+	var out []any
+	for i, a := range args {
+		ba, ok := a.(binaryArg)
+		if !ok {
+			continue
+		}
+		if out == nil {
+			out = make([]any, len(args))
+			copy(out, args)
+		}
+		out[i] = ba.base64()
+	}
+	if out == nil {
+		return args
+	}
+	return out
+}
@@ -0,0 +1,74 @@
+package nekomimi
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBinaryEncoding(t *testing.T) {
+	Convey("Binary encoding tests", t, func() {
+		sig := bytes.Repeat([]byte{0xde, 0xad, 0xbe, 0xef}, 10)
+
+		Convey("Default renders by fmt", func() {
+			rec := &captureLogHandler{}
+			l := New("Test", LogConfig{
+				Handler:        rec.handler(),
+				LevelWithTrace: FATAL,
+			})
+			l.Inf("sig:", []byte{1, 2, 3})
+			So(rec.last(), ShouldEndWith, "sig: [1 2 3]\n")
+		})
+
+		Convey("Hex preview in console output", func() {
+			rec := &captureLogHandler{}
+			l := New("Test", LogConfig{
+				Handler:        rec.handler(),
+				LevelWithTrace: FATAL,
+				BinaryEncoding: BinaryHexPreview,
+			})
+			l.Inf("sig:", sig)
+			So(rec.last(), ShouldEndWith, "sig: 0x"+
+				strings.Repeat("deadbeef", 4)+"...(40 bytes)\n")
+			l.Inf("short:", []byte{0x0a, 0x1b})
+			So(rec.last(), ShouldEndWith, "short: 0x0a1b\n")
+			l.Inff("sig: %s, hex: %x", []byte{1}, []byte{0xff})
+			So(rec.last(), ShouldEndWith, "sig: 0x01, hex: ff\n")
+		})
+
+		Convey("Base64 round-trips in JSON output", func() {
+			var value []byte
+			producer := func(key, v []byte) error {
+				value = v
+				return nil
+			}
+			for _, enc := range []BinaryEncoding{BinaryHexPreview, BinaryBase64} {
+				l := New("Test", LogConfig{
+					Handler:        NewKafkaLogHandler(producer, nil),
+					BinaryEncoding: enc,
+				})
+				l.Inf(sig)
+				out := map[string]any{}
+				So(json.Unmarshal(value, &out), ShouldBeNil)
+				data, err := base64.StdEncoding.DecodeString(out["msg"].(string))
+				So(err, ShouldBeNil)
+				So(data, ShouldResemble, sig)
+			}
+		})
+
+		Convey("Base64 in console output", func() {
+			rec := &captureLogHandler{}
+			l := New("Test", LogConfig{
+				Handler:        rec.handler(),
+				LevelWithTrace: FATAL,
+				BinaryEncoding: BinaryBase64,
+			})
+			l.Derive("sub").Trace("req").Inf("sig:", []byte("hello"))
+			So(rec.last(), ShouldEndWith, "sig: aGVsbG8=\n")
+		})
+	})
+}
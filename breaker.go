@@ -0,0 +1,173 @@
+package nekomimi
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// breakerHandler is the LogHandler returned by NewCircuitBreakerLogHandler
+type breakerHandler struct {
+	wrap      LogHandler
+	threshold int
+	cooldown  time.Duration
+
+	mtx       sync.Mutex
+	failures  int       // consecutive failures
+	openUntil time.Time // circuit is open before this time
+
+	probing atomic.Bool // a probe is in flight while the circuit is open
+	skipped atomic.Uint64
+}
+
+// NewCircuitBreakerLogHandler creates a LogHandler protecting the hot path
+// from a consistently failing sink. after threshold consecutive write
+// failures of the wrapped handler, the circuit opens and regular log
+// messages are skipped (and counted, see DropCounter) until cooldown
+// elapses. then the next message is sent as a probe, the circuit is closed
+// again if it succeeds, otherwise it stays open for another cooldown. the
+// other messages are skipped while the probe is in flight, so only a single
+// concurrent caller reaches the failing sink.
+//
+// a write is considered failed when the StringWriter given by the wrapped
+// handler returns error, or when the Dropped() counter of the wrapped
+// handler (if it implements DropCounter) increases during the write.
+//
+// regular log messages are forwarded to the wrapped handler by
+// RegularWriter, as a Wrapper receives them. panic and fatal messages,
+// including those received by RegularWriter as a Wrapper, are always
+// forwarded and never counted as failure.
+func NewCircuitBreakerLogHandler(
+	wrap LogHandler, threshold int, cooldown time.Duration,
+) LogHandler {
+	return &breakerHandler{
+		wrap:      wrap,
+		threshold: max(threshold, 1),
+		cooldown:  cooldown,
+	}
+}
+
+// errorWriter records the first error returned by the underlying writer
+type errorWriter struct {
+	w   io.StringWriter
+	err error
+}
+
+func (ew *errorWriter) WriteString(s string) (int, error) {
+	n, err := ew.w.WriteString(s)
+	if err != nil && ew.err == nil {
+		ew.err = err
+	}
+	return n, err
+}
+
+// allow reports whether a message should be sent to the wrapped handler,
+// and whether it's the probe of an open circuit
+func (b *breakerHandler) allow() (ok, probe bool) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if b.failures < b.threshold {
+		return true, false
+	}
+	if time.Now().Before(b.openUntil) ||
+		!b.probing.CompareAndSwap(false, true) {
+		return false, false
+	}
+	return true, true
+}
+
+// report records the result of a write, opens the circuit when the failure
+// threshold is reached. the result of a probe lets the next probe in.
+func (b *breakerHandler) report(failed, probe bool) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if probe {
+		defer b.probing.Store(false)
+	}
+	if !failed {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
+
+// dropped returns the drop counter of the wrapped handler, if any
+func (b *breakerHandler) dropped() (uint64, bool) {
+	if dc, ok := b.wrap.(DropCounter); ok {
+		return dc.Dropped(), true
+	}
+	return 0, false
+}
+
+// Dropped returns the number of messages skipped while the circuit is open
+func (b *breakerHandler) Dropped() uint64 {
+	return b.skipped.Load()
+}
+
+func (b *breakerHandler) IsShutdown() bool {
+	return b.wrap.IsShutdown()
+}
+
+func (b *breakerHandler) RegularWriter(
+	level LogLevel, pnt func(io.StringWriter),
+) {
+	if level >= PANIC {
+		b.wrap.RegularWriter(level, pnt)
+		return
+	}
+	ok, probe := b.allow()
+	if !ok {
+		b.skipped.Add(1)
+		return
+	}
+	// a write which doesn't return, e.g. the wrapped handler panics, is a
+	// failure, the result is reported anyway to let the next probe in
+	failed := true
+	defer func() { b.report(failed, probe) }()
+	d0, hasdc := b.dropped()
+	var werr error
+	b.wrap.RegularWriter(level, func(w io.StringWriter) {
+		ew := &errorWriter{w: w}
+		pnt(ew)
+		if ew.err != nil {
+			werr = ew.err
+		}
+	})
+	failed = werr != nil
+	if d1, _ := b.dropped(); hasdc && d1 > d0 {
+		failed = true
+	}
+}
+
+func (b *breakerHandler) RegularLog(
+	level LogLevel, header string, message ...any,
+) {
+	sp := fmt.Sprintln(message...)
+	b.RegularWriter(level, func(w io.StringWriter) {
+		w.WriteString(header)
+		w.WriteString(sp)
+	})
+}
+
+func (b *breakerHandler) PanicLog(header string, message ...any) {
+	b.wrap.PanicLog(header, message...)
+}
+
+func (b *breakerHandler) FatalLog(header string, message ...any) {
+	b.wrap.FatalLog(header, message...)
+}
+
+// Flush flushes the wrapped handler, even if the circuit is open
+func (b *breakerHandler) Flush() error {
+	return flushHandler(b.wrap)
+}
+
+// Close closes the wrapped handler
+func (b *breakerHandler) Close() error {
+	return closeHandler(b.wrap)
+}
@@ -0,0 +1,214 @@
+package nekomimi
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// failingWriter fails all writes while fail is set
+type failingWriter struct {
+	fail atomic.Bool
+	sb   strings.Builder
+}
+
+func (fw *failingWriter) WriteString(s string) (int, error) {
+	if fw.fail.Load() {
+		return 0, fmt.Errorf("sink down")
+	}
+	return fw.sb.WriteString(s)
+}
+
+// dropSink counts a drop for each message while fail is set
+type dropSink struct {
+	TinyLogHandlerFunc
+	fail    atomic.Bool
+	dropped atomic.Uint64
+	sent    atomic.Uint64
+}
+
+func (ds *dropSink) Dropped() uint64 {
+	return ds.dropped.Load()
+}
+
+func newDropSink() *dropSink {
+	ds := &dropSink{}
+	ds.TinyLogHandlerFunc = func(level LogLevel, pnt func(io.StringWriter)) {
+		if ds.fail.Load() {
+			ds.dropped.Add(1)
+			return
+		}
+		ds.sent.Add(1)
+	}
+	return ds
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	Convey("Circuit breaker tests", t, func() {
+		Convey("Opens on write errors and recovers after cooldown", func() {
+			fw := &failingWriter{}
+			calls := 0
+			sink := TinyLogHandlerFunc(
+				func(level LogLevel, pnt func(io.StringWriter)) {
+					calls++
+					pnt(fw)
+				})
+			h := NewCircuitBreakerLogHandler(sink, 3, 50*time.Millisecond)
+			l := New("Test", LogConfig{Handler: h})
+
+			l.Inf("before outage")
+			So(fw.sb.String(), ShouldContainSubstring, "before outage")
+
+			fw.fail.Store(true)
+			for i := range 10 {
+				l.Inf("during outage", i)
+			}
+			// only the failures up to threshold reach the sink
+			So(calls, ShouldEqual, 4)
+			So(h.(DropCounter).Dropped(), ShouldEqual, 7)
+
+			// probe after cooldown fails, circuit stays open
+			time.Sleep(60 * time.Millisecond)
+			l.Inf("probe")
+			l.Inf("skipped")
+			So(calls, ShouldEqual, 5)
+			So(h.(DropCounter).Dropped(), ShouldEqual, 8)
+
+			// sink recovered, probe closes the circuit
+			fw.fail.Store(false)
+			time.Sleep(60 * time.Millisecond)
+			l.Inf("recovered")
+			l.Inf("after recovery")
+			So(calls, ShouldEqual, 7)
+			So(fw.sb.String(), ShouldContainSubstring, "after recovery")
+		})
+
+		Convey("Half-open circuit lets a single probe through", func() {
+			fw := &failingWriter{}
+			fw.fail.Store(true)
+			var calls atomic.Int32
+			entered := make(chan struct{})
+			release := make(chan struct{})
+			sink := TinyLogHandlerFunc(
+				func(level LogLevel, pnt func(io.StringWriter)) {
+					if calls.Add(1) == 2 {
+						close(entered)
+						<-release
+					}
+					pnt(fw)
+				})
+			h := NewCircuitBreakerLogHandler(sink, 1, 20*time.Millisecond)
+			h.RegularLog(INFO, "header - ", "open")
+			time.Sleep(30 * time.Millisecond)
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				h.RegularLog(INFO, "header - ", "probe")
+			}()
+			<-entered
+			var wg sync.WaitGroup
+			for range 10 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					h.RegularLog(INFO, "header - ", "concurrent")
+				}()
+			}
+			wg.Wait()
+			So(calls.Load(), ShouldEqual, 2)
+			So(h.(DropCounter).Dropped(), ShouldEqual, 10)
+
+			// the probe succeeds and closes the circuit
+			fw.fail.Store(false)
+			close(release)
+			<-done
+			h.RegularLog(INFO, "header - ", "after probe")
+			So(calls.Load(), ShouldEqual, 3)
+			So(fw.sb.String(), ShouldEqual,
+				"header - probe\nheader - after probe\n")
+		})
+
+		Convey("A panicking probe lets the next probe in", func() {
+			fw := &failingWriter{}
+			fw.fail.Store(true)
+			var panics atomic.Bool
+			sink := TinyLogHandlerFunc(
+				func(level LogLevel, pnt func(io.StringWriter)) {
+					if panics.Load() {
+						panic("sink crashed")
+					}
+					pnt(fw)
+				})
+			h := NewCircuitBreakerLogHandler(sink, 1, 20*time.Millisecond)
+			h.RegularLog(INFO, "header - ", "open")
+			time.Sleep(30 * time.Millisecond)
+			panics.Store(true)
+			So(func() { h.RegularLog(INFO, "header - ", "probe") },
+				ShouldPanicWith, "sink crashed")
+
+			// the panic opened the circuit again
+			panics.Store(false)
+			fw.fail.Store(false)
+			h.RegularLog(INFO, "header - ", "skipped")
+			So(h.(DropCounter).Dropped(), ShouldEqual, 1)
+			time.Sleep(30 * time.Millisecond)
+			h.RegularLog(INFO, "header - ", "recovered")
+			So(fw.sb.String(), ShouldEqual, "header - recovered\n")
+		})
+
+		Convey("Panic is forwarded by RegularWriter while open", func() {
+			ds := newDropSink()
+			h := NewCircuitBreakerLogHandler(ds, 1, time.Hour)
+			ds.fail.Store(true)
+			h.RegularLog(INFO, "header - ", "open")
+			ds.fail.Store(false)
+			h.RegularWriter(PANIC, func(w io.StringWriter) {
+				w.WriteString("header - panic\n")
+			})
+			h.RegularLog(INFO, "header - ", "skipped")
+			So(ds.sent.Load(), ShouldEqual, 1)
+			So(h.(DropCounter).Dropped(), ShouldEqual, 1)
+		})
+
+		Convey("Detects failures by drop counter", func() {
+			ds := newDropSink()
+			h := NewCircuitBreakerLogHandler(ds, 2, time.Hour)
+			ds.fail.Store(true)
+			for range 5 {
+				h.RegularLog(INFO, "header - ", "message")
+			}
+			So(ds.Dropped(), ShouldEqual, 2)
+			So(h.(DropCounter).Dropped(), ShouldEqual, 3)
+		})
+
+		Convey("Success resets consecutive failures", func() {
+			ds := newDropSink()
+			h := NewCircuitBreakerLogHandler(ds, 2, time.Hour)
+			for range 3 {
+				ds.fail.Store(true)
+				h.RegularLog(INFO, "header - ", "failed")
+				ds.fail.Store(false)
+				h.RegularLog(INFO, "header - ", "sent")
+			}
+			So(ds.sent.Load(), ShouldEqual, 3)
+			So(h.(DropCounter).Dropped(), ShouldEqual, 0)
+		})
+
+		Convey("Panic is always forwarded", func() {
+			ds := newDropSink()
+			h := NewCircuitBreakerLogHandler(ds, 1, time.Hour)
+			ds.fail.Store(true)
+			h.RegularLog(INFO, "header - ", "open")
+			ds.fail.Store(false)
+			h.PanicLog("header - ", "panic")
+			So(ds.sent.Load(), ShouldEqual, 1)
+		})
+	})
+}
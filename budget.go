@@ -0,0 +1,128 @@
+package nekomimi
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// budgetHandler is the LogHandler returned by NewBudgetLogHandler
+type budgetHandler struct {
+	wrap      LogHandler
+	budget    int64
+	interval  time.Duration
+	onOverrun func(over int64)
+	clock     Clock
+
+	start   atomic.Int64  // UnixNano of the start of the current interval
+	bytes   atomic.Int64  // bytes emitted in the current interval
+	overrun atomic.Bool   // onOverrun was called in the current interval
+	total   atomic.Uint64 // bytes emitted since the creation
+}
+
+// NewBudgetLogHandler creates a LogHandler which counts the bytes of the
+// log messages forwarded to wrapped, and calls onOverrun once per interval
+// when the bytes emitted in the interval exceed budget, e.g. to shed the
+// verbosity under paid log ingestion. over is the number of bytes above
+// the budget, including the message which crossed it. the intervals are
+// fixed, starting at the first message.
+//
+// the messages are always forwarded, onOverrun is called synchronously
+// after the crossing message, so it should be quick and must not log
+// through the same handler. the total is reported by EmittedBytes.
+func NewBudgetLogHandler(
+	wrapped LogHandler, budget int64, interval time.Duration,
+	onOverrun func(over int64),
+) LogHandler {
+	return &budgetHandler{
+		wrap:      wrapped,
+		budget:    budget,
+		interval:  interval,
+		onOverrun: onOverrun,
+		clock:     RealClock,
+	}
+}
+
+// EmittedBytes returns the number of bytes emitted since the handler was
+// created
+func (bh *budgetHandler) EmittedBytes() uint64 {
+	return bh.total.Load()
+}
+
+// account adds n bytes to the current interval, and calls onOverrun if the
+// budget is exceeded for the first time in the interval
+func (bh *budgetHandler) account(n int) {
+	bh.total.Add(uint64(n))
+	now := bh.clock.Now().UnixNano()
+	if start := bh.start.Load(); start == 0 ||
+		now-start >= int64(bh.interval) {
+		if bh.start.CompareAndSwap(start, now) {
+			bh.bytes.Store(0)
+			bh.overrun.Store(false)
+		}
+	}
+	used := bh.bytes.Add(int64(n))
+	if used > bh.budget && bh.onOverrun != nil &&
+		bh.overrun.CompareAndSwap(false, true) {
+		bh.onOverrun(used - bh.budget)
+	}
+}
+
+// countingWriter counts the bytes written to the StringWriter
+type countingWriter struct {
+	w io.StringWriter
+	n int
+}
+
+func (cw *countingWriter) WriteString(s string) (int, error) {
+	n, err := cw.w.WriteString(s)
+	cw.n += n
+	return n, err
+}
+
+func (bh *budgetHandler) IsShutdown() bool {
+	return bh.wrap.IsShutdown()
+}
+
+func (bh *budgetHandler) RegularWriter(
+	level LogLevel, pnt func(io.StringWriter),
+) {
+	n := 0
+	bh.wrap.RegularWriter(level, func(w io.StringWriter) {
+		cw := &countingWriter{w: w}
+		pnt(cw)
+		n += cw.n
+	})
+	bh.account(n)
+}
+
+func (bh *budgetHandler) RegularLog(
+	level LogLevel, header string, message ...any,
+) {
+	sp := fmt.Sprintln(message...)
+	bh.RegularWriter(level, func(w io.StringWriter) {
+		w.WriteString(header)
+		w.WriteString(sp)
+	})
+}
+
+func (bh *budgetHandler) PanicLog(header string, message ...any) {
+	bh.account(len(header) + len(fmt.Sprintln(message...)))
+	bh.wrap.PanicLog(header, message...)
+}
+
+func (bh *budgetHandler) FatalLog(header string, message ...any) {
+	bh.account(len(header) + len(fmt.Sprintln(message...)))
+	bh.wrap.FatalLog(header, message...)
+}
+
+// Flush flushes the wrapped handler
+func (bh *budgetHandler) Flush() error {
+	return flushHandler(bh.wrap)
+}
+
+// Close closes the wrapped handler
+func (bh *budgetHandler) Close() error {
+	return closeHandler(bh.wrap)
+}
@@ -0,0 +1,43 @@
+package nekomimi
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBudgetLogHandler(t *testing.T) {
+	Convey("Budget handler tests", t, func() {
+		sink := &captureLogHandler{}
+		var overruns []int64
+		h := NewBudgetLogHandler(sink.handler(), 100, time.Minute,
+			func(over int64) { overruns = append(overruns, over) })
+		clock := NewMockClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+		h.(*budgetHandler).clock = clock
+		l := New("App", LogConfig{Handler: h, TestMode: true})
+		// "2000-01-01 00:00:00.000 [INFO], App - 0123456789\n"
+		const lineLen = 49
+
+		Convey("Callback fires once per interval with the overrun", func() {
+			l.Inf("0123456789")
+			l.Inf("0123456789")
+			So(overruns, ShouldBeEmpty)
+			l.Inf("0123456789")
+			So(overruns, ShouldResemble, []int64{3*lineLen - 100})
+			l.Inf("0123456789")
+			So(overruns, ShouldHaveLength, 1)
+			So(sink.count(), ShouldEqual, 4)
+			So(h.(interface{ EmittedBytes() uint64 }).EmittedBytes(),
+				ShouldEqual, 4*lineLen)
+
+			clock.Advance(time.Minute)
+			l.Inf("0123456789")
+			l.Inf("0123456789")
+			So(overruns, ShouldHaveLength, 1)
+			l.Panic("0123456789") // "[PANIC]" is 1 byte longer
+			So(overruns, ShouldResemble,
+				[]int64{3*lineLen - 100, 3*lineLen + 1 - 100})
+		})
+	})
+}
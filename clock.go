@@ -0,0 +1,80 @@
+package nekomimi
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the time source of the logger. Now provides the wall time of
+// log timestamps, Since provides the durations of traces (see
+// TraceLogger.Elapsed), which should use the monotonic component if
+// available.
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+}
+
+// realClock is the default Clock based on the time package
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) Since(t time.Time) time.Duration {
+	return time.Since(t)
+}
+
+// RealClock is the default Clock of the logger
+var RealClock Clock = realClock{}
+
+// ClockFunc adapts a function returning the current time (e.g. time.Now
+// or a frozen time in tests) to Clock. Since is computed from the function.
+type ClockFunc func() time.Time
+
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+func (f ClockFunc) Since(t time.Time) time.Duration {
+	return f().Sub(t)
+}
+
+// MockClock is a manually driven Clock for tests. the time only changes by
+// Set or Advance. it's safe for concurrent use.
+type MockClock struct {
+	mtx sync.RWMutex
+	now time.Time
+}
+
+// NewMockClock creates a MockClock starting at t
+func NewMockClock(t time.Time) *MockClock {
+	return &MockClock{now: t}
+}
+
+// Now returns the current time of the mock clock
+func (mc *MockClock) Now() time.Time {
+	mc.mtx.RLock()
+	defer mc.mtx.RUnlock()
+	return mc.now
+}
+
+// Since returns the duration between t and the current time of the mock
+// clock
+func (mc *MockClock) Since(t time.Time) time.Duration {
+	return mc.Now().Sub(t)
+}
+
+// Set sets the current time of the mock clock
+func (mc *MockClock) Set(t time.Time) {
+	mc.mtx.Lock()
+	defer mc.mtx.Unlock()
+	mc.now = t
+}
+
+// Advance moves the mock clock forward by d
+func (mc *MockClock) Advance(d time.Duration) {
+	mc.mtx.Lock()
+	defer mc.mtx.Unlock()
+	mc.now = mc.now.Add(d)
+}
@@ -0,0 +1,83 @@
+package nekomimi
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClock(t *testing.T) {
+	Convey("Clock tests", t, func() {
+		Convey("Mock clock drives timestamps and trace durations", func() {
+			start := time.Date(2026, 3, 14, 15, 9, 26, 0, time.UTC)
+			clock := NewMockClock(start)
+			rec := &captureLogHandler{}
+			l := New("Test", LogConfig{
+				Handler:        rec.handler(),
+				LevelWithTrace: ERROR,
+				TimeFormat:     time.RFC3339,
+				Clock:          clock,
+			})
+			tl := l.Derive("sub").Trace("req")
+			tl.Inf("begin")
+			So(rec.last(), ShouldStartWith, "2026-03-14T15:09:26Z [INFO]")
+			So(tl.Elapsed(), ShouldEqual, 0)
+
+			clock.Advance(1500 * time.Millisecond)
+			tl.Inf("end")
+			So(rec.last(), ShouldStartWith, "2026-03-14T15:09:27Z [INFO]")
+			So(tl.Elapsed(), ShouldEqual, 1500*time.Millisecond)
+
+			clock.Set(start.Add(time.Hour))
+			So(tl.Elapsed(), ShouldEqual, time.Hour)
+		})
+
+		Convey("Real clock is the default", func() {
+			l := New("Test", LogConfig{Handler: (&captureLogHandler{}).handler()})
+			tl := l.Trace("req")
+			time.Sleep(5 * time.Millisecond)
+			So(tl.Elapsed(), ShouldBeGreaterThanOrEqualTo, 5*time.Millisecond)
+		})
+
+		Convey("Test mode uses a fixed clock unless given", func() {
+			rec := &captureLogHandler{}
+			clock := NewMockClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+			l := New("Test", LogConfig{
+				Handler:  rec.handler(),
+				TestMode: true,
+				Clock:    clock,
+			})
+			l.Inf("hello")
+			So(rec.last(), ShouldStartWith, "2026-01-01 00:00:00.000 [INFO]")
+			l = New("Test", LogConfig{Handler: rec.handler(), TestMode: true})
+			tl := l.Trace("req")
+			l.Inf("hello")
+			So(rec.last(), ShouldStartWith, "2000-01-01 00:00:00.000 [INFO]")
+			So(tl.Elapsed(), ShouldEqual, 0)
+		})
+
+		Convey("Clock function is shared by derived and trace loggers", func() {
+			frozen := time.Date(2026, 5, 1, 8, 30, 0, 0, time.UTC)
+			rec := &captureLogHandler{}
+			l := New("App", LogConfig{
+				Handler:        rec.handler(),
+				LevelWithTrace: FATAL,
+				Clock:          ClockFunc(func() time.Time { return frozen }),
+			})
+			l.Inf("root")
+			So(rec.last(), ShouldEqual,
+				"2026-05-01 08:30:00.000 [INFO], App - root\n")
+			l.Derive("DB").War("derived")
+			So(rec.last(), ShouldEqual,
+				"2026-05-01 08:30:00.000 [WARN], App.DB - derived\n")
+			tl := l.Trace("req")
+			tl.Err("traced")
+			So(rec.last(), ShouldEqual, "2026-05-01 08:30:00.000 [ERROR], App<req:"+
+				tl.TraceID()+"> - traced\n")
+
+			frozen = frozen.Add(2 * time.Second)
+			So(tl.Elapsed(), ShouldEqual, 2*time.Second)
+		})
+	})
+}
@@ -0,0 +1,100 @@
+package nekomimi
+
+import (
+	"io"
+	"strings"
+)
+
+// correlationHandler is the LogHandler returned by NewCorrelationLogHandler
+type correlationHandler struct {
+	wrap  LogHandler
+	idFn  func() string
+	field string
+}
+
+// NewCorrelationLogHandler creates a LogHandler which tags every log message
+// with a correlation id obtained from an external source, e.g. the request
+// id of a serverless invocation. idFn is called for each message, the id is
+// injected into the header fields as `<field>=<id>`, so structured handlers
+// (see RecordLogHandlerFunc) receive it as a Record field as well:
+//
+//	2026-01-01 00:00:00.000 [INFO], App {request_id=c0ffee} - message
+//
+// nothing is injected if idFn returns empty string. all the messages are
+// forwarded to wrap.
+func NewCorrelationLogHandler(
+	wrap LogHandler, idFn func() string, field string,
+) LogHandler {
+	return &correlationHandler{
+		wrap:  wrap,
+		idFn:  idFn,
+		field: field,
+	}
+}
+
+// injectHeaderField adds the field to the fields of the log header at the
+// beginning of s. s is returned unchanged if the header is not recognizable.
+func injectHeaderField(s string, key, value string) string {
+	lb := strings.Index(s, "], ")
+	if lb < 0 {
+		return s
+	}
+	end := strings.Index(s[lb:], " - ")
+	if end < 0 {
+		return s
+	}
+	end += lb
+	kv := key + "=" + quoteFieldValue(value)
+	if s[end-1] == '}' {
+		return s[:end-1] + " " + kv + s[end-1:]
+	}
+	return s[:end] + " {" + kv + "}" + s[end:]
+}
+
+// inject injects the correlation id into the header
+func (c *correlationHandler) inject(header string) string {
+	id := c.idFn()
+	if id == "" {
+		return header
+	}
+	return injectHeaderField(header, c.field, id)
+}
+
+func (c *correlationHandler) RegularLog(
+	level LogLevel, header string, message ...any,
+) {
+	c.wrap.RegularLog(level, c.inject(header), message...)
+}
+
+func (c *correlationHandler) RegularWriter(
+	level LogLevel, pnt func(io.StringWriter),
+) {
+	sb := strings.Builder{}
+	pnt(&sb)
+	line := c.inject(sb.String())
+	c.wrap.RegularWriter(level, func(w io.StringWriter) {
+		w.WriteString(line)
+	})
+}
+
+func (c *correlationHandler) PanicLog(header string, message ...any) {
+	c.wrap.PanicLog(c.inject(header), message...)
+}
+
+func (c *correlationHandler) FatalLog(header string, message ...any) {
+	c.wrap.FatalLog(c.inject(header), message...)
+}
+
+func (c *correlationHandler) IsShutdown() bool {
+	return c.wrap.IsShutdown()
+}
+
+// Flush flushes the wrapped handler
+func (c *correlationHandler) Flush() error {
+	return flushHandler(c.wrap)
+}
+
+// Close closes the wrapped handler
+func (c *correlationHandler) Close() error {
+	return closeHandler(c.wrap)
+}
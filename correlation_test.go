@@ -0,0 +1,67 @@
+package nekomimi
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCorrelationLogHandler(t *testing.T) {
+	Convey("Correlation handler tests", t, func() {
+		requestID := "req-1"
+		idFn := func() string { return requestID }
+
+		Convey("Id is injected into the console header", func() {
+			capture := &captureLogHandler{}
+			l := New("App", LogConfig{
+				Handler: NewCorrelationLogHandler(
+					capture.handler(), idFn, "request_id"),
+				TestMode: true,
+			})
+			l.Inf("first")
+			So(capture.last(), ShouldEndWith,
+				"[INFO], App {request_id=req-1} - first\n")
+
+			requestID = "req 2"
+			tl := l.Trace("REQ")
+			tl.SetBaggage("tenant", "acme")
+			tl.War("second")
+			So(capture.last(), ShouldEndWith,
+				`{tenant=acme request_id="req 2"} - second`+"\n")
+
+			l.GetWriter(ERROR, false).WriteString("raw - writer")
+			So(capture.last(), ShouldEndWith,
+				`[ERROR], App {request_id="req 2"} - raw - writer`+"\n")
+
+			l.Panic("third")
+			So(capture.last(), ShouldEndWith,
+				`[PANIC], App {request_id="req 2"} - third`+"\n")
+
+			requestID = ""
+			l.Err("untagged")
+			So(capture.last(), ShouldEndWith, "[ERROR], App - untagged\n")
+			So(capture.count(), ShouldEqual, 5)
+		})
+
+		Convey("Id is a field of the records", func() {
+			var recs []Record
+			l := New("App", LogConfig{
+				Handler: NewCorrelationLogHandler(
+					RecordLogHandlerFunc(func(rec Record) {
+						recs = append(recs, rec)
+					}), idFn, "aws_request_id"),
+				TestMode: true,
+			})
+			for _, id := range []string{"a", "b", "c"} {
+				requestID = id
+				l.Inf("invocation")
+			}
+			So(len(recs), ShouldEqual, 3)
+			for i, id := range []string{"a", "b", "c"} {
+				So(recs[i].Fields, ShouldResemble,
+					[]Field{{Key: "aws_request_id", Value: id}})
+				So(recs[i].Message, ShouldEqual, "invocation")
+			}
+		})
+	})
+}
@@ -0,0 +1,179 @@
+package nekomimi
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// contextField is a registered context field extractor
+type contextField struct {
+	key       string
+	extractor func(ctx context.Context) (any, bool)
+}
+
+// contextFields holds the registered extractors. the slice is replaced on
+// each registration (copy-on-write), so the log path reads it lock-free.
+var contextFields atomic.Pointer[[]contextField]
+
+// contextFieldsMtx serializes registrations
+var contextFieldsMtx sync.Mutex
+
+// RegisterContextField registers an extractor for a context field. all the
+// context-aware log methods (DbgCtx, InfCtx...) call every registered
+// extractor with the given context, the extracted values are attached to
+// the log header as `{key=value ...}` in registration order. the extractor
+// should return false if the value is not present in the context.
+//
+// registering an existing key replaces its extractor. a nil extractor
+// removes the key.
+func RegisterContextField(
+	key string, extractor func(ctx context.Context) (any, bool),
+) {
+	contextFieldsMtx.Lock()
+	defer contextFieldsMtx.Unlock()
+	var old []contextField
+	if p := contextFields.Load(); p != nil {
+		old = *p
+	}
+	fields := make([]contextField, 0, len(old)+1)
+	replaced := false
+	for _, f := range old {
+		if f.key == key {
+			replaced = true
+			if extractor == nil {
+				continue
+			}
+			f.extractor = extractor
+		}
+		fields = append(fields, f)
+	}
+	if !replaced && extractor != nil {
+		fields = append(fields, contextField{key: key, extractor: extractor})
+	}
+	contextFields.Store(&fields)
+}
+
+// collectFields returns the given fields followed by the fields extracted
+// from ctx (could be nil) for the log header
+func collectFields(pre []Field, ctx context.Context) []Field {
+	p := contextFields.Load()
+	if ctx == nil || p == nil {
+		return pre
+	}
+	fields := pre
+	for _, f := range *p {
+		if v, ok := f.extractor(ctx); ok {
+			if len(fields) == len(pre) {
+				fields = slices.Clone(pre)
+			}
+			fields = append(fields, Field{Key: f.key, Value: fmt.Sprint(v)})
+		}
+	}
+	return fields
+}
+
+// fieldsString renders the given fields followed by the fields extracted
+// from ctx (could be nil) for the log header. returns empty string if there
+// is no field.
+func fieldsString(pre []Field, ctx context.Context) string {
+	return renderFields(collectFields(pre, ctx))
+}
+
+// renderFields renders the fields for the log header, e.g.
+// ` {key=value ...}`. returns empty string if there is no field.
+func renderFields(fields []Field) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	sb := strings.Builder{}
+	sb.WriteString(" {")
+	for i, f := range fields {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(f.Key)
+		sb.WriteByte('=')
+		sb.WriteString(quoteFieldValue(f.Value))
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+// quoteFieldValue quotes a rendered field value if it's empty or contains
+// spaces, quotes, '=' or braces
+func quoteFieldValue(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\r\n\"={}") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// parseFields parses the rendered fields `{key=value ...}` at the beginning
+// of s. returns the fields and the remaining string.
+func parseFields(s string) ([]Field, string, bool) {
+	if !strings.HasPrefix(s, "{") {
+		return nil, s, false
+	}
+	s = s[1:]
+	var fields []Field
+	for {
+		s = strings.TrimLeft(s, " ")
+		if strings.HasPrefix(s, "}") {
+			return fields, s[1:], true
+		}
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 {
+			return nil, s, false
+		}
+		key := s[:eq]
+		s = s[eq+1:]
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			q, err := strconv.QuotedPrefix(s)
+			if err != nil {
+				return nil, s, false
+			}
+			value, _ = strconv.Unquote(q)
+			s = s[len(q):]
+		} else {
+			end := strings.IndexAny(s, " }")
+			if end < 0 {
+				return nil, s, false
+			}
+			value = s[:end]
+			s = s[end:]
+		}
+		fields = append(fields, Field{Key: key, Value: value})
+	}
+}
+
+// processFieldsString renders the process fields (pid, host and exe) for
+// the log header, without braces. instanceID resolves the host field, nil
+// means os.Hostname.
+func processFieldsString(instanceID func() string) string {
+	var host string
+	if instanceID != nil {
+		host = instanceID()
+	} else {
+		var err error
+		if host, err = os.Hostname(); err != nil {
+			host = "unknown"
+		}
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		exe = os.Args[0]
+	}
+	return fmt.Sprintf("pid=%d host=%s exe=%s",
+		os.Getpid(),
+		quoteFieldValue(host),
+		quoteFieldValue(filepath.Base(exe)),
+	)
+}
@@ -0,0 +1,231 @@
+package nekomimi
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type requestIDKey struct{}
+
+func TestContextField(t *testing.T) {
+	Convey("Context field tests", t, func() {
+		RegisterContextField("request_id", func(ctx context.Context) (any, bool) {
+			v, ok := ctx.Value(requestIDKey{}).(string)
+			return v, ok
+		})
+		defer RegisterContextField("request_id", nil)
+
+		rec := &captureLogHandler{}
+		l := New("App", LogConfig{
+			Handler:        rec.handler(),
+			LevelWithTrace: ERROR,
+		})
+		ctx := context.WithValue(
+			context.Background(), requestIDKey{}, "req-42")
+
+		Convey("Fields appear on every logger kind", func() {
+			l.InfCtx(ctx, "handled")
+			So(rec.last(), ShouldContainSubstring,
+				"[INFO], App {request_id=req-42} - handled")
+			l.Derive("DB").WarCtx(ctx, "slow")
+			So(rec.last(), ShouldContainSubstring, "[WARN], App.DB ")
+			So(rec.last(), ShouldContainSubstring,
+				" {request_id=req-42} - slow")
+			tl := l.Trace("T")
+			tl.DbgCtx(ctx, "traced")
+			So(rec.last(), ShouldContainSubstring,
+				"App<T:"+tl.TraceID()+"> {request_id=req-42} - traced")
+			l.ErrCtx(ctx, "failed")
+			So(rec.last(), ShouldContainSubstring,
+				"ctxfield_test.go")
+			So(rec.last(), ShouldContainSubstring,
+				" {request_id=req-42} - failed")
+		})
+
+		Convey("Missing value and quoting", func() {
+			l.InfCtx(context.Background(), "no id")
+			So(rec.last(), ShouldContainSubstring, "[INFO], App - no id")
+			RegisterContextField("user", func(ctx context.Context) (any, bool) {
+				return "john doe", true
+			})
+			defer RegisterContextField("user", nil)
+			l.InfCtx(ctx, "quoted")
+			So(rec.last(), ShouldContainSubstring,
+				`{request_id=req-42 user="john doe"} - quoted`)
+		})
+
+		Convey("Fields are decomposed into Record", func() {
+			var recs []Record
+			l.SetLogHandler(RecordLogHandlerFunc(func(r Record) {
+				recs = append(recs, r)
+			}))
+			RegisterContextField("user", func(ctx context.Context) (any, bool) {
+				return "a=b {c}", true
+			})
+			defer RegisterContextField("user", nil)
+			l.ErrCtx(ctx, "failed - again")
+			So(len(recs), ShouldEqual, 1)
+			So(recs[0].Caller, ShouldStartWith, "ctxfield_test.go:")
+			So(recs[0].Fields, ShouldResemble, []Field{
+				{Key: "request_id", Value: "req-42"},
+				{Key: "user", Value: "a=b {c}"},
+			})
+			So(recs[0].Message, ShouldEqual, "failed - again")
+		})
+	})
+}
+
+func TestProcessFields(t *testing.T) {
+	Convey("Process fields tests", t, func() {
+		var recs []Record
+		l := New("App", LogConfig{
+			Handler: RecordLogHandlerFunc(func(rec Record) {
+				recs = append(recs, rec)
+			}),
+			LevelWithTrace:       FATAL,
+			IncludeProcessFields: true,
+		})
+		host, _ := os.Hostname()
+		exe, _ := os.Executable()
+		want := []Field{
+			{Key: "pid", Value: strconv.Itoa(os.Getpid())},
+			{Key: "host", Value: host},
+			{Key: "exe", Value: filepath.Base(exe)},
+		}
+
+		l.Inf("first")
+		l.Derive("sub").War("second")
+		tl := l.Trace("req")
+		tl.SetBaggage("tenant", "acme")
+		tl.Err("third")
+		So(len(recs), ShouldEqual, 3)
+		So(recs[0].Fields, ShouldResemble, want)
+		So(recs[1].Fields, ShouldResemble, want)
+		So(recs[1].Prefix, ShouldEqual, "App.sub")
+		So(recs[2].Fields, ShouldResemble,
+			append(want, Field{Key: "tenant", Value: "acme"}))
+		So(recs[2].Message, ShouldEqual, "third")
+
+		recs = nil
+		New("App", LogConfig{
+			Handler: RecordLogHandlerFunc(func(rec Record) {
+				recs = append(recs, rec)
+			}),
+		}).Inf("disabled")
+		So(recs[0].Fields, ShouldBeEmpty)
+
+		recs = nil
+		calls := 0
+		l = New("App", LogConfig{
+			Handler: RecordLogHandlerFunc(func(rec Record) {
+				recs = append(recs, rec)
+			}),
+			IncludeProcessFields: true,
+			InstanceID: func() string {
+				calls++
+				return "pod web-7f9c 2"
+			},
+		})
+		l.Inf("first")
+		l.Derive("sub").Inf("second")
+		So(calls, ShouldEqual, 1)
+		for _, rec := range recs {
+			So(rec.Fields[1], ShouldResemble,
+				Field{Key: "host", Value: "pod web-7f9c 2"})
+		}
+	})
+}
+
+func TestEpochNanos(t *testing.T) {
+	Convey("Epoch nanos tests", t, func() {
+		var recs []Record
+		clock := NewMockClock(testModeTime)
+		l := New("App", LogConfig{
+			Handler: RecordLogHandlerFunc(func(rec Record) {
+				recs = append(recs, rec)
+			}),
+			LevelWithTrace:    FATAL,
+			Clock:             clock,
+			IncludeEpochNanos: true,
+		})
+
+		l.Inf("first")
+		clock.Advance(time.Microsecond)
+		l.Derive("sub").Inf("second")
+		l.Inf("third")
+		So(len(recs), ShouldEqual, 3)
+		So(recs[0].Fields, ShouldResemble, []Field{{
+			Key:   "ts_nanos",
+			Value: strconv.FormatInt(testModeTime.UnixNano(), 10),
+		}})
+		last := int64(0)
+		for _, rec := range recs {
+			So(len(rec.Fields), ShouldEqual, 1)
+			So(rec.Fields[0].Key, ShouldEqual, "ts_nanos")
+			ts, err := strconv.ParseInt(rec.Fields[0].Value, 10, 64)
+			So(err, ShouldBeNil)
+			So(ts, ShouldBeGreaterThanOrEqualTo, last)
+			last = ts
+		}
+
+		recs = nil
+		New("App", LogConfig{
+			Handler: RecordLogHandlerFunc(func(rec Record) {
+				recs = append(recs, rec)
+			}),
+			IncludeEpochNanos:    true,
+			IncludeProcessFields: true,
+		}).Inf("merged")
+		So(len(recs[0].Fields), ShouldEqual, 4)
+		So(recs[0].Fields[0].Key, ShouldEqual, "pid")
+		So(recs[0].Fields[3].Key, ShouldEqual, "ts_nanos")
+	})
+}
+
+// logAtDepth logs after n nested calls
+func logAtDepth(l Logger, n int) {
+	if n > 0 {
+		logAtDepth(l, n-1)
+		return
+	}
+	l.Inf("nested")
+}
+
+func TestStackDepth(t *testing.T) {
+	Convey("Stack depth tests", t, func() {
+		var recs []Record
+		l := New("App", LogConfig{
+			Handler: RecordLogHandlerFunc(func(rec Record) {
+				recs = append(recs, rec)
+			}),
+			LevelWithTrace:    FATAL,
+			IncludeStackDepth: true,
+		})
+		depth := func(rec Record) int {
+			So(len(rec.Fields), ShouldEqual, 1)
+			So(rec.Fields[0].Key, ShouldEqual, "stack_depth")
+			d, err := strconv.Atoi(rec.Fields[0].Value)
+			So(err, ShouldBeNil)
+			return d
+		}
+
+		logAtDepth(l, 0)
+		logAtDepth(l, 1)
+		logAtDepth(l, 10)
+		logAtDepth(l.Derive("sub"), 10)
+		logAtDepth(l, 100)
+		So(len(recs), ShouldEqual, 5)
+		d0 := depth(recs[0])
+		So(d0, ShouldBeGreaterThan, 0)
+		So(depth(recs[1]), ShouldEqual, d0+1)
+		So(depth(recs[2]), ShouldEqual, d0+10)
+		So(depth(recs[3]), ShouldEqual, d0+10)
+		So(depth(recs[4]), ShouldEqual, d0+100)
+	})
+}
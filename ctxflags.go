@@ -0,0 +1,39 @@
+package nekomimi
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+// flagsContextKey is the context key of the feature flags
+type flagsContextKey struct{}
+
+// FlagsField is the header field key of the feature flags attached by
+// ContextWithFlags
+const FlagsField = "flags"
+
+func init() {
+	RegisterContextField(FlagsField, func(ctx context.Context) (any, bool) {
+		flags, ok := FlagsFromContext(ctx)
+		if !ok {
+			return nil, false
+		}
+		return strings.Join(flags, ","), true
+	})
+}
+
+// ContextWithFlags returns a copy of ctx carrying the active feature flags
+// (e.g. the enabled experiment variants), so the context-aware log methods
+// attach them as `{flags=a,b}` to slice the logs by variant. an empty set
+// attaches nothing.
+func ContextWithFlags(ctx context.Context, flags []string) context.Context {
+	return context.WithValue(ctx, flagsContextKey{}, slices.Clone(flags))
+}
+
+// FlagsFromContext returns the feature flags stored by ContextWithFlags.
+// returns false if there is none.
+func FlagsFromContext(ctx context.Context) ([]string, bool) {
+	flags, ok := ctx.Value(flagsContextKey{}).([]string)
+	return flags, ok && len(flags) > 0
+}
@@ -0,0 +1,48 @@
+package nekomimi
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestContextFlags(t *testing.T) {
+	Convey("Context flags tests", t, func() {
+		rec := &captureLogHandler{}
+		l := New("App", LogConfig{Handler: rec.handler(), TestMode: true})
+		flags := []string{"new_checkout", "dark_mode"}
+		ctx := ContextWithFlags(context.Background(), flags)
+		flags[0] = "changed"
+
+		l.InfCtx(ctx, "handled")
+		So(rec.last(), ShouldEqual, "2000-01-01 00:00:00.000 [INFO], "+
+			"App {flags=new_checkout,dark_mode} - handled\n")
+		tl := l.Trace("T")
+		tl.WarCtx(ctx, "traced")
+		So(rec.last(), ShouldContainSubstring,
+			"> {flags=new_checkout,dark_mode} - traced")
+
+		var recs []Record
+		l.SetLogHandler(RecordLogHandlerFunc(func(r Record) {
+			recs = append(recs, r)
+		}))
+		l.ErrCtx(ctx, "failed")
+		So(len(recs), ShouldEqual, 1)
+		So(recs[0].Fields, ShouldResemble, []Field{
+			{Key: FlagsField, Value: "new_checkout,dark_mode"},
+		})
+
+		got, ok := FlagsFromContext(ctx)
+		So(ok, ShouldBeTrue)
+		So(got, ShouldResemble, []string{"new_checkout", "dark_mode"})
+		_, ok = FlagsFromContext(ContextWithFlags(context.Background(), nil))
+		So(ok, ShouldBeFalse)
+		_, ok = FlagsFromContext(context.Background())
+		So(ok, ShouldBeFalse)
+		l.SetLogHandler(rec.handler())
+		l.InfCtx(ContextWithFlags(context.Background(), nil), "no flags")
+		So(rec.last(), ShouldEqual,
+			"2000-01-01 00:00:00.000 [INFO], App - no flags\n")
+	})
+}
@@ -0,0 +1,44 @@
+package nekomimi
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// loggerContextKey is the context key of the context-scoped Logger
+type loggerContextKey struct{}
+
+// contextDefault holds the Logger set by SetContextDefault, nil if unset
+var contextDefault atomic.Pointer[Logger]
+
+// ContextWithLogger returns a copy of ctx carrying the Logger, which is
+// returned by Ctx for the context
+func ContextWithLogger(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, l)
+}
+
+// SetContextDefault sets the Logger returned by Ctx for the contexts without
+// a context-scoped Logger, e.g. a logger for the libraries which only have
+// a context. nil unsets it, so Ctx falls back to Default.
+func SetContextDefault(l Logger) {
+	if l == nil {
+		contextDefault.Store(nil)
+		return
+	}
+	contextDefault.Store(&l)
+}
+
+// Ctx returns the Logger for ctx: the context-scoped Logger stored by
+// ContextWithLogger if present, else the Logger set by SetContextDefault,
+// else the package-level Default. a nil ctx is allowed.
+func Ctx(ctx context.Context) Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(loggerContextKey{}).(Logger); ok && l != nil {
+			return l
+		}
+	}
+	if p := contextDefault.Load(); p != nil {
+		return *p
+	}
+	return Default()
+}
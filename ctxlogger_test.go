@@ -0,0 +1,49 @@
+package nekomimi
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestContextLogger(t *testing.T) {
+	Convey("Context logger tests", t, func() {
+		capture := &captureLogHandler{}
+		newLogger := func(name string) Logger {
+			return New(name, LogConfig{Handler: capture.handler(), TestMode: true})
+		}
+		orig := Default()
+		SetDefault(newLogger("Global"))
+		defer SetDefault(orig)
+		defer SetContextDefault(nil)
+
+		Convey("Fallback tiers", func() {
+			ctx := context.Background()
+			Ctx(ctx).Inf("global")
+			So(capture.last(), ShouldEndWith, "[INFO], Global - global\n")
+
+			SetContextDefault(newLogger("CtxDefault"))
+			Ctx(ctx).Inf("context default")
+			So(capture.last(), ShouldEndWith,
+				"[INFO], CtxDefault - context default\n")
+			Ctx(nil).Inf("nil context")
+			So(capture.last(), ShouldEndWith,
+				"[INFO], CtxDefault - nil context\n")
+
+			scoped := ContextWithLogger(ctx, newLogger("Scoped"))
+			Ctx(scoped).Inf("scoped")
+			So(capture.last(), ShouldEndWith, "[INFO], Scoped - scoped\n")
+			child, cancel := context.WithCancel(scoped)
+			defer cancel()
+			Ctx(child).Inf("child")
+			So(capture.last(), ShouldEndWith, "[INFO], Scoped - child\n")
+
+			SetContextDefault(nil)
+			Ctx(ctx).Inf("unset")
+			So(capture.last(), ShouldEndWith, "[INFO], Global - unset\n")
+			Ctx(ContextWithLogger(ctx, nil)).Inf("nil logger")
+			So(capture.last(), ShouldEndWith, "[INFO], Global - nil logger\n")
+		})
+	})
+}
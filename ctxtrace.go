@@ -0,0 +1,21 @@
+package nekomimi
+
+import "context"
+
+// traceContextKey is the context key of the TraceLogger
+type traceContextKey struct{}
+
+// ContextWithTrace returns a copy of ctx carrying the TraceLogger, so the
+// functions deep in the call chain can log with the same trace (see
+// TraceFromContext) without passing the logger through every signature.
+func ContextWithTrace(ctx context.Context, tl TraceLogger) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, tl)
+}
+
+// TraceFromContext returns the TraceLogger stored by ContextWithTrace.
+// returns false if there is none, the caller could fall back to a default
+// logger then.
+func TraceFromContext(ctx context.Context) (TraceLogger, bool) {
+	tl, ok := ctx.Value(traceContextKey{}).(TraceLogger)
+	return tl, ok && tl != nil
+}
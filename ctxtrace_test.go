@@ -0,0 +1,42 @@
+package nekomimi
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTraceContext(t *testing.T) {
+	Convey("Trace context tests", t, func() {
+		capture := &captureLogHandler{}
+		l := New("App", LogConfig{Handler: capture.handler(), TestMode: true})
+
+		Convey("Trace logger travels with the context", func() {
+			tl := l.Trace("REQ")
+			ctx := ContextWithTrace(context.Background(), tl)
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+
+			deep := func(ctx context.Context) {
+				got, ok := TraceFromContext(ctx)
+				So(ok, ShouldBeTrue)
+				got.Inf("deep call")
+			}
+			deep(ctx)
+			So(capture.last(), ShouldEndWith,
+				"[INFO], App<REQ:"+tl.TraceID()+"> - deep call\n")
+		})
+
+		Convey("No trace logger in the context", func() {
+			tl, ok := TraceFromContext(context.Background())
+			So(ok, ShouldBeFalse)
+			So(tl, ShouldBeNil)
+
+			tl, ok = TraceFromContext(
+				ContextWithTrace(context.Background(), nil))
+			So(ok, ShouldBeFalse)
+			So(tl, ShouldBeNil)
+		})
+	})
+}
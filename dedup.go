@@ -0,0 +1,160 @@
+package nekomimi
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dedupHandler is the LogHandler returned by NewDedupHandler
+type dedupHandler struct {
+	wrap   LogHandler
+	window time.Duration
+
+	mtx      sync.Mutex
+	lastKey  string    // last message without the timestamp
+	lastLine string    // last repetition of the message
+	lastTime time.Time // time of the last repetition
+	level    LogLevel
+	repeated int // repetitions suppressed in the streak
+	timer    *time.Timer
+	closed   bool
+}
+
+// NewDedupHandler creates a LogHandler which suppresses the consecutive
+// identical regular log messages: a message equal to the previous one
+// (header and body, the timestamp is ignored) within window after it is
+// not forwarded to wrapped but counted. when the streak ends, i.e. a
+// different message arrives or window elapses without a repetition, the
+// last repetition is forwarded with the count:
+//
+//	2026-01-01 10:00:03.000 [ERROR], App - dial failed (repeated x 41)
+//
+// flushing or closing the handler ends the streak as well. panic and fatal
+// messages, including those received by RegularWriter as a Wrapper, end
+// the streak and are never suppressed.
+func NewDedupHandler(wrapped LogHandler, window time.Duration) LogHandler {
+	return &dedupHandler{wrap: wrapped, window: window}
+}
+
+// endStreak forwards the summary of the suppressed repetitions and forgets
+// the last message. must be called with mtx held.
+func (d *dedupHandler) endStreak() {
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	if d.repeated > 0 {
+		line := fmt.Sprintf("%s (repeated x %d)\n",
+			strings.TrimSuffix(d.lastLine, "\n"), d.repeated)
+		d.wrap.RegularWriter(d.level, func(w io.StringWriter) {
+			w.WriteString(line)
+		})
+	}
+	d.lastKey, d.lastLine, d.repeated = "", "", 0
+}
+
+// expire ends the streak if no repetition arrived within the window
+func (d *dedupHandler) expire() {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	if d.lastKey != "" && time.Since(d.lastTime) >= d.window {
+		d.endStreak()
+	}
+}
+
+func (d *dedupHandler) IsShutdown() bool {
+	return d.wrap.IsShutdown()
+}
+
+func (d *dedupHandler) RegularWriter(
+	level LogLevel, pnt func(io.StringWriter),
+) {
+	if level >= PANIC {
+		func() {
+			d.mtx.Lock()
+			defer d.mtx.Unlock()
+			d.endStreak()
+		}()
+		d.wrap.RegularWriter(level, pnt)
+		return
+	}
+	sb := strings.Builder{}
+	pnt(&sb)
+	line := sb.String()
+	key := trimTimestamp(line)
+	now := time.Now()
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	if d.closed {
+		d.wrap.RegularWriter(level, pnt)
+		return
+	}
+	if key == d.lastKey && now.Sub(d.lastTime) < d.window {
+		d.repeated++
+		d.lastLine, d.lastTime = line, now
+		d.timer.Reset(d.window)
+		return
+	}
+	d.endStreak()
+	d.wrap.RegularWriter(level, func(w io.StringWriter) {
+		w.WriteString(line)
+	})
+	d.lastKey, d.lastLine, d.lastTime, d.level = key, line, now, level
+	d.timer = time.AfterFunc(d.window, d.expire)
+}
+
+func (d *dedupHandler) RegularLog(
+	level LogLevel, header string, message ...any,
+) {
+	sp := fmt.Sprintln(message...)
+	d.RegularWriter(level, func(w io.StringWriter) {
+		w.WriteString(header)
+		w.WriteString(sp)
+	})
+}
+
+func (d *dedupHandler) PanicLog(header string, message ...any) {
+	func() {
+		d.mtx.Lock()
+		defer d.mtx.Unlock()
+		d.endStreak()
+	}()
+	d.wrap.PanicLog(header, message...)
+}
+
+func (d *dedupHandler) FatalLog(header string, message ...any) {
+	func() {
+		d.mtx.Lock()
+		defer d.mtx.Unlock()
+		d.endStreak()
+	}()
+	d.wrap.FatalLog(header, message...)
+}
+
+// Flush forwards the summary of the current streak, then flushes the
+// wrapped handler
+func (d *dedupHandler) Flush() error {
+	func() {
+		d.mtx.Lock()
+		defer d.mtx.Unlock()
+		d.endStreak()
+	}()
+	return flushHandler(d.wrap)
+}
+
+// Close forwards the summary of the current streak, then closes the
+// wrapped handler. the following messages are forwarded without
+// deduplication.
+func (d *dedupHandler) Close() error {
+	func() {
+		d.mtx.Lock()
+		defer d.mtx.Unlock()
+		d.endStreak()
+		d.closed = true
+	}()
+	return closeHandler(d.wrap)
+}
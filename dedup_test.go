@@ -0,0 +1,90 @@
+package nekomimi
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDedupHandler(t *testing.T) {
+	Convey("Dedup handler tests", t, func() {
+		sink := &captureLogHandler{}
+		h := NewDedupHandler(sink.handler(), time.Hour)
+		clock := NewMockClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+		l := New("App", LogConfig{
+			Handler:        h,
+			Clock:          clock,
+			LevelWithTrace: FATAL,
+		})
+
+		Convey("Repetitions are collapsed until a different message", func() {
+			for range 5 {
+				l.Err("dial failed")
+				clock.Advance(time.Second)
+			}
+			So(sink.count(), ShouldEqual, 1)
+			l.War("giving up")
+			So(sink.lines, ShouldResemble, []string{
+				"2026-01-01 00:00:00.000 [ERROR], App - dial failed\n",
+				"2026-01-01 00:00:04.000 [ERROR], App - dial failed (repeated x 4)\n",
+				"2026-01-01 00:00:05.000 [WARN], App - giving up\n",
+			})
+			So(sink.levels, ShouldResemble, []LogLevel{ERROR, ERROR, WARN})
+
+			// different level is a different message
+			l.Err("giving up")
+			So(sink.count(), ShouldEqual, 4)
+		})
+
+		Convey("Flush and panic end the streak", func() {
+			l.Inf("same")
+			l.Inf("same")
+			So(h.(Flusher).Flush(), ShouldBeNil)
+			So(sink.last(), ShouldEndWith, "- same (repeated x 1)\n")
+			l.Inf("same")
+			So(sink.count(), ShouldEqual, 3)
+			l.Inf("same")
+			l.Panic("boom")
+			So(sink.lines[3], ShouldEndWith, "- same (repeated x 1)\n")
+			So(sink.last(), ShouldEndWith, "<<<< - boom\n")
+		})
+
+		Convey("Panic received as a wrapper is never suppressed", func() {
+			dl := New("App", LogConfig{
+				Handler: NewNativeLogHandlerWithConfig(context.Background(),
+					NativeConfig{Stdout: io.Discard, Stderr: io.Discard},
+					NewDedupHandler(sink.handler(), time.Hour)),
+				TestMode: true,
+			})
+			dl.Inf("same")
+			dl.Inf("same")
+			So(func() { dl.Panic("boom") }, ShouldPanic)
+			So(func() { dl.Panic("boom") }, ShouldPanic)
+			So(sink.count(), ShouldEqual, 4)
+			So(sink.lines[1], ShouldEndWith, "- same (repeated x 1)\n")
+			So(sink.levels[2:], ShouldResemble, []LogLevel{PANIC, PANIC})
+			So(sink.last(), ShouldEqual,
+				"2000-01-01 00:00:00.000 [PANIC], App - boom\n")
+		})
+
+		Convey("Streak expires after the window", func() {
+			sh := NewDedupHandler(sink.handler(), 50*time.Millisecond)
+			sl := New("App", LogConfig{Handler: sh, TestMode: true})
+			sl.Inf("tick")
+			sl.Inf("tick")
+			sl.Inf("tick")
+			So(waitFor(func() bool { return sink.count() == 2 }), ShouldBeTrue)
+			So(sink.last(), ShouldEqual,
+				"2000-01-01 00:00:00.000 [INFO], App - tick (repeated x 2)\n")
+			sl.Inf("tick") // a new streak
+			So(sink.count(), ShouldEqual, 3)
+
+			So(sh.(interface{ Close() error }).Close(), ShouldBeNil)
+			sl.Inf("tick")
+			So(sink.count(), ShouldEqual, 4)
+		})
+	})
+}
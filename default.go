@@ -0,0 +1,103 @@
+package nekomimi
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// defaultLogger holds the package-level default Logger
+var defaultLogger atomic.Pointer[Logger]
+
+func init() {
+	SetDefault(New("*", LogConfig{}))
+}
+
+// Default returns the package-level default Logger used by the package
+// functions (Dbg, Inf...). it's `New("*", LogConfig{})` unless replaced by
+// SetDefault.
+func Default() Logger {
+	return *defaultLogger.Load()
+}
+
+// SetDefault replaces the package-level default Logger. it's safe to call
+// concurrently with the package functions. nil is ignored.
+func SetDefault(l Logger) {
+	if l == nil {
+		return
+	}
+	defaultLogger.Store(&l)
+}
+
+// package functions forward to the default Logger, the call trace reports
+// the caller of the function
+
+// Dbg outputs a DEBUG message with the default Logger
+func Dbg(message ...any) {
+	Default().DbgSkip(1, message...)
+}
+
+// Dbgf outputs a formatted DEBUG message with the default Logger
+func Dbgf(format string, args ...any) {
+	if l := Default(); l.DbgP() != nil {
+		l.DbgSkip(1, fmt.Sprintf(format, args...))
+	}
+}
+
+// Inf outputs an INFO message with the default Logger
+func Inf(message ...any) {
+	Default().InfSkip(1, message...)
+}
+
+// Inff outputs a formatted INFO message with the default Logger
+func Inff(format string, args ...any) {
+	if l := Default(); l.InfP() != nil {
+		l.InfSkip(1, fmt.Sprintf(format, args...))
+	}
+}
+
+// War outputs a WARN message with the default Logger
+func War(message ...any) {
+	Default().WarSkip(1, message...)
+}
+
+// Warf outputs a formatted WARN message with the default Logger
+func Warf(format string, args ...any) {
+	if l := Default(); l.WarP() != nil {
+		l.WarSkip(1, fmt.Sprintf(format, args...))
+	}
+}
+
+// Err outputs an ERROR message with the default Logger
+func Err(message ...any) {
+	Default().ErrSkip(1, message...)
+}
+
+// Errf outputs a formatted ERROR message with the default Logger
+func Errf(format string, args ...any) {
+	if l := Default(); l.ErrP() != nil {
+		l.ErrSkip(1, fmt.Sprintf(format, args...))
+	}
+}
+
+// Panic outputs a PANIC message with the default Logger, then raises panic
+func Panic(message ...any) {
+	Default().Panic(message...)
+}
+
+// Panicf outputs a formatted PANIC message with the default Logger, then
+// raises panic
+func Panicf(format string, args ...any) {
+	Default().Panicf(format, args...)
+}
+
+// Fatal outputs a FATAL message with the default Logger, then terminates
+// the program
+func Fatal(message ...any) {
+	Default().Fatal(message...)
+}
+
+// Fatalf outputs a formatted FATAL message with the default Logger, then
+// terminates the program
+func Fatalf(format string, args ...any) {
+	Default().Fatalf(format, args...)
+}
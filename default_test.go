@@ -0,0 +1,79 @@
+package nekomimi
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDefaultLogger(t *testing.T) {
+	Convey("Default logger tests", t, func() {
+		orig := Default()
+		defer SetDefault(orig)
+		So(orig, ShouldNotBeNil)
+
+		rec := &captureLogHandler{}
+		l := New("Default", LogConfig{Handler: rec.handler(), TestMode: true})
+		SetDefault(l)
+		SetDefault(nil) // ignored
+		So(Default(), ShouldEqual, l)
+
+		Convey("Package functions forward to the default logger", func() {
+			Dbg("debug")
+			Dbgf("debug %d", 1)
+			Inf("info")
+			Inff("info %d", 2)
+			War("warn")
+			Warf("warn %d", 3)
+			Err("error")
+			Errf("error %d", 4)
+			So(rec.levels, ShouldResemble, []LogLevel{
+				DEBUG, DEBUG, INFO, INFO, WARN, WARN, ERROR, ERROR})
+			So(rec.lines[1], ShouldEndWith, "[DEBUG], Default - debug 1\n")
+			So(rec.last(), ShouldEndWith, "[ERROR], Default - error 4\n")
+
+			l.SetLevel(WARN)
+			Inff("filtered %d", 5)
+			So(rec.count(), ShouldEqual, 8)
+
+			Panicf("boom %d", 6) // the capture handler doesn't panic
+			So(rec.last(), ShouldEndWith, "[PANIC], Default - boom 6\n")
+		})
+
+		Convey("Call trace reports the caller", func() {
+			tl := New("Default", LogConfig{Handler: rec.handler()})
+			SetDefault(tl)
+			_, _, line, _ := runtime.Caller(0)
+			Inf("where")
+			So(rec.last(), ShouldContainSubstring,
+				fmt.Sprintf("default_test.go:%d(", line+1))
+			Warf("where %s", "formatted")
+			So(rec.last(), ShouldContainSubstring,
+				fmt.Sprintf("default_test.go:%d(", line+4))
+		})
+
+		Convey("Replace concurrently", func() {
+			wg := sync.WaitGroup{}
+			for range 4 {
+				wg.Add(2)
+				go func() {
+					defer wg.Done()
+					for range 100 {
+						Inf("concurrent")
+					}
+				}()
+				go func() {
+					defer wg.Done()
+					for range 100 {
+						SetDefault(l)
+					}
+				}()
+			}
+			wg.Wait()
+			So(rec.count(), ShouldEqual, 400)
+		})
+	})
+}
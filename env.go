@@ -0,0 +1,245 @@
+package nekomimi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// environment variables read by NewFromEnv
+const (
+	EnvLevel          = "NEKO_LEVEL"
+	EnvFormat         = "NEKO_FORMAT"
+	EnvTimeFormat     = "NEKO_TIME_FORMAT"
+	EnvFile           = "NEKO_FILE"
+	EnvCallTraceLevel = "NEKO_CALLTRACE_LEVEL"
+)
+
+// NewFromEnv creates a Logger configured by the environment variables, for
+// twelve-factor apps:
+//
+//	NEKO_LEVEL            minimum level, e.g. "info" (default: DEBUG)
+//	NEKO_CALLTRACE_LEVEL  level to include call trace (default: DEBUG)
+//	NEKO_TIME_FORMAT      time layout of the header
+//	NEKO_FORMAT           "console" (default), "json" or "logfmt"
+//	NEKO_FILE             append the output to the file instead of stdio
+//
+// the json and logfmt formats write one encoded Record per line. with
+// NEKO_FILE the handler implements Flusher and io.Closer, so Logger.Flush
+// (or the shutdown helpers) sync the file before the program exits. an
+// error is returned for invalid values, or if the file can't be opened.
+func NewFromEnv(name string) (Logger, error) {
+	cfg := LogConfig{
+		Level:          DEBUG,
+		LevelWithTrace: DEBUG,
+		TimeFormat:     os.Getenv(EnvTimeFormat),
+	}
+	var err error
+	if v := os.Getenv(EnvLevel); v != "" {
+		if cfg.Level, err = ParseLevel(v); err != nil {
+			return nil, fmt.Errorf("%s: %w", EnvLevel, err)
+		}
+	}
+	if v := os.Getenv(EnvCallTraceLevel); v != "" {
+		if cfg.LevelWithTrace, err = ParseLevel(v); err != nil {
+			return nil, fmt.Errorf("%s: %w", EnvCallTraceLevel, err)
+		}
+	}
+
+	format := strings.ToLower(os.Getenv(EnvFormat))
+	switch format {
+	case "", "console", "json", "logfmt":
+	default:
+		return nil, fmt.Errorf(
+			"%s: nekomimi: unknown log format %q", EnvFormat, format)
+	}
+
+	var out io.Writer
+	var fp *os.File
+	if path := os.Getenv(EnvFile); path != "" {
+		fp, err = os.OpenFile(
+			path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", EnvFile, err)
+		}
+		out = fp
+	}
+
+	switch format {
+	case "json", "logfmt":
+		if out == nil {
+			out = os.Stdout
+		}
+		encode := encodeJSONRecord
+		if format == "logfmt" {
+			encode = encodeLogfmtRecord
+		}
+		cfg.Handler = newRecordWriterHandler(out, encode)
+	default:
+		cfg.Handler = NewNativeLogHandlerWithConfig(
+			context.Background(),
+			NativeConfig{Stdout: out, Stderr: out},
+			nil,
+		)
+	}
+	if fp != nil {
+		cfg.Handler = &envFileHandler{LogHandler: cfg.Handler, fp: fp}
+	}
+	return New(name, cfg), nil
+}
+
+// envFileHandler is the handler of NewFromEnv writing to the file of
+// EnvFile, which flushes and closes the file, e.g. by Logger.Flush before
+// os.Exit or by the shutdown helpers
+type envFileHandler struct {
+	LogHandler
+	fp *os.File
+}
+
+// RegularLogMeta implements MetaLogHandler, the metadata is dropped if the
+// wrapped handler doesn't accept it
+func (eh *envFileHandler) RegularLogMeta(
+	level LogLevel, header string, meta []Field, message ...any,
+) {
+	if mh, ok := eh.LogHandler.(MetaLogHandler); ok {
+		mh.RegularLogMeta(level, header, meta, message...)
+		return
+	}
+	eh.LogHandler.RegularLog(level, header, message...)
+}
+
+// Flush flushes the handler, then writes the file to the storage
+func (eh *envFileHandler) Flush() error {
+	return errors.Join(flushHandler(eh.LogHandler), eh.fp.Sync())
+}
+
+// Close closes the handler and the file, the following messages are lost
+func (eh *envFileHandler) Close() error {
+	return errors.Join(closeHandler(eh.LogHandler), eh.fp.Close())
+}
+
+// WatchEnvLevel spawns a goroutine which re-reads the level from the
+// environment variable varname (e.g. EnvLevel) and calls SetLevel of the
+// logger when the value changes, so operators can bump the verbosity
+// without a redeploy. the variable is checked at once, then every interval,
+// or on SIGHUP if interval is not positive. the goroutine exits when ctx is
+// done.
+//
+// an empty value leaves the level unchanged. an invalid value is ignored
+// with a WARN message, which is output once until the value changes.
+func WatchEnvLevel(
+	ctx context.Context, l Logger, varname string, interval time.Duration,
+) {
+	last := ""
+	check := func() {
+		v := os.Getenv(varname)
+		if v == last {
+			return
+		}
+		last = v
+		if v == "" {
+			return
+		}
+		lv, err := ParseLevel(v)
+		if err != nil {
+			l.War(varname, "ignored:", err)
+			return
+		}
+		l.SetLevel(lv)
+	}
+	// the signal is watched before returning, so a SIGHUP sent right after
+	// the call doesn't terminate the program
+	var hup chan os.Signal
+	if interval <= 0 {
+		hup = make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+	}
+	go func() {
+		var tick <-chan time.Time
+		if hup != nil {
+			defer signal.Stop(hup)
+		} else {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+		check()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tick:
+				check()
+			case <-hup:
+				check()
+			}
+		}
+	}()
+}
+
+// LevelReporter is implemented by the loggers which report their level, such
+// as the loggers created by New
+type LevelReporter interface {
+	// Level returns the current minimum level of the logger
+	Level() LogLevel
+}
+
+// errNotLevelReporter is returned by ListenLevelSignal for the loggers
+// without LevelReporter
+var errNotLevelReporter = errors.New("nekomimi: logger doesn't report its level")
+
+// ListenLevelSignal installs the handlers of the signals up and down, e.g.
+// SIGUSR1 and SIGUSR2, until ctx is done. receiving up lowers the level of l
+// one step toward TRACE (more logs), down raises it toward FATAL. each change
+// is logged at INFO level:
+//
+//	nekomimi.ListenLevelSignal(ctx, logger, syscall.SIGUSR1, syscall.SIGUSR2)
+//
+// returns error if l doesn't implement LevelReporter, the step needs the
+// current level.
+func ListenLevelSignal(
+	ctx context.Context, l Logger, up, down os.Signal,
+) error {
+	lr, ok := l.(LevelReporter)
+	if !ok {
+		return errNotLevelReporter
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, up, down)
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-ch:
+				old := lr.Level()
+				lv := old
+				if sig == up && old > TRACE {
+					lv = old - 1
+				} else if sig == down && old < FATAL {
+					lv = old + 1
+				}
+				if lv == old {
+					continue
+				}
+				// log at the lower of both levels, so the change is visible
+				// whenever INFO is enabled before or after it
+				if lv < old {
+					l.SetLevel(lv)
+				}
+				l.Inf("level changed from", old, "to", lv, "by", sig)
+				if lv > old {
+					l.SetLevel(lv)
+				}
+			}
+		}
+	}()
+	return nil
+}
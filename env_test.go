@@ -0,0 +1,248 @@
+package nekomimi
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNewFromEnv(t *testing.T) {
+	Convey("NewFromEnv tests", t, func() {
+		for _, key := range []string{
+			EnvLevel, EnvFormat, EnvTimeFormat, EnvFile, EnvCallTraceLevel,
+		} {
+			t.Setenv(key, "")
+		}
+		path := filepath.Join(t.TempDir(), "app.log")
+		readLines := func() []string {
+			data, err := os.ReadFile(path)
+			So(err, ShouldBeNil)
+			return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+		}
+
+		Convey("Defaults", func() {
+			l, err := NewFromEnv("App")
+			So(err, ShouldBeNil)
+			lg := l.(*logger)
+			So(lg.level, ShouldEqual, DEBUG)
+			So(lg.levelct, ShouldEqual, DEBUG)
+			So(lg.timefmt, ShouldEqual, "2006-01-02 15:04:05.000")
+			So(l.Name(), ShouldEqual, "App")
+		})
+
+		Convey("Levels and console file output", func() {
+			t.Setenv(EnvLevel, "warn")
+			t.Setenv(EnvCallTraceLevel, "FATAL")
+			t.Setenv(EnvTimeFormat, "15:04:05")
+			t.Setenv(EnvFile, path)
+			l, err := NewFromEnv("App")
+			So(err, ShouldBeNil)
+			lg := l.(*logger)
+			So(lg.level, ShouldEqual, WARN)
+			So(lg.levelct, ShouldEqual, FATAL)
+			So(lg.timefmt, ShouldEqual, "15:04:05")
+			l.Inf("dropped")
+			l.Err("console line")
+			lines := readLines()
+			So(len(lines), ShouldEqual, 1)
+			So(lines[0], ShouldEndWith, "[ERROR], App - console line")
+		})
+
+		Convey("TRACE level", func() {
+			t.Setenv(EnvLevel, "trace")
+			l, err := NewFromEnv("App")
+			So(err, ShouldBeNil)
+			So(l.(*logger).level, ShouldEqual, TRACE)
+		})
+
+		Convey("JSON format", func() {
+			t.Setenv(EnvFormat, "json")
+			t.Setenv(EnvFile, path)
+			l, err := NewFromEnv("App")
+			So(err, ShouldBeNil)
+			l.Derive("DB").War("slow query")
+			lines := readLines()
+			So(len(lines), ShouldEqual, 1)
+			rec := map[string]any{}
+			So(json.Unmarshal([]byte(lines[0]), &rec), ShouldBeNil)
+			So(rec["level"], ShouldEqual, "WARN")
+			So(rec["prefix"], ShouldEqual, "App.DB")
+			So(rec["msg"], ShouldEqual, "slow query")
+		})
+
+		Convey("Logfmt format", func() {
+			t.Setenv(EnvFormat, "LOGFMT")
+			t.Setenv(EnvCallTraceLevel, "fatal")
+			t.Setenv(EnvFile, path)
+			l, err := NewFromEnv("App")
+			So(err, ShouldBeNil)
+			tl := l.Trace("REQ")
+			tl.SetBaggage("tenant", "acme")
+			tl.Inf("hello world")
+			lines := readLines()
+			So(len(lines), ShouldEqual, 1)
+			So(lines[0], ShouldStartWith, "time=")
+			So(lines[0], ShouldEndWith,
+				" level=INFO prefix=App trace_name=REQ trace="+tl.TraceID()+
+					` tenant=acme msg="hello world"`)
+
+			// the fields named like a key of the record are prefixed
+			tl.SetBaggage("level", "x")
+			tl.SetBaggage("msg", "y")
+			l.WithMeta("trace", 2).Inf("clash")
+			tl.Inf("clash")
+			lines = readLines()
+			So(lines[1], ShouldEndWith, " level=INFO prefix=App meta.trace=2 msg=clash")
+			So(lines[2], ShouldEndWith,
+				" tenant=acme fields.level=x fields.msg=y msg=clash")
+		})
+
+		Convey("The file is flushed and closed", func() {
+			t.Setenv(EnvFile, path)
+			for _, format := range []string{"console", "json"} {
+				t.Setenv(EnvFormat, format)
+				l, err := NewFromEnv("App")
+				So(err, ShouldBeNil)
+				l.Inf(format)
+				So(l.Flush(), ShouldBeNil)
+				So(shutdownLogger(l), ShouldBeNil)
+				l.Inf("after close")
+			}
+			lines := readLines()
+			So(len(lines), ShouldEqual, 2)
+			So(lines[0], ShouldContainSubstring, "[INFO], App")
+			So(lines[0], ShouldEndWith, " - console")
+			So(lines[1], ShouldContainSubstring, `"msg":"json"`)
+		})
+
+		Convey("Invalid values", func() {
+			t.Setenv(EnvLevel, "verbose")
+			_, err := NewFromEnv("App")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, EnvLevel)
+
+			t.Setenv(EnvLevel, "")
+			t.Setenv(EnvCallTraceLevel, "sometimes")
+			_, err = NewFromEnv("App")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, EnvCallTraceLevel)
+
+			t.Setenv(EnvCallTraceLevel, "")
+			t.Setenv(EnvFormat, "xml")
+			_, err = NewFromEnv("App")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, EnvFormat)
+
+			t.Setenv(EnvFormat, "")
+			t.Setenv(EnvFile, filepath.Join(path, "missing", "app.log"))
+			_, err = NewFromEnv("App")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestWatchEnvLevel(t *testing.T) {
+	Convey("WatchEnvLevel tests", t, func() {
+		const key = "NEKO_TEST_WATCH_LEVEL"
+		t.Setenv(key, "info")
+		rec := &captureLogHandler{}
+		l := New("App", LogConfig{Handler: rec.handler(), TestMode: true})
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		level := l.(LevelReporter).Level
+
+		Convey("Polling", func() {
+			WatchEnvLevel(ctx, l, key, 5*time.Millisecond)
+			So(waitFor(func() bool { return level() == INFO }), ShouldBeTrue)
+			os.Setenv(key, "error")
+			So(waitFor(func() bool { return level() == ERROR }), ShouldBeTrue)
+
+			// a manual change is kept until the variable changes
+			l.SetLevel(DEBUG)
+			time.Sleep(20 * time.Millisecond)
+			So(level(), ShouldEqual, DEBUG)
+
+			os.Setenv(key, "loud")
+			So(waitFor(func() bool { return rec.count() == 1 }), ShouldBeTrue)
+			time.Sleep(20 * time.Millisecond)
+			So(rec.count(), ShouldEqual, 1) // warned once
+			So(rec.last(), ShouldStartWith, "2000-01-01 00:00:00.000 [WARN], "+
+				"App - NEKO_TEST_WATCH_LEVEL ignored: ")
+			So(rec.last(), ShouldContainSubstring, "loud")
+			So(level(), ShouldEqual, DEBUG)
+
+			cancel()
+			time.Sleep(20 * time.Millisecond)
+			os.Setenv(key, "fatal")
+			time.Sleep(20 * time.Millisecond)
+			So(level(), ShouldEqual, DEBUG)
+		})
+
+		Convey("SIGHUP", func() {
+			WatchEnvLevel(ctx, l, key, 0)
+			So(waitFor(func() bool { return level() == INFO }), ShouldBeTrue)
+			os.Setenv(key, "warn")
+			p, err := os.FindProcess(os.Getpid())
+			So(err, ShouldBeNil)
+			So(p.Signal(syscall.SIGHUP), ShouldBeNil)
+			So(waitFor(func() bool { return level() == WARN }), ShouldBeTrue)
+		})
+	})
+}
+
+func TestListenLevelSignal(t *testing.T) {
+	Convey("ListenLevelSignal tests", t, func() {
+		rec := &captureLogHandler{}
+		l := New("App", LogConfig{Handler: rec.handler(), TestMode: true})
+		l.SetLevel(INFO)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		level := l.(LevelReporter).Level
+		p, err := os.FindProcess(os.Getpid())
+		So(err, ShouldBeNil)
+
+		// the level of a wrapped logger is unknown
+		wrapped := struct{ Logger }{l}
+		So(ListenLevelSignal(ctx, wrapped, syscall.SIGUSR1, syscall.SIGUSR2),
+			ShouldEqual, errNotLevelReporter)
+
+		So(ListenLevelSignal(ctx, l, syscall.SIGUSR1, syscall.SIGUSR2),
+			ShouldBeNil)
+		So(p.Signal(syscall.SIGUSR1), ShouldBeNil)
+		So(waitFor(func() bool { return level() == DEBUG }), ShouldBeTrue)
+		So(rec.last(), ShouldStartWith, "2000-01-01 00:00:00.000 [INFO], "+
+			"App - level changed from INFO to DEBUG by ")
+
+		So(p.Signal(syscall.SIGUSR2), ShouldBeNil)
+		So(waitFor(func() bool { return level() == INFO }), ShouldBeTrue)
+		So(p.Signal(syscall.SIGUSR2), ShouldBeNil)
+		So(waitFor(func() bool { return level() == WARN }), ShouldBeTrue)
+		So(waitFor(func() bool { return rec.count() == 3 }), ShouldBeTrue)
+		So(rec.last(), ShouldContainSubstring, "from INFO to WARN")
+
+		// the level stops at FATAL
+		l.SetLevel(FATAL)
+		So(p.Signal(syscall.SIGUSR2), ShouldBeNil)
+		time.Sleep(20 * time.Millisecond)
+		So(level(), ShouldEqual, FATAL)
+		So(rec.count(), ShouldEqual, 3)
+
+		// the handlers are removed once ctx is done, SIGUSR1 is ignored
+		// meanwhile so it doesn't terminate the test
+		cancel()
+		time.Sleep(20 * time.Millisecond)
+		signal.Ignore(syscall.SIGUSR1)
+		defer signal.Reset(syscall.SIGUSR1)
+		So(p.Signal(syscall.SIGUSR1), ShouldBeNil)
+		time.Sleep(20 * time.Millisecond)
+		So(level(), ShouldEqual, FATAL)
+	})
+}
@@ -0,0 +1,132 @@
+package nekomimi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// verboseError wraps an error message argument, so it's rendered with %+v
+// by fmt. error libraries like pkg/errors print the stack with %+v.
+type verboseError struct {
+	err error
+}
+
+// Format renders the error with %+v for the verbs 'v' and 's', other verbs
+// are passed through
+func (ve verboseError) Format(f fmt.State, verb rune) {
+	switch verb {
+	case 'v', 's':
+		fmt.Fprintf(f, "%+v", ve.err)
+	default:
+		fmt.Fprintf(f, fmt.FormatString(f, verb), ve.err)
+	}
+}
+
+// Error returns the plain error message
+func (ve verboseError) Error() string {
+	return ve.err.Error()
+}
+
+// Unwrap returns the wrapped error
+func (ve verboseError) Unwrap() error {
+	return ve.err
+}
+
+// verboseArgs returns a copy of args with the errors wrapped as
+// verboseError. args is returned as is if there is no error.
+func verboseArgs(args []any) []any {
+	var out []any
+	for i, a := range args {
+		err, ok := a.(error)
+		if !ok {
+			continue
+		}
+		if _, ok := err.(verboseError); ok {
+			continue
+		}
+		if out == nil {
+			out = make([]any, len(args))
+			copy(out, args)
+		}
+		out[i] = verboseError{err}
+	}
+	if out == nil {
+		return args
+	}
+	return out
+}
+
+// splitVerboseArgs unwraps the verboseError arguments. returns the plain
+// arguments and the verbose form of the errors joined by newlines, which is
+// empty if there is no verboseError.
+func splitVerboseArgs(args []any) ([]any, string) {
+	var out []any
+	var verbose []string
+	for i, a := range args {
+		ve, ok := a.(verboseError)
+		if !ok {
+			continue
+		}
+		if out == nil {
+			out = make([]any, len(args))
+			copy(out, args)
+		}
+		out[i] = ve.err
+		verbose = append(verbose, fmt.Sprintf("%+v", ve.err))
+	}
+	if out == nil {
+		return args, ""
+	}
+	return out, strings.Join(verbose, "\n")
+}
+
+// errorChain returns the messages of err and the errors it wraps, depth
+// first. both `Unwrap() error` and `Unwrap() []error` are followed.
+func errorChain(err error) []string {
+	var chain []string
+	var walk func(err error)
+	walk = func(err error) {
+		for err != nil {
+			chain = append(chain, err.Error())
+			switch u := err.(type) {
+			case interface{ Unwrap() []error }:
+				for _, e := range u.Unwrap() {
+					walk(e)
+				}
+				return
+			case interface{ Unwrap() error }:
+				err = u.Unwrap()
+			default:
+				return
+			}
+		}
+	}
+	walk(err)
+	return chain
+}
+
+// causeFields returns the Unwrap chains of the error arguments of panic and
+// fatal messages as the header fields `cause.0`, `cause.1`..., outermost
+// first, so the root cause is kept by structured sinks. errors which wrap
+// nothing are skipped, their message is the body already.
+func causeFields(args []any) []Field {
+	var fields []Field
+	for _, a := range args {
+		err, ok := a.(error)
+		if !ok {
+			continue
+		}
+		chain := errorChain(err)
+		if len(chain) < 2 {
+			continue
+		}
+		for _, c := range chain {
+			fields = append(fields, Field{
+				Key:   "cause." + strconv.Itoa(len(fields)),
+				Value: c,
+			})
+		}
+	}
+	return fields
+}
@@ -0,0 +1,121 @@
+package nekomimi
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// stackError mimics the errors of pkg/errors, the stack is printed only
+// with %+v
+type stackError struct {
+	msg string
+}
+
+func (e *stackError) Error() string {
+	return e.msg
+}
+
+func (e *stackError) Format(s fmt.State, verb rune) {
+	if verb == 'v' && s.Flag('+') {
+		io.WriteString(s, e.msg+"\nmain.handler\n\tserver.go:42")
+		return
+	}
+	io.WriteString(s, e.msg)
+}
+
+func TestVerboseErrors(t *testing.T) {
+	Convey("Verbose errors tests", t, func() {
+		err := &stackError{msg: "connection reset"}
+
+		Convey("Default output has no stack", func() {
+			rec := &captureLogHandler{}
+			l := New("Test", LogConfig{Handler: rec.handler()})
+			l.Err("failed:", err)
+			So(rec.last(), ShouldEndWith, "failed: connection reset\n")
+		})
+
+		Convey("Verbose output prints stack", func() {
+			rec := &captureLogHandler{}
+			l := New("Test", LogConfig{
+				Handler:       rec.handler(),
+				VerboseErrors: true,
+			})
+			l.Err("failed:", err)
+			So(rec.last(), ShouldContainSubstring,
+				"failed: connection reset\nmain.handler\n\tserver.go:42")
+			l.Errf("failed: %v (%q)", err, err)
+			So(rec.last(), ShouldContainSubstring,
+				"failed: connection reset\nmain.handler\n\tserver.go:42 "+
+					fmt.Sprintf("(%q)", err))
+			l.Derive("sub").Trace("req").Err(err)
+			So(rec.last(), ShouldContainSubstring, "main.handler")
+			l.Inf("no error", 42)
+			So(rec.last(), ShouldEndWith, "no error 42\n")
+		})
+
+		Convey("Record receives verbose form separately", func() {
+			var got Record
+			l := New("Test", LogConfig{
+				Handler: RecordLogHandlerFunc(func(rec Record) {
+					got = rec
+				}),
+				VerboseErrors: true,
+			})
+			l.Err("failed:", err)
+			So(got.Message, ShouldEqual, "failed: connection reset")
+			So(got.ErrorVerbose, ShouldEqual,
+				"connection reset\nmain.handler\n\tserver.go:42")
+			data, jerr := got.MarshalJSON()
+			So(jerr, ShouldBeNil)
+			So(string(data), ShouldContainSubstring,
+				`"error_verbose":"connection reset\nmain.handler`)
+		})
+	})
+}
+
+func TestPanicCauses(t *testing.T) {
+	Convey("Panic cause chain tests", t, func() {
+		base := errors.New("disk full")
+		var recs []Record
+		l := New("App", LogConfig{
+			Handler: RecordLogHandlerFunc(func(rec Record) {
+				recs = append(recs, rec)
+			}),
+		})
+
+		Convey("Wrapped error renders the chain", func() {
+			l.Panic(fmt.Errorf("wrap: %w", base))
+			So(len(recs), ShouldEqual, 1)
+			So(recs[0].Level, ShouldEqual, PANIC)
+			So(recs[0].Fields, ShouldResemble, []Field{
+				{Key: "cause.0", Value: "wrap: disk full"},
+				{Key: "cause.1", Value: "disk full"},
+			})
+			So(recs[0].Message, ShouldEqual, "wrap: disk full")
+			So(len(recs[0].Stack), ShouldBeGreaterThan, 0)
+		})
+
+		Convey("Joined errors are walked depth first", func() {
+			l.Fatal("shutdown:", fmt.Errorf("save: %w",
+				errors.Join(base, errors.New("timeout"))))
+			So(len(recs), ShouldEqual, 1)
+			So(recs[0].Fields, ShouldResemble, []Field{
+				{Key: "cause.0", Value: "save: disk full\ntimeout"},
+				{Key: "cause.1", Value: "disk full\ntimeout"},
+				{Key: "cause.2", Value: "disk full"},
+				{Key: "cause.3", Value: "timeout"},
+			})
+		})
+
+		Convey("Plain errors and values have no causes", func() {
+			l.Panic(base, "boom")
+			So(len(recs), ShouldEqual, 1)
+			So(recs[0].Fields, ShouldBeEmpty)
+			So(errorChain(nil), ShouldBeEmpty)
+		})
+	})
+}
@@ -0,0 +1,143 @@
+package nekomimi
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// exemplarHandler is the LogHandler returned by NewExemplarLogHandler
+type exemplarHandler struct {
+	wrap   LogHandler
+	window time.Duration
+	clock  Clock
+
+	mtx   sync.Mutex
+	start time.Time           // start of the current window
+	seen  map[string]struct{} // templates forwarded in the current window
+
+	dropped atomic.Uint64
+}
+
+// NewExemplarLogHandler creates a LogHandler which forwards one exemplar of
+// each distinct message template per window to wrapped and drops the other
+// messages of the template silently (they are counted, see DropCounter), so
+// every distinct issue is seen once under volume. the template is the
+// message body with the digit runs replaced by `#`, keyed together with the
+// level, e.g. `user 42 not found` and `user 7 not found` share a template.
+//
+// the windows are fixed: all the templates are forgotten when window
+// elapses since the start of the current window. panic and fatal messages
+// are never dropped.
+func NewExemplarLogHandler(wrap LogHandler, window time.Duration) LogHandler {
+	return &exemplarHandler{
+		wrap:   wrap,
+		window: window,
+		clock:  RealClock,
+		seen:   map[string]struct{}{},
+	}
+}
+
+// messageTemplate returns the body of a formatted log line with the digit
+// runs replaced by '#'. the body follows the ` - ` after the level tag, the
+// whole line without the timestamp is used if the header is not
+// recognizable.
+func messageTemplate(line string) string {
+	body := trimTimestamp(line)
+	if _, rb, ok := findLevelTag(line); ok {
+		if idx := strings.Index(line[rb:], " - "); idx >= 0 {
+			body = line[rb+idx+3:]
+		}
+	}
+	sb := strings.Builder{}
+	digits := false
+	for _, r := range body {
+		if r >= '0' && r <= '9' {
+			if !digits {
+				sb.WriteByte('#')
+			}
+			digits = true
+			continue
+		}
+		digits = false
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// allow reports whether the line is the first of its template in the
+// current window
+func (eh *exemplarHandler) allow(level LogLevel, line string) bool {
+	if level >= PANIC {
+		return true
+	}
+	key := fmt.Sprintf("%d:%s", level, messageTemplate(line))
+	eh.mtx.Lock()
+	defer eh.mtx.Unlock()
+	now := eh.clock.Now()
+	if now.Sub(eh.start) >= eh.window {
+		eh.start = now
+		clear(eh.seen)
+	}
+	if _, ok := eh.seen[key]; ok {
+		return false
+	}
+	eh.seen[key] = struct{}{}
+	return true
+}
+
+// Dropped returns the number of messages dropped as duplicates of a
+// template
+func (eh *exemplarHandler) Dropped() uint64 {
+	return eh.dropped.Load()
+}
+
+func (eh *exemplarHandler) IsShutdown() bool {
+	return eh.wrap.IsShutdown()
+}
+
+func (eh *exemplarHandler) RegularWriter(
+	level LogLevel, pnt func(io.StringWriter),
+) {
+	sb := strings.Builder{}
+	pnt(&sb)
+	line := sb.String()
+	if !eh.allow(level, line) {
+		eh.dropped.Add(1)
+		return
+	}
+	eh.wrap.RegularWriter(level, func(w io.StringWriter) {
+		w.WriteString(line)
+	})
+}
+
+func (eh *exemplarHandler) RegularLog(
+	level LogLevel, header string, message ...any,
+) {
+	if !eh.allow(level, header+fmt.Sprintln(message...)) {
+		eh.dropped.Add(1)
+		return
+	}
+	eh.wrap.RegularLog(level, header, message...)
+}
+
+func (eh *exemplarHandler) PanicLog(header string, message ...any) {
+	eh.wrap.PanicLog(header, message...)
+}
+
+func (eh *exemplarHandler) FatalLog(header string, message ...any) {
+	eh.wrap.FatalLog(header, message...)
+}
+
+// Flush flushes the wrapped handler
+func (eh *exemplarHandler) Flush() error {
+	return flushHandler(eh.wrap)
+}
+
+// Close closes the wrapped handler
+func (eh *exemplarHandler) Close() error {
+	return closeHandler(eh.wrap)
+}
@@ -0,0 +1,47 @@
+package nekomimi
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestExemplarLogHandler(t *testing.T) {
+	Convey("Exemplar handler tests", t, func() {
+		sink := &captureLogHandler{}
+		h := NewExemplarLogHandler(sink.handler(), time.Minute)
+		clock := NewMockClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+		h.(*exemplarHandler).clock = clock
+		l := New("App", LogConfig{Handler: h, TestMode: true})
+
+		Convey("One message per template passes in a window", func() {
+			for i := range 10 {
+				l.Dbgf("user %d not found", i)
+				l.Dbgf("cache miss for key k%d", i*7)
+				l.Dbg("connection reset")
+			}
+			l.Inf("connection reset") // different level
+			So(sink.lines, ShouldResemble, []string{
+				"2000-01-01 00:00:00.000 [DEBUG], App - user 0 not found\n",
+				"2000-01-01 00:00:00.000 [DEBUG], App - cache miss for key k0\n",
+				"2000-01-01 00:00:00.000 [DEBUG], App - connection reset\n",
+				"2000-01-01 00:00:00.000 [INFO], App - connection reset\n",
+			})
+			So(h.(DropCounter).Dropped(), ShouldEqual, 27)
+
+			clock.Advance(time.Minute)
+			l.Dbgf("user %d not found", 99)
+			So(sink.last(), ShouldEndWith, "- user 99 not found\n")
+			l.Dbgf("user %d not found", 100)
+			So(sink.count(), ShouldEqual, 5)
+		})
+
+		Convey("Panic is never dropped", func() {
+			l.Panic("boom")
+			l.Panic("boom")
+			So(sink.count(), ShouldEqual, 2)
+			So(h.(DropCounter).Dropped(), ShouldEqual, 0)
+		})
+	})
+}
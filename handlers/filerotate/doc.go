@@ -10,10 +10,14 @@
 //
 //   - Automatic rotation by max file size, max log entries, or max file TTL
 //   - Timestamp-based archive naming (prefix_yymmdd_seconds.log)
-//   - Optional gzip compression of archived files
+//   - Optional compression of archived files, gzip by default or any
+//     streaming codec plugged in by Codec
 //   - Fallback file naming when primary name is unavailable
 //   - Suspended state with automatic audit recovery
 //   - Synchronous panic/fatal writes with forced fsync before crash
+//   - Write error reporting (OnError) with an optional Fallback writer,
+//     the log file is retried on every tick
+//   - Optional FileHeader line written once at the start of each new file
 //
 // # Usage
 //
@@ -60,8 +60,11 @@ type Config struct {
 	// MaxArchives is the maximum number of archived log files to retain.
 	// When exceeded, the oldest archives are deleted. 0 means no limit.
 	MaxArchives int
-	// Compress enables gzip compression for archived log files.
+	// Compress enables compression for archived log files.
 	Compress bool
+	// Codec is the compression codec used when Compress is set. nil means
+	// GzipCodec.
+	Codec *Codec
 	// RotatePanic causes a panic if log file rotation fails.
 	// When false, the handler suspends writes instead of crashing.
 	RotatePanic bool
@@ -72,6 +75,22 @@ type Config struct {
 	// Wrapper is an optional LogHandler that receives log messages before
 	// this handler does.
 	Wrapper nekomimi.LogHandler
+	// OnError is an optional callback invoked when writing to the log file
+	// fails, e.g. the disk is full (ENOSPC). The error wraps the original
+	// one, so errors.Is works. It is called with the handler lock held and
+	// must not log through this handler.
+	OnError func(error)
+	// Fallback is an optional writer (e.g. os.Stderr, or a file on another
+	// disk) receiving log messages while writes to the log file fail. The
+	// log file is retried on the next tick.
+	Fallback io.Writer
+	// FileHeader is an optional function returning a header (e.g. start
+	// time, version and format) written once at the beginning of each new
+	// log file, including the files created by rotation. It follows the
+	// #created stamp line; a trailing newline is added if missing. Log
+	// files are always created empty, so the header is never written in
+	// the middle of a file.
+	FileHeader func() string
 
 	// testTickCh is an optional channel for triggering ticker events in
 	// tests. When set, the handler uses this channel instead of a real
@@ -88,6 +107,34 @@ type Config struct {
 	// compression goroutine enters its body, signalling the test
 	// that compression work is in-flight. Only used by tests.
 	testCompressStarted chan struct{}
+	// testWriter replaces the log file as the write target in tests.
+	testWriter io.StringWriter
+}
+
+// Codec is a streaming compression codec for archived log files, e.g.
+// gzip, zstd or lz4.
+type Codec struct {
+	// Ext is the file extension appended to the compressed archives, e.g.
+	// ".gz". it must start with a dot and contain no other dot.
+	Ext string
+	// NewWriter returns a writer compressing the data written to w. Close
+	// of the returned writer must finish the stream, but not close w.
+	NewWriter func(w io.Writer) (io.WriteCloser, error)
+	// Flush is an optional hook flushing the data buffered by the writer of
+	// NewWriter to w, so the compressed data written so far is readable.
+	// it's called before the writer is closed.
+	Flush func(w io.WriteCloser) error
+}
+
+// GzipCodec is the default Codec, compressing with compress/gzip.
+var GzipCodec = &Codec{
+	Ext: ".gz",
+	NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+		return gzip.NewWriter(w), nil
+	},
+	Flush: func(w io.WriteCloser) error {
+		return w.(*gzip.Writer).Flush()
+	},
 }
 
 // handler implements the file rotation log handler using LogHandlerFunc.
@@ -112,6 +159,9 @@ type handler struct {
 	currentName string
 	// lastFlushCount tracks byteCount at last flush
 	lastFlushCount int64
+	// writeFailed is set when a write to the log file fails. Log
+	// messages go to cfg.Fallback until the next tick retries the file.
+	writeFailed bool
 
 	// archive-name deduplication: when multiple rotations happen
 	// within the same calendar second, a monotonic counter avoids
@@ -148,15 +198,30 @@ type handler struct {
 type countWriter struct {
 	w     io.StringWriter
 	count *int64
+	// err is the first write error
+	err error
 }
 
 // WriteString writes string data and increments the byte counter.
 func (cw *countWriter) WriteString(s string) (int, error) {
 	n, err := cw.w.WriteString(s)
 	*cw.count += int64(n)
+	if err != nil && cw.err == nil {
+		cw.err = err
+	}
 	return n, err
 }
 
+// fallbackWriter adapts the Fallback io.Writer to io.StringWriter.
+type fallbackWriter struct {
+	w io.Writer
+}
+
+// WriteString writes string data to the fallback writer.
+func (fw fallbackWriter) WriteString(s string) (int, error) {
+	return io.WriteString(fw.w, s)
+}
+
 // New creates a new file rotation log handler. It returns an error if
 // the target directory cannot be created or the log file cannot be opened.
 // The ctx controls the lifetime of background tasks (ticker, compression).
@@ -325,8 +390,7 @@ func buildCounterMap(
 func parseArchiveSecAndCounter(
 	name, prefix string,
 ) (int64, int) {
-	base := strings.TrimSuffix(
-		strings.TrimSuffix(name, ".gz"), ".log")
+	base := trimArchiveExt(name)
 	rest := base[len(prefix)+1:]
 	parts := strings.SplitN(rest, "_", 3)
 	if len(parts) < 2 {
@@ -442,6 +506,10 @@ func (h *handler) openNewFile(
 		fp.Close()
 		return nil, fmt.Errorf("write created stamp: %w", err)
 	}
+	if err := h.writeFileHeader(fp); err != nil {
+		fp.Close()
+		return nil, fmt.Errorf("write file header: %w", err)
+	}
 	return fp, nil
 }
 
@@ -454,6 +522,53 @@ func (h *handler) writeCreatedStamp(
 	return err
 }
 
+// writeFileHeader writes the Config.FileHeader to a new file, if any.
+func (h *handler) writeFileHeader(fp *os.File) error {
+	if h.cfg.FileHeader == nil {
+		return nil
+	}
+	header := h.cfg.FileHeader()
+	if header == "" {
+		return nil
+	}
+	if !strings.HasSuffix(header, "\n") {
+		header += "\n"
+	}
+	_, err := fp.WriteString(header)
+	return err
+}
+
+// output returns the current write target. Must be called with mu held.
+func (h *handler) output() io.StringWriter {
+	if h.cfg.testWriter != nil {
+		return h.cfg.testWriter
+	}
+	return h.fp
+}
+
+// writeEntry writes a log entry to the log file. If the write fails, the
+// error is reported and the entry goes to the fallback writer, as do the
+// following entries until the next tick. Returns true if the entry was
+// written to the log file. Must be called with mu held.
+func (h *handler) writeEntry(pnt func(io.StringWriter)) bool {
+	if !h.writeFailed {
+		cw := &countWriter{w: h.output(), count: &h.byteCount}
+		pnt(cw)
+		if cw.err == nil {
+			return true
+		}
+		h.writeFailed = true
+		if h.cfg.OnError != nil {
+			h.cfg.OnError(fmt.Errorf(
+				"filerotate: write %s: %w", h.currentName, cw.err))
+		}
+	}
+	if h.cfg.Fallback != nil {
+		pnt(fallbackWriter{h.cfg.Fallback})
+	}
+	return false
+}
+
 // regularLogFunc writes a log entry with byte counting and rotation check.
 func (h *handler) regularLogFunc(
 	_ nekomimi.LogLevel, pnt func(io.StringWriter),
@@ -461,8 +576,9 @@ func (h *handler) regularLogFunc(
 	if h.state != stateActive || h.fp == nil {
 		return
 	}
-	cw := &countWriter{w: h.fp, count: &h.byteCount}
-	pnt(cw)
+	if !h.writeEntry(pnt) {
+		return
+	}
 	h.itemCount++
 	if h.shouldRotate() {
 		h.rotate()
@@ -475,7 +591,7 @@ func (h *handler) panicLogFunc(
 	pnt func(io.StringWriter), info string,
 ) func() {
 	if h.state == stateActive && h.fp != nil {
-		pnt(h.fp)
+		h.writeEntry(pnt)
 		h.fp.Sync()
 	}
 	if h.cfg.WrapOnly {
@@ -490,7 +606,7 @@ func (h *handler) fatalLogFunc(
 	pnt func(io.StringWriter),
 ) func() {
 	if h.state == stateActive && h.fp != nil {
-		pnt(h.fp)
+		h.writeEntry(pnt)
 		h.fp.Sync()
 	}
 	if h.cfg.WrapOnly {
@@ -675,6 +791,9 @@ func (h *handler) onTick() {
 			return
 		}
 
+		// retry the log file after write failures
+		h.writeFailed = false
+
 		// flush if there is new data
 		if h.fp != nil && h.state == stateActive &&
 			h.byteCount != h.lastFlushCount {
@@ -846,13 +965,26 @@ func (h *handler) cleanArchives(skipKeys map[string]struct{}) {
 	}
 }
 
+// trimArchiveExt removes the ".log" suffix and the codec extension which
+// might follow it, e.g. ".log.gz". name is returned unchanged otherwise.
+func trimArchiveExt(name string) string {
+	idx := strings.LastIndex(name, ".log")
+	if idx < 0 {
+		return name
+	}
+	ext := name[idx+len(".log"):]
+	if ext != "" && (ext == ".tmp" || strings.Count(ext, ".") != 1 ||
+		!strings.HasPrefix(ext, ".")) {
+		return name
+	}
+	return name[:idx]
+}
+
 // isArchiveFile reports whether name matches the archive file pattern:
-// <prefix>_<yymmdd>_<seconds>_<counter>.log[.gz]
+// <prefix>_<yymmdd>_<seconds>_<counter>.log[.<codec ext>]
 // counter is a zero-padded 4-digit sequence within the same second.
 func isArchiveFile(name, prefix string) bool {
-	base := name
-	base = strings.TrimSuffix(base, ".gz")
-	base = strings.TrimSuffix(base, ".log")
+	base := trimArchiveExt(name)
 
 	p := prefix + "_"
 	if !strings.HasPrefix(base, p) {
@@ -904,8 +1036,7 @@ func (h *handler) matchLogFile(name string) bool {
 // as a grouping key.  For "prefix_260627_45045.log" or ".log.gz" it
 // returns "260627_45045".  Returns "" for non-archive filenames.
 func extractTimestampKey(name string) string {
-	name = strings.TrimSuffix(name, ".gz")
-	name = strings.TrimSuffix(name, ".log")
+	name = trimArchiveExt(name)
 	idx := strings.Index(name, "_")
 	if idx < 0 {
 		return ""
@@ -913,9 +1044,17 @@ func extractTimestampKey(name string) string {
 	return name[idx+1:]
 }
 
-// compressFile gzip-compresses an archive file.  It writes to a temp
-// file first (.log.gz.tmp) then atomically renames to .log.gz to avoid
-// leaving a partial .gz that audit could confuse as complete.  While
+// codec returns the compression codec of the archives
+func (h *handler) codec() *Codec {
+	if h.cfg.Codec != nil {
+		return h.cfg.Codec
+	}
+	return GzipCodec
+}
+
+// compressFile compresses an archive file with the codec.  It writes to a
+// temp file first (e.g. .log.gz.tmp) then atomically renames to .log.gz to
+// avoid leaving a partial .gz that audit could confuse as complete.  While
 // compression is in progress the file's timestamp key is registered in
 // h.compressing so that cleanArchives skips it.
 func (h *handler) compressFile(path string) {
@@ -937,19 +1076,29 @@ func (h *handler) compressFile(path string) {
 	}
 	defer in.Close()
 
-	outPath := path + ".gz"
+	codec := h.codec()
+	outPath := path + codec.Ext
 	tmpPath := outPath + ".tmp"
 	out, err := os.Create(tmpPath)
 	if err != nil {
 		return
 	}
 
-	gw := gzip.NewWriter(out)
-	_, copyErr := io.Copy(gw, in)
-	gwCloseErr := gw.Close()
+	cw, err := codec.NewWriter(out)
+	if err != nil {
+		out.Close()
+		os.Remove(tmpPath)
+		return
+	}
+	_, copyErr := io.Copy(cw, in)
+	var flushErr error
+	if copyErr == nil && codec.Flush != nil {
+		flushErr = codec.Flush(cw)
+	}
+	cwCloseErr := cw.Close()
 	out.Close()
 
-	if copyErr != nil || gwCloseErr != nil {
+	if copyErr != nil || flushErr != nil || cwCloseErr != nil {
 		os.Remove(tmpPath) // clean up partial temp
 		return
 	}
@@ -10,6 +10,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"testing"
 	"time"
 
@@ -449,6 +450,98 @@ func TestLogRotation_Compress(t *testing.T) {
 	assert.True(t, hasGz, "compressed .log.gz should exist")
 }
 
+// passthroughCodec stores the data as is and records the lifecycle calls
+type passthroughCodec struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+type passthroughWriter struct {
+	w     io.Writer
+	codec *passthroughCodec
+}
+
+func (pc *passthroughCodec) record(call string) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if n := len(pc.calls); n > 0 && pc.calls[n-1] == call {
+		return // collapse repeated writes
+	}
+	pc.calls = append(pc.calls, call)
+}
+
+func (pc *passthroughCodec) lifecycle() []string {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	return append([]string(nil), pc.calls...)
+}
+
+func (pw *passthroughWriter) Write(p []byte) (int, error) {
+	pw.codec.record("write")
+	return pw.w.Write(p)
+}
+
+func (pw *passthroughWriter) Close() error {
+	pw.codec.record("close")
+	return nil
+}
+
+func (pc *passthroughCodec) codec() *Codec {
+	return &Codec{
+		Ext: ".raw",
+		NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+			pc.record("new")
+			return &passthroughWriter{w: w, codec: pc}, nil
+		},
+		Flush: func(w io.WriteCloser) error {
+			pc.record("flush")
+			return nil
+		},
+	}
+}
+
+func TestLogRotation_CustomCodec(t *testing.T) {
+	dir := tempDir(t)
+	ctx := context.Background()
+	pc := &passthroughCodec{}
+
+	h, err := New(ctx, Config{
+		Path:        dir,
+		FilePrefix:  "app",
+		MaxFileSize: 1,
+		Compress:    true,
+		Codec:       pc.codec(),
+	})
+	require.NoError(t, err)
+
+	bigMsg := strings.Repeat("x", 2048)
+	h.RegularLog(nekomimi.INFO, "h ", bigMsg)
+
+	// Compression is async
+	require.Eventually(t, func() bool {
+		return len(pc.lifecycle()) == 4
+	}, 3*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"new", "write", "flush", "close"}, pc.lifecycle())
+
+	var archive string
+	require.Eventually(t, func() bool {
+		for _, f := range listFiles(t, dir) {
+			if strings.HasSuffix(f, ".log.raw") {
+				archive = f
+				return true
+			}
+		}
+		return false
+	}, 3*time.Second, 10*time.Millisecond)
+	assert.True(t, isArchiveFile(archive, "app"))
+	content := readFileContent(t, filepath.Join(dir, archive))
+	assert.True(t, strings.HasPrefix(content, "#created:"))
+	assert.Contains(t, content, bigMsg)
+	for _, f := range listFiles(t, dir) {
+		assert.False(t, strings.HasSuffix(f, ".tmp"), "temp file left: %s", f)
+	}
+}
+
 // ============================================================
 // TestLogRotation_FallbackNaming
 // ============================================================
@@ -1230,3 +1323,129 @@ func TestIsShutdown_WithCompressionInFlight(t *testing.T) {
 	}, 5*time.Second, 100*time.Millisecond,
 		"IsShutdown should become true after compression goroutine drains")
 }
+
+// diskWriter is a test write target which fails with ENOSPC while full
+// is set.
+type diskWriter struct {
+	mu   sync.Mutex
+	full bool
+	sb   strings.Builder
+}
+
+func (dw *diskWriter) WriteString(s string) (int, error) {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+	if dw.full {
+		return 0, &os.PathError{
+			Op: "write", Path: "app.log", Err: syscall.ENOSPC,
+		}
+	}
+	return dw.sb.WriteString(s)
+}
+
+func (dw *diskWriter) setFull(full bool) {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+	dw.full = full
+}
+
+func (dw *diskWriter) String() string {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+	return dw.sb.String()
+}
+
+// ============================================================
+// TestWriteError_FallbackAndRecover
+// ============================================================
+func TestWriteError_FallbackAndRecover(t *testing.T) {
+	dir := tempDir(t)
+	tickCh := make(chan time.Time, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	disk := &diskWriter{}
+	fallback := &strings.Builder{}
+	var errs []error
+	h, err := New(ctx, Config{
+		Path:       dir,
+		FilePrefix: "app",
+		Fallback:   fallback,
+		OnError:    func(err error) { errs = append(errs, err) },
+		testTickCh: tickCh,
+		testWriter: disk,
+	})
+	require.NoError(t, err)
+
+	h.RegularLog(nekomimi.INFO, "h ", "before full")
+	assert.Contains(t, disk.String(), "before full")
+
+	// Disk full → error reported once, fallback engages
+	disk.setFull(true)
+	h.RegularLog(nekomimi.INFO, "h ", "first lost")
+	h.RegularLog(nekomimi.INFO, "h ", "second lost")
+	require.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], syscall.ENOSPC)
+	assert.Equal(t, "h first lost\nh second lost\n", fallback.String())
+
+	// Tick retries the primary, still full → reported again
+	tickCh <- time.Now()
+	assert.Eventually(t, func() bool {
+		h.RegularLog(nekomimi.INFO, "h ", "retry")
+		return len(errs) == 2
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// Disk recovered → next tick switches back to the primary
+	disk.setFull(false)
+	tickCh <- time.Now()
+	assert.Eventually(t, func() bool {
+		h.RegularLog(nekomimi.INFO, "h ", "recovered")
+		return strings.Contains(disk.String(), "recovered")
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Len(t, errs, 2)
+	assert.NotContains(t, disk.String(), "lost")
+}
+
+// ============================================================
+// TestFileHeader_WrittenOncePerFile
+// ============================================================
+func TestFileHeader_WrittenOncePerFile(t *testing.T) {
+	dir := tempDir(t)
+	ctx := context.Background()
+
+	calls := 0
+	h, err := New(ctx, Config{
+		Path:         dir,
+		FilePrefix:   "test",
+		MaxFileItems: 100,
+		FileHeader: func() string {
+			calls++
+			return fmt.Sprintf("# app v1.2.3 format=console file=%d", calls)
+		},
+	})
+	require.NoError(t, err)
+
+	content := readFileContent(t, filepath.Join(dir, "test.log"))
+	lines := strings.Split(content, "\n")
+	require.GreaterOrEqual(t, len(lines), 2)
+	assert.True(t, strings.HasPrefix(lines[0], "#created:"))
+	assert.Equal(t, "# app v1.2.3 format=console file=1", lines[1])
+
+	// Fill the file and rotate, the new file gets its own header
+	for i := 0; i < 102; i++ {
+		h.RegularLog(nekomimi.INFO, "h ", "msg")
+	}
+	content = readFileContent(t, filepath.Join(dir, "test.log"))
+	assert.Equal(t, 1, strings.Count(content, "# app v1.2.3"))
+	assert.Contains(t, content, "file=2\nh msg\nh msg\n")
+
+	for _, name := range listFiles(t, dir) {
+		if name == "test.log" {
+			continue
+		}
+		archive := readFileContent(t, filepath.Join(dir, name))
+		assert.Equal(t, 1, strings.Count(archive, "# app v1.2.3"))
+		assert.Contains(t, archive, "file=1\n")
+	}
+	assert.Equal(t, 2, calls)
+}
@@ -9,9 +9,17 @@
 //
 // TCP mode supports automatic reconnection when the connection drops.
 // A background ticker attempts to reconnect every 2 seconds,
-// and log messages are silently discarded while disconnected.
+// and log messages are discarded while disconnected.
 // UDP mode is connectionless with no reconnection logic.
 //
+// Config.Retry bounds the retries of a failed send (for TCP a retry
+// redials synchronously). A message still failing after the last
+// attempt is dropped; the handler implements nekomimi.DropCounter to
+// report the number of dropped messages.
+//
+// Config.OTelSeverity adds the OpenTelemetry "severity_text" and
+// "severity_number" fields, so OTel collectors can ingest the entries.
+//
 // # Usage
 //
 //	handler, err := netlog.New(ctx, netlog.Config{
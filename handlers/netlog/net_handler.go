@@ -11,6 +11,7 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fiathux/nekomimi"
@@ -42,8 +43,49 @@ type Config struct {
 	// Wrapper is an optional LogHandler that receives log messages
 	// before this handler does. Typically used to chain handlers.
 	Wrapper nekomimi.LogHandler
+	// Retry is the bounded retry policy for a failed send. For TCP,
+	// each retry redials the collector synchronously (dial timeout 2 s)
+	// when disconnected. After exhausting the attempts, the message is
+	// dropped and counted (see nekomimi.DropCounter). The zero value
+	// sends once without retry and leaves reconnection to the
+	// background ticker.
+	Retry nekomimi.RetryPolicy
+	// OTelSeverity adds the OpenTelemetry style "severity_text" (the
+	// level name) and "severity_number" (1-24 scale) fields to each JSON
+	// entry, so OTel pipelines can ingest the logs without an exporter.
+	// See OTelSeverityNumber for the mapping.
+	OTelSeverity bool
 }
 
+// OTelSeverityNumber maps a nekomimi level to the OpenTelemetry severity
+// number: TRACE=1, DEBUG=5, INFO=9, WARN=13, ERROR=17, PANIC=21 and FATAL=24.
+// PANIC is in the FATAL range as it usually crashes the program. Returns
+// 0 (unspecified) for unknown levels.
+func OTelSeverityNumber(level nekomimi.LogLevel) int {
+	switch level {
+	case nekomimi.TRACE:
+		return 1
+	case nekomimi.DEBUG:
+		return 5
+	case nekomimi.INFO:
+		return 9
+	case nekomimi.WARN:
+		return 13
+	case nekomimi.ERROR:
+		return 17
+	case nekomimi.PANIC:
+		return 21
+	case nekomimi.FATAL:
+		return 24
+	default:
+		return 0
+	}
+}
+
+// errDisconnected is returned by a send attempt while the connection is
+// not available.
+var errDisconnected = fmt.Errorf("netlog: disconnected")
+
 // netHandler implements nekomimi.LogHandler for network log transport.
 type netHandler struct {
 	cfg     Config
@@ -58,6 +100,14 @@ type netHandler struct {
 	// connection has been closed and no further reconnection attempts
 	// will be made.  Used by IsShutdown() to report full termination.
 	shutdownDone chan struct{}
+
+	// dropped counts log messages dropped after exhausting retries,
+	// including those discarded while disconnected.
+	dropped atomic.Uint64
+
+	// disabled is toggled by SetEnabled. While disabled, regular log
+	// messages are not sent but still forwarded to the Wrapper.
+	disabled atomic.Bool
 }
 
 // New creates a new network log handler. The Connect URL must use
@@ -166,30 +216,98 @@ func (h *netHandler) bgLoop() {
 }
 
 // sendJSON marshals the log entry to NDJSON and writes it to the
-// connection. Must be called with h.mu held. For TCP, write failure
-// triggers disconnect. For UDP, failure is silently ignored.
+// connection following the retry policy. For TCP, write failure
+// triggers disconnect. A message that still fails after the last
+// attempt is dropped and counted.
+//
+// Lock discipline: like bgLoop, h.mu is held only for each write, not
+// across the backoff sleep and the redial of a retry, so a collector
+// outage does not stall the other log calls for the whole policy.
 func (h *netHandler) sendJSON(
 	level nekomimi.LogLevel, header, body string,
 ) {
 	body = strings.TrimSuffix(body, "\n")
-	entry := map[string]string{
+	entry := map[string]any{
 		"level":  level.String(),
 		"header": header,
 		"body":   body,
 	}
+	if h.cfg.OTelSeverity {
+		entry["severity_text"] = level.String()
+		entry["severity_number"] = OTelSeverityNumber(level)
+	}
 	data, err := json.Marshal(entry)
 	if err != nil {
-		return // marshal failure, drop log
+		h.dropped.Add(1) // marshal failure, drop log
+		return
 	}
 	data = append(data, '\n')
+	err = h.cfg.Retry.Run(func(attempt int) error {
+		return h.writeData(attempt, data)
+	})
+	if err != nil {
+		h.dropped.Add(1)
+	}
+}
+
+// writeData performs one send attempt. Must be called without h.mu.
+// A retry attempt (attempt > 0) redials a disconnected TCP connection
+// synchronously, outside the lock; the first attempt never dials so the
+// default policy does not block callers while the collector is down.
+func (h *netHandler) writeData(attempt int, data []byte) error {
+	h.mu.Lock()
+	connected := h.conn != nil
+	h.mu.Unlock()
+	if !connected {
+		if attempt == 0 || h.network != "tcp" || h.ctx.Err() != nil {
+			return errDisconnected
+		}
+		d := net.Dialer{Timeout: ioDeadline}
+		conn, err := d.DialContext(h.ctx, "tcp", h.addr)
+		if err != nil {
+			return err
+		}
+		h.mu.Lock()
+		// another caller or bgLoop may have reconnected meanwhile, and
+		// bgLoop must not miss a connection installed after ctx is done
+		if h.conn == nil && h.ctx.Err() == nil {
+			h.conn = conn
+		} else {
+			conn.Close()
+		}
+		h.mu.Unlock()
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.conn == nil {
+		return errDisconnected
+	}
 	if h.network == "tcp" {
 		h.conn.SetWriteDeadline(time.Now().Add(ioDeadline))
 	}
-	_, err = h.conn.Write(data)
+	_, err := h.conn.Write(data)
 	if err != nil && h.network == "tcp" {
 		h.conn.Close()
 		h.conn = nil // mark disconnected, ticker will retry
 	}
+	return err
+}
+
+// SetEnabled enables or disables sending regular log messages. Panic
+// and fatal messages are always sent.
+func (h *netHandler) SetEnabled(enabled bool) {
+	h.disabled.Store(!enabled)
+}
+
+// Enabled reports whether the handler sends regular log messages.
+func (h *netHandler) Enabled() bool {
+	return !h.disabled.Load()
+}
+
+// Dropped returns the number of log messages dropped so far, either
+// after exhausting retries or while disconnected.
+func (h *netHandler) Dropped() uint64 {
+	return h.dropped.Load()
 }
 
 // makePnt creates a pnt function that writes header + message body.
@@ -218,12 +336,10 @@ func (h *netHandler) IsShutdown() bool {
 func (h *netHandler) RegularLog(
 	level nekomimi.LogLevel, header string, message ...any,
 ) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
 	if h.cfg.Wrapper != nil {
 		h.cfg.Wrapper.RegularLog(level, header, message...)
 	}
-	if h.conn == nil {
+	if level < nekomimi.PANIC && h.disabled.Load() {
 		return
 	}
 	h.sendJSON(level, header, fmt.Sprint(message...))
@@ -234,12 +350,10 @@ func (h *netHandler) RegularLog(
 func (h *netHandler) RegularWriter(
 	level nekomimi.LogLevel, pnt func(io.StringWriter),
 ) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
 	if h.cfg.Wrapper != nil {
 		h.cfg.Wrapper.RegularWriter(level, pnt)
 	}
-	if h.conn == nil {
+	if level < nekomimi.PANIC && h.disabled.Load() {
 		return
 	}
 	var buf bytes.Buffer
@@ -248,18 +362,13 @@ func (h *netHandler) RegularWriter(
 }
 
 // PanicLog handles panic-level log messages. After sending the log,
-// it panics unless WrapOnly is true. The lock is released via defer
-// so it is always freed — even if sendJSON panics.
+// it panics unless WrapOnly is true. No lock is held while panicking.
 func (h *netHandler) PanicLog(header string, message ...any) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
 	if h.cfg.Wrapper != nil {
 		pnt := makePnt(header, message...)
 		h.cfg.Wrapper.RegularWriter(nekomimi.PANIC, pnt)
 	}
-	if h.conn != nil {
-		h.sendJSON(nekomimi.PANIC, header, fmt.Sprint(message...))
-	}
+	h.sendJSON(nekomimi.PANIC, header, fmt.Sprint(message...))
 	if !h.cfg.WrapOnly {
 		panic(fmt.Sprint(message...))
 	}
@@ -267,18 +376,12 @@ func (h *netHandler) PanicLog(header string, message ...any) {
 
 // FatalLog handles fatal-level log messages. After sending the log,
 // it terminates the program via exitFunc unless WrapOnly is true.
-// The lock is released via defer so it is always freed — even if
-// sendJSON panics.  exitFunc is called after the defer stack unwinds.
 func (h *netHandler) FatalLog(header string, message ...any) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
 	if h.cfg.Wrapper != nil {
 		pnt := makePnt(header, message...)
 		h.cfg.Wrapper.RegularWriter(nekomimi.FATAL, pnt)
 	}
-	if h.conn != nil {
-		h.sendJSON(nekomimi.FATAL, header, fmt.Sprint(message...))
-	}
+	h.sendJSON(nekomimi.FATAL, header, fmt.Sprint(message...))
 	if !h.cfg.WrapOnly {
 		exitFunc(1)
 	}
@@ -849,3 +849,194 @@ func TestIsShutdown_DuringTCPReconnect(t *testing.T) {
 	}, 2*time.Second, 50*time.Millisecond,
 		"IsShutdown should become true after context cancel")
 }
+
+// forceDisconnect closes the handler connection from the test side.
+func forceDisconnect(h nekomimi.LogHandler) {
+	nh := h.(*netHandler)
+	nh.mu.Lock()
+	defer nh.mu.Unlock()
+	if nh.conn != nil {
+		nh.conn.Close()
+		nh.conn = nil
+	}
+}
+
+func TestRetry_DropAfterExhausted(t *testing.T) {
+	addr, lis, _ := serveTCP(t)
+	ctx, _ := newTestContext(t)
+
+	h, err := New(ctx, Config{
+		Connect: "tcp://" + addr,
+		Retry: nekomimi.RetryPolicy{
+			MaxAttempts: 3,
+			Backoff:     20 * time.Millisecond,
+		},
+	})
+	require.NoError(t, err)
+	dc, ok := h.(nekomimi.DropCounter)
+	require.True(t, ok, "handler should implement DropCounter")
+	assert.Equal(t, uint64(0), dc.Dropped())
+
+	// sink down: redial is refused
+	lis.Close()
+	forceDisconnect(h)
+
+	start := time.Now()
+	h.RegularLog(nekomimi.ERROR, "h - ", "lost")
+	elapsed := time.Since(start)
+	assert.GreaterOrEqual(t, elapsed, 40*time.Millisecond,
+		"two backoff intervals between three attempts")
+	assert.Equal(t, uint64(1), dc.Dropped())
+
+	h.RegularLog(nekomimi.ERROR, "h - ", "lost again")
+	assert.Equal(t, uint64(2), dc.Dropped())
+}
+
+func TestRetry_BackoffDoesNotHoldLock(t *testing.T) {
+	addr, lis, _ := serveTCP(t)
+	ctx, _ := newTestContext(t)
+
+	h, err := New(ctx, Config{
+		Connect: "tcp://" + addr,
+		Retry: nekomimi.RetryPolicy{
+			MaxAttempts: 3,
+			Backoff:     100 * time.Millisecond,
+		},
+	})
+	require.NoError(t, err)
+	nh := h.(*netHandler)
+
+	lis.Close()
+	forceDisconnect(h)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.RegularLog(nekomimi.ERROR, "h - ", "retrying")
+	}()
+	time.Sleep(30 * time.Millisecond) // inside the first backoff
+
+	start := time.Now()
+	nh.mu.Lock()
+	nh.mu.Unlock()
+	assert.Less(t, time.Since(start), 50*time.Millisecond,
+		"the lock must be free while the sender backs off")
+	<-done
+}
+
+func TestRetry_RedialDelivers(t *testing.T) {
+	addr, _, data := serveTCP(t)
+	ctx, _ := newTestContext(t)
+
+	h, err := New(ctx, Config{
+		Connect: "tcp://" + addr,
+		Retry:   nekomimi.RetryPolicy{MaxAttempts: 2},
+	})
+	require.NoError(t, err)
+
+	forceDisconnect(h)
+	h.RegularLog(nekomimi.INFO, "h - ", "redelivered")
+	e := recvJSON(t, data)
+	assert.Equal(t, "redelivered", e.Body)
+	assert.Equal(t, uint64(0), h.(nekomimi.DropCounter).Dropped())
+}
+
+func TestRetry_DefaultCountsDisconnectedDrops(t *testing.T) {
+	addr, _, data := serveTCP(t)
+	ctx, _ := newTestContext(t)
+
+	h, err := New(ctx, Config{Connect: "tcp://" + addr})
+	require.NoError(t, err)
+
+	forceDisconnect(h)
+	h.RegularLog(nekomimi.INFO, "h - ", "dropped")
+	assertNoData(t, data, 100*time.Millisecond)
+	assert.Equal(t, uint64(1), h.(nekomimi.DropCounter).Dropped())
+}
+
+func TestSetEnabled_SkipsSendButForwards(t *testing.T) {
+	addr, _, data := serveTCP(t)
+	ctx, _ := newTestContext(t)
+	mock := &mockHandler{}
+
+	h, err := New(ctx, Config{Connect: "tcp://" + addr, Wrapper: mock})
+	require.NoError(t, err)
+	sw, ok := h.(nekomimi.Switchable)
+	require.True(t, ok, "handler should implement Switchable")
+
+	sw.SetEnabled(false)
+	assert.False(t, sw.Enabled())
+	h.RegularLog(nekomimi.INFO, "h - ", "silenced")
+	assertNoData(t, data, 100*time.Millisecond)
+	assert.Equal(t, int32(1), mock.regularLogCount.Load())
+
+	sw.SetEnabled(true)
+	h.RegularLog(nekomimi.INFO, "h - ", "enabled")
+	e := recvJSON(t, data)
+	assert.Equal(t, "enabled", e.Body)
+	assert.Equal(t, int32(2), mock.regularLogCount.Load())
+	assert.Equal(t, uint64(0), h.(nekomimi.DropCounter).Dropped())
+
+	// panic messages received as a wrapper are sent even if disabled
+	sw.SetEnabled(false)
+	h.RegularWriter(nekomimi.PANIC, func(w io.StringWriter) {
+		w.WriteString("boom")
+	})
+	e = recvJSON(t, data)
+	assert.Equal(t, "boom", e.Body)
+}
+
+func TestOTelSeverity_Mapping(t *testing.T) {
+	addr, _, data := serveTCP(t)
+	ctx, _ := newTestContext(t)
+
+	h, err := New(ctx, Config{
+		Connect:      "tcp://" + addr,
+		OTelSeverity: true,
+	})
+	require.NoError(t, err)
+
+	levels := []nekomimi.LogLevel{
+		nekomimi.TRACE, nekomimi.DEBUG, nekomimi.INFO, nekomimi.WARN,
+		nekomimi.ERROR, nekomimi.PANIC, nekomimi.FATAL,
+	}
+	for _, lv := range levels {
+		h.RegularLog(lv, "h - ", "msg")
+	}
+
+	expectedText := []string{
+		"TRACE", "DEBUG", "INFO", "WARN", "ERROR", "PANIC", "FATAL",
+	}
+	expectedNumber := []float64{1, 5, 9, 13, 17, 21, 24}
+	for i := range levels {
+		var e map[string]any
+		select {
+		case raw := <-data:
+			require.NoError(t, json.Unmarshal([]byte(raw), &e))
+		case <-time.After(3 * time.Second):
+			t.Fatal("timeout waiting for log entry")
+		}
+		assert.Equal(t, expectedText[i], e["severity_text"],
+			"severity_text mismatch at index %d", i)
+		assert.Equal(t, expectedNumber[i], e["severity_number"],
+			"severity_number mismatch at index %d", i)
+		assert.Equal(t, expectedText[i], e["level"])
+	}
+	assert.Equal(t, 0, OTelSeverityNumber(nekomimi.TINY_DONE))
+}
+
+func TestOTelSeverity_DisabledByDefault(t *testing.T) {
+	addr, _, data := serveTCP(t)
+	ctx, _ := newTestContext(t)
+
+	h, err := New(ctx, Config{Connect: "tcp://" + addr})
+	require.NoError(t, err)
+	h.RegularLog(nekomimi.INFO, "h - ", "msg")
+
+	select {
+	case raw := <-data:
+		assert.NotContains(t, raw, "severity_")
+	case <-time.After(3 * time.Second):
+		t.Fatal("timeout waiting for log entry")
+	}
+}
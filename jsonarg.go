@@ -0,0 +1,28 @@
+package nekomimi
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// jsonArg wraps a message argument to render it as JSON
+type jsonArg struct {
+	v any
+}
+
+// JSON wraps a message argument to render it as compact JSON instead of
+// the %v form of fmt, e.g. `l.Inf("config:", nekomimi.JSON(cfg))` gives
+// `config: {"addr":":8080","debug":true}`. the value is marshaled when the
+// message is formatted, so nothing is done for a disabled level. the %v
+// form is rendered if the value can't be marshaled.
+func JSON(v any) fmt.Stringer {
+	return jsonArg{v: v}
+}
+
+func (ja jsonArg) String() string {
+	data, err := json.Marshal(ja.v)
+	if err != nil {
+		return fmt.Sprintf("%v", ja.v)
+	}
+	return string(data)
+}
@@ -0,0 +1,53 @@
+package nekomimi
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestJSONArg(t *testing.T) {
+	Convey("JSON argument tests", t, func() {
+		capture := &captureLogHandler{}
+		l := New("App", LogConfig{Handler: capture.handler(), TestMode: true})
+		type config struct {
+			Addr  string   `json:"addr"`
+			Debug bool     `json:"debug"`
+			Tags  []string `json:"tags,omitempty"`
+		}
+
+		Convey("Value is rendered as compact JSON", func() {
+			l.Inf("config:", JSON(config{Addr: ":8080", Debug: true}))
+			So(capture.last(), ShouldEqual, "2000-01-01 00:00:00.000 [INFO], App - "+
+				`config: {"addr":":8080","debug":true}`+"\n")
+			l.Inff("tags %s", JSON([]string{"a", "b"}))
+			So(capture.last(), ShouldEndWith, ` - tags ["a","b"]`+"\n")
+		})
+
+		Convey("Marshal error falls back to %v", func() {
+			l.Inf("func:", JSON(map[string]any{"f": func() {}}))
+			So(capture.last(), ShouldStartWith,
+				"2000-01-01 00:00:00.000 [INFO], App - func: map[f:0x")
+		})
+
+		Convey("Disabled level doesn't marshal", func() {
+			l.SetLevel(ERROR)
+			called := false
+			l.Dbg(JSON(marshalProbe{called: &called}))
+			So(called, ShouldBeFalse)
+			l.Err(JSON(marshalProbe{called: &called}))
+			So(called, ShouldBeTrue)
+			So(capture.last(), ShouldEndWith, ` - "probe"`+"\n")
+		})
+	})
+}
+
+// marshalProbe records whether it's marshaled
+type marshalProbe struct {
+	called *bool
+}
+
+func (mp marshalProbe) MarshalJSON() ([]byte, error) {
+	*mp.called = true
+	return []byte(`"probe"`), nil
+}
@@ -0,0 +1,67 @@
+package nekomimi
+
+import "io"
+
+// levelFilterHandler is the LogHandler returned by NewLevelFilterHandler
+type levelFilterHandler struct {
+	min  LogLevel
+	wrap LogHandler
+}
+
+// NewLevelFilterHandler creates a LogHandler which forwards the regular log
+// messages at or above min to wrapped, and drops the others. panic and
+// fatal messages always pass. with the handlers composed by Wrapper, it
+// lets each sink have its own verbosity, e.g. a console showing WARN and
+// above over a file recording everything:
+//
+//	handler := nekomimi.NewLevelFilterHandler(nekomimi.WARN,
+//		nekomimi.NewNativeLogHandler(nil))
+//	logger := nekomimi.New("App", nekomimi.LogConfig{
+//		Handler: &nekomimi.LogHandlerFunc{
+//			RegularLogFunc: ..., // the file, at the logger level
+//			Wrapper:        handler,
+//		},
+//	})
+//
+// the level of the logger still gates all the handlers.
+func NewLevelFilterHandler(min LogLevel, wrapped LogHandler) LogHandler {
+	return &levelFilterHandler{min: min, wrap: wrapped}
+}
+
+func (lf *levelFilterHandler) IsShutdown() bool {
+	return lf.wrap.IsShutdown()
+}
+
+func (lf *levelFilterHandler) RegularWriter(
+	level LogLevel, pnt func(io.StringWriter),
+) {
+	if level >= lf.min {
+		lf.wrap.RegularWriter(level, pnt)
+	}
+}
+
+func (lf *levelFilterHandler) RegularLog(
+	level LogLevel, header string, message ...any,
+) {
+	if level >= lf.min {
+		lf.wrap.RegularLog(level, header, message...)
+	}
+}
+
+func (lf *levelFilterHandler) PanicLog(header string, message ...any) {
+	lf.wrap.PanicLog(header, message...)
+}
+
+func (lf *levelFilterHandler) FatalLog(header string, message ...any) {
+	lf.wrap.FatalLog(header, message...)
+}
+
+// Flush flushes the wrapped handler
+func (lf *levelFilterHandler) Flush() error {
+	return flushHandler(lf.wrap)
+}
+
+// Close closes the wrapped handler
+func (lf *levelFilterHandler) Close() error {
+	return closeHandler(lf.wrap)
+}
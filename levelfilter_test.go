@@ -0,0 +1,63 @@
+package nekomimi
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLevelFilterHandler(t *testing.T) {
+	Convey("Level filter handler tests", t, func() {
+		file := &captureLogHandler{}
+		console := &strings.Builder{}
+		handler := NewNativeLogHandlerWithConfig(context.Background(),
+			NativeConfig{Stdout: console, Stderr: console},
+			NewLevelFilterHandler(DEBUG, file.handler()))
+		l := New("App", LogConfig{
+			Handler:  NewLevelFilterHandler(WARN, handler),
+			TestMode: true,
+		})
+
+		Convey("Regular messages below min are dropped", func() {
+			inner := New("App", LogConfig{
+				Handler: &LogHandlerFunc{
+					Wrapper: NewLevelFilterHandler(WARN, file.handler()),
+				},
+				TestMode: true,
+			})
+			inner.Trc("trace")
+			inner.Inf("info")
+			inner.War("warn")
+			inner.GetWriter(ERROR, false).WriteString("by writer")
+			So(file.count(), ShouldEqual, 2)
+			So(file.lines[0], ShouldEndWith, "[WARN], App - warn\n")
+		})
+
+		Convey("Sinks have their own verbosity", func() {
+			fanout := New("App", LogConfig{
+				Handler: NewNativeLogHandlerWithConfig(context.Background(),
+					NativeConfig{Stdout: console}, nil),
+				TestMode: true,
+			})
+			fanout.WrapLogHandler(func(old LogHandler) LogHandler {
+				return &LogHandlerFunc{
+					Wrapper:        NewLevelFilterHandler(DEBUG, file.handler()),
+					RegularLogFunc: NewLevelFilterHandler(WARN, old).RegularWriter,
+				}
+			})
+			fanout.Dbg("debug")
+			fanout.War("warn")
+			So(file.levels, ShouldResemble, []LogLevel{DEBUG, WARN})
+			So(console.String(), ShouldEqual,
+				"2000-01-01 00:00:00.000 [WARN], App - warn\n")
+		})
+
+		Convey("Panic always passes", func() {
+			So(func() { l.Panic("boom") }, ShouldPanic)
+			So(file.last(), ShouldEndWith, "[PANIC], App - boom\n")
+			So(console.String(), ShouldEndWith, "[PANIC], App - boom\n")
+		})
+	})
+}
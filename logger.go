@@ -3,9 +3,14 @@
 package nekomimi
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"maps"
+	"math"
 	"runtime"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -15,11 +20,15 @@ import (
 )
 
 // LogLevel represents the severity level of a log message
-type LogLevel uint32
+type LogLevel int32
 
 const (
+	// TRACE level for extremely verbose tracing information, e.g. per
+	// packet logging. it's the lowest level, numbered below DEBUG so the
+	// other levels keep their values, SetLevel(TRACE) enables all messages.
+	TRACE LogLevel = iota - 1
 	// DEBUG level for detailed debugging information
-	DEBUG LogLevel = iota
+	DEBUG
 	// INFO level for general informational messages
 	INFO
 	// WARN level for warning messages
@@ -33,13 +42,20 @@ const (
 
 	// TINY_DONE is a non-logging probe level used by
 	// TinyLogHandlerFunc.IsShutdown to detect whether the
-	// underlying handler has permanently stopped processing.
-	TINY_DONE LogLevel = 0x80000000
+	// underlying handler has permanently stopped processing. it sorts
+	// above all the levels.
+	TINY_DONE LogLevel = math.MaxInt32
 )
 
+// levelNameWidth is the width of the longest level name, see
+// LogConfig.PadLevel
+const levelNameWidth = 5
+
 // String returns the string representation of the log level
 func (l LogLevel) String() string {
 	switch l {
+	case TRACE:
+		return "TRACE"
 	case DEBUG:
 		return "DEBUG"
 	case INFO:
@@ -59,8 +75,37 @@ func (l LogLevel) String() string {
 	}
 }
 
+// ParseLevel parses a level name case-insensitively, e.g. "info" or "WARN".
+// the names returned by LogLevel.String are accepted, as well as the
+// aliases "warning" and "err". returns error for unknown names.
+func ParseLevel(s string) (LogLevel, error) {
+	name := strings.ToUpper(strings.TrimSpace(s))
+	switch name {
+	case "WARNING":
+		return WARN, nil
+	case "ERR":
+		return ERROR, nil
+	}
+	lv, ok := levelFromName(name)
+	if !ok {
+		return 0, fmt.Errorf("nekomimi: unknown log level %q", s)
+	}
+	return lv, nil
+}
+
+// MustParseLevel is like ParseLevel but raises panic for unknown names,
+// e.g. for the initialization of package level variables
+func MustParseLevel(s string) LogLevel {
+	lv, err := ParseLevel(s)
+	if err != nil {
+		panic(err)
+	}
+	return lv
+}
+
 // BasicLogger defines the basic logging methods for different log levels
 // following log levels are supported:
+//   - Trc: Trace level logging
 //   - Dbg: Debug level logging
 //   - Inf: Info level logging
 //   - War: Warning level logging
@@ -75,6 +120,12 @@ func (l LogLevel) String() string {
 // message construction. the Deferred type might return nil if the log level is
 // not enabled.
 type BasicLogger interface {
+	// Trace level - simple output
+	Trc(message ...any)
+	// Trace level - formatted output
+	Trcf(format string, args ...any)
+	// Trace level - deferred output
+	TrcP() func(message ...any)
 	// Debug level - simple output
 	Dbg(message ...any)
 	// Debug level - formatted output
@@ -99,6 +150,14 @@ type BasicLogger interface {
 	Errf(format string, args ...any)
 	// Error level - deferred output
 	ErrP() func(message ...any)
+	// Context-aware output for each level. fields extracted from ctx by the
+	// registered extractors (see RegisterContextField) are attached to the
+	// log header.
+	TrcCtx(ctx context.Context, message ...any)
+	DbgCtx(ctx context.Context, message ...any)
+	InfCtx(ctx context.Context, message ...any)
+	WarCtx(ctx context.Context, message ...any)
+	ErrCtx(ctx context.Context, message ...any)
 }
 
 // TraceLogger extends BasicLogger with tracing capabilities
@@ -109,8 +168,32 @@ type TraceLogger interface {
 	TraceID() string
 	// Retrieve the Trace Name
 	TraceName() string
+	// Update the Trace Name used in subsequent log headers. the Trace ID is
+	// kept unchanged.
+	SetName(name string)
+	// Elapsed returns the duration since the trace was created, measured by
+	// the Clock of the logger
+	Elapsed() time.Duration
+	// SetBaggage sets a key-value which travels with the trace. baggage is
+	// attached to the header fields of every subsequent log message of the
+	// trace, and is inherited by child traces.
+	SetBaggage(key, value string)
+	// Child creates a new trace with the given name which inherits the
+	// current baggage
+	Child(name string) TraceLogger
+	// Breadcrumb records a short step of the trace. the last steps (up to
+	// MaxBreadcrumbs) are attached to the ERROR records of the trace as the
+	// `breadcrumbs` header field, e.g. `breadcrumbs="auth > load > save"`.
+	Breadcrumb(s string)
+	// Span times the code block f. it logs "span start" at DEBUG level, runs
+	// f, then logs "span end" with the elapsed time at INFO level. a panic
+	// from f is logged at PANIC level and raised again.
+	Span(name string, f func())
 }
 
+// MaxBreadcrumbs is the number of the recent breadcrumbs kept by a trace
+const MaxBreadcrumbs = 8
+
 // RawWriter is an interface that combines io.StringWriter and io.Writer for
 // raw log writing.
 //
@@ -133,6 +216,42 @@ type Logger interface {
 	// Fatal level logging
 	Fatal(message ...any)
 	Fatalf(format string, args ...any)
+	// Output with skip extra stack frames for the call trace of this
+	// record only. useful for logging helpers, e.g. `InfSkip(1, msg)`
+	// reports the caller of the helper.
+	TrcSkip(skip int, message ...any)
+	DbgSkip(skip int, message ...any)
+	InfSkip(skip int, message ...any)
+	WarSkip(skip int, message ...any)
+	ErrSkip(skip int, message ...any)
+	// DbgPTimeout is DbgP with the formatting of the message bounded by d,
+	// for expensive debug values (e.g. a slow String method). the arguments
+	// with a formatting method (String, Error, Format or GoString) are
+	// formatted in a goroutine, if it takes longer than d the formatting
+	// is abandoned and a placeholder is logged instead, e.g.
+	// "<message formatting exceeded 10ms>". the other arguments are
+	// formatted before, so they may be reused after the call, but the
+	// values read by the methods must not be changed after the call. at
+	// most 64 formattings run at once (including the abandoned ones), a
+	// placeholder is logged instead of starting more. returns nil if the
+	// level is not enabled.
+	DbgPTimeout(d time.Duration) func(message ...any)
+	// Log the error at ERROR level after the message and return it, e.g.
+	// `return l.ErrReturn(err, "loading config")`. nothing is logged for
+	// a nil error.
+	ErrReturn(err error, message ...any) error
+	// Create a LogBatch which emits the added fragments as one record on
+	// Commit. returns a no-op batch if the level is not enabled.
+	Batch(level LogLevel) *LogBatch
+	// Emit the memory and GC statistics of the runtime as INFO records
+	// every interval, until ctx is done or the returned stop is called.
+	// see RuntimeStats for the fields.
+	RuntimeStats(ctx context.Context, every time.Duration) (stop func())
+	// ThrottleSummary returns a BasicLogger which outputs the first message
+	// of the key immediately, suppresses the following ones within the
+	// interval, then outputs a summary "<key> occurred N times in the last
+	// <interval>" when the interval expires.
+	ThrottleSummary(key string, every time.Duration) BasicLogger
 	// Create a new TraceLogger with the given name
 	Trace(name string) TraceLogger
 	// Get a StringWriter for the given log level.
@@ -144,6 +263,9 @@ type Logger interface {
 	// it's not already present.
 	// set the argument calltrace to false you can force disable call trace
 	// information in the log header.
+	// the messages are passed to LogHandler.RegularWriter with the given
+	// level (before NewLevelFilterHandler they were passed as INFO), so the
+	// level-aware handlers route and filter them like the other messages.
 	// returns nil if the log level is not enabled.
 	GetWriter(level LogLevel, calltrace bool) io.StringWriter
 	// Get a RawWriter. which directly write the input message to the log handler
@@ -151,6 +273,25 @@ type Logger interface {
 	RawWriter() RawWriter
 	// Derive a new Logger with the given prefix name
 	Derive(pfx string) Logger
+	// Named is an alias of Derive
+	Named(name string) Logger
+	// Name returns the full dotted name of the logger, e.g. "App.Database"
+	Name() string
+	// Derive a Logger with the same prefix which attaches the current call
+	// stack to every regular log message, e.g. `l.WithStack().Err(err)`
+	WithStack() Logger
+	// Derive a Logger with the same prefix which attaches a machine-only
+	// metadata field (e.g. a schema version) to every regular log message.
+	// unlike the header fields, metadata is only delivered to structured
+	// handlers implementing MetaLogHandler (see Record.Meta), the console
+	// never shows it.
+	WithMeta(key string, value any) Logger
+	// Derive a Logger with the same prefix which requires the header
+	// fields of the keys (e.g. `tenant_id` from the baggage or the context
+	// fields) on every regular log message. a WARN message reports each
+	// message missing any of them. only effective with
+	// LogConfig.StrictFields, otherwise the logger itself is returned.
+	RequireFields(keys ...string) Logger
 	// Set log level
 	SetLevel(level LogLevel)
 	// Set log level that includes call trace information
@@ -163,6 +304,59 @@ type Logger interface {
 	// if the wrapper returns nil, the log handler will be reset to the default
 	// handler (NativeLogHandler).
 	WrapLogHandler(wrapper func(old LogHandler) LogHandler)
+	// Enter logs "enter name" at DEBUG level and returns the function to
+	// defer, which logs "exit name (elapsed)" at DEBUG level. if the
+	// function is unwinding by a panic, the panic is logged at PANIC level
+	// with the elapsed time and raised again:
+	//
+	//	defer logger.Enter("ProcessOrder")()
+	Enter(name string) func()
+	// Flush flushes the log handler synchronously, e.g. before os.Exit in
+	// short-lived tools. the handlers implementing Flusher are flushed
+	// through the Wrapper chain (see LogHandlerFunc.Flush).
+	Flush() error
+}
+
+// HyperlinkScheme selects the link form of the call trace, see
+// LogConfig.CallerHyperlink
+type HyperlinkScheme int
+
+const (
+	// HyperlinkNone renders the base file name, e.g. `main.go:42(main.run)`
+	HyperlinkNone HyperlinkScheme = iota
+	// HyperlinkFile renders a file URL, e.g.
+	// `file:///src/app/main.go:42(main.run)`
+	HyperlinkFile
+	// HyperlinkVSCode renders a VS Code URL, e.g.
+	// `vscode://file/src/app/main.go:42(main.run)`
+	HyperlinkVSCode
+)
+
+// CallerFormat selects how much of the source path is kept in the call
+// trace, see LogConfig.CallerFormat
+type CallerFormat int
+
+const (
+	// CallerBase keeps the base file name, e.g. `main.go:42(main.run)`.
+	// the frames of stacks keep the full path.
+	CallerBase CallerFormat = iota
+	// CallerShort keeps the last two path segments, e.g.
+	// `app/main.go:42(main.run)`
+	CallerShort
+	// CallerFull keeps the full path, e.g. `/src/app/main.go:42(main.run)`
+	CallerFull
+)
+
+// LevelBehavior defines the terminal behavior of a log level, see
+// LogConfig.LevelPolicy
+type LevelBehavior struct {
+	// Exit terminates the program with ExitCode after logging
+	Exit bool
+	// Panic raises panic with the message after logging. Exit takes
+	// precedence if both are set.
+	Panic bool
+	// ExitCode is the exit code used by Exit
+	ExitCode int
 }
 
 // LogConfig provides configuration options for the logger
@@ -171,6 +365,87 @@ type LogConfig struct {
 	Level          LogLevel
 	LevelWithTrace LogLevel
 	TimeFormat     string
+	// Clock is the time source of timestamps and trace durations. default
+	// is RealClock.
+	Clock Clock
+	// TestMode produces byte-stable output for golden-file tests: a fixed
+	// clock is used for timestamps (unless Clock is given), trace IDs are
+	// generated from a
+	// deterministic sequence, and call trace paths (including stacks of
+	// panic/fatal messages) are omitted.
+	TestMode bool
+	// IncludeProcessFields attaches the pid, hostname and executable name
+	// to the header fields of every log message, e.g. for multi-host
+	// aggregation. the values are computed once when the logger is created.
+	IncludeProcessFields bool
+	// InstanceID resolves the host field of IncludeProcessFields, e.g. the
+	// pod name or the instance id of the orchestrator, where the hostname
+	// isn't meaningful. it's called once when the logger is created.
+	// default is os.Hostname.
+	InstanceID func() string
+	// IncludeEpochNanos attaches the UnixNano of the log timestamp to the
+	// header fields as `ts_nanos`, a high-resolution sort key for merging
+	// logs from multiple sources. it's taken from the same clock reading as
+	// the formatted time, so it's wall time rather than monotonic.
+	IncludeEpochNanos bool
+	// IncludeStackDepth attaches the number of stack frames of the calling
+	// goroutine at the log call as `stack_depth` to the header fields, to
+	// spot runaway recursion. it costs a runtime.Callers per message.
+	IncludeStackDepth bool
+	// PadLevel right-pads the level name in the header to the width of the
+	// longest level name, e.g. `[INFO ]`, so the messages line up in
+	// columns on the console.
+	PadLevel bool
+	// CallerHyperlink renders the call trace of WARN and above levels as a
+	// link with the absolute source path, so editors and terminals can
+	// jump to the source. default is HyperlinkNone.
+	CallerHyperlink HyperlinkScheme
+	// CallerCache memoizes the formatted call trace `file:line(func)` by
+	// program counter, so repeated logging from the same call site skips
+	// the symbol lookup and formatting. the cache is shared by all the
+	// loggers and grows with the number of distinct call sites.
+	CallerCache bool
+	// CallerFormat selects how much of the source path is kept in the call
+	// trace and the frames of stacks, to tell apart files sharing the same
+	// base name. default is CallerBase.
+	CallerFormat CallerFormat
+	// BinaryEncoding selects how []byte message arguments are rendered.
+	// default is BinaryDefault (decimal byte slice by fmt).
+	BinaryEncoding BinaryEncoding
+	// VerboseErrors formats the error typed message arguments with %+v
+	// instead of %v, so the stacks embedded by error libraries (e.g.
+	// pkg/errors) are printed. RecordLogHandlerFunc also receives the
+	// verbose form separately (see Record.ErrorVerbose).
+	VerboseErrors bool
+	// LevelPolicy overrides the terminal behavior of the PANIC and FATAL
+	// levels, which is otherwise decided by the log handler. for a level in
+	// the policy, the message is sent to the handler as a regular log
+	// message, the handler is flushed (see Flusher) if the behavior
+	// terminates, then the program exits or panics according to the
+	// behavior. e.g. `{PANIC: {}}` logs panic messages without panic.
+	LevelPolicy map[LogLevel]LevelBehavior
+	// StrictFields enables the checks of Logger.RequireFields, intended for
+	// development and tests. without it RequireFields has no effect, so
+	// production logging pays nothing for the checks.
+	StrictFields bool
+	// DebugConfig outputs a one-time DEBUG note when SetLevel of a derived
+	// logger makes its level differ from the logger it's derived from, e.g.
+	// `derived App.Cache at WARN, parent at INFO`, to diagnose the
+	// components which don't log. the note bypasses the level of the
+	// derived logger.
+	DebugConfig bool
+}
+
+// testModeTime is the fixed timestamp used by the test mode
+var testModeTime = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// sequenceIDGenerator returns a deterministic trace ID generator for the
+// test mode. the IDs are UUID-shaped and monotonically increasing.
+func sequenceIDGenerator() func() string {
+	seq := &atomic.Uint64{}
+	return func() string {
+		return fmt.Sprintf("00000000-0000-0000-0000-%012x", seq.Add(1))
+	}
 }
 
 // traceID represents a trace identifier with a name and ID
@@ -187,24 +462,68 @@ type logger struct {
 	levelct    LogLevel
 	prefix     string
 	timefmt    string
-	fmtHeader  func(level LogLevel, tid *traceID) string
+	withStack  bool
+	testMode   bool
+	idgen      func() string
+	clock      Clock
+	callerc    bool
+	callerFmt  CallerFormat
+	hyperlink  HyperlinkScheme
+	procFields string
+	epochNanos bool
+	stackDepth bool
+	padLevel   bool
+	fmtHeader  headerFormatter
+	// states of ThrottleSummary keys, shared by the derived loggers
+	throttles *sync.Map
+	// immutable after construction
+	verboseErrors bool
+	binaryEnc     BinaryEncoding
+	policy        map[LogLevel]LevelBehavior
+	// machine-only metadata, see WithMeta
+	meta []Field
+	// enables RequireFields
+	strictFields bool
+	// header fields required by RequireFields
+	required []string
+	// enables the level override note, see LogConfig.DebugConfig
+	debugConfig bool
+	// the logger derived from, only kept with debugConfig
+	parent *logger
+	// the level override note was output, shared by the siblings
+	levelNoted *atomic.Bool
 }
 
 // traceLogger implements the TraceLogger interface
 type traceLogger struct {
+	mtx    sync.RWMutex
 	parent *logger
 	tid    traceID
+	start  time.Time
+	// baggage is replaced on each update (copy-on-write), so a snapshot
+	// could be used without lock
+	baggage []Field
+	// recent breadcrumbs, oldest first
+	crumbs []string
 }
 
 // levelWriter is a helper struct for implementing the GetWriter method of the
 // Logger interface
 type levelWriter struct {
 	parent    *logger
+	level     LogLevel
 	fmtHeader func() string
 }
 
-// newTraceID generates a new traceID with the given name
-func newTraceID(name string) traceID {
+// newTraceID generates a new traceID with the given name. a UUIDv7 is
+// generated if idgen is nil.
+func newTraceID(name string, idgen func() string) traceID {
+	if idgen != nil {
+		return traceID{
+			name: name,
+			id:   idgen(),
+		}
+	}
 	id, _ := uuid.NewV7()
 	return traceID{
 		name: name,
@@ -212,18 +531,36 @@ func newTraceID(name string) traceID {
 	}
 }
 
+// trimCallerFile keeps the part of the source path selected by the format
+func trimCallerFile(file string, format CallerFormat) string {
+	switch format {
+	case CallerFull:
+		return file
+	case CallerShort:
+		idx := strings.LastIndex(file, "/")
+		if idx <= 0 {
+			return file
+		}
+		if idx = strings.LastIndex(file[:idx], "/"); idx != -1 {
+			return file[idx+1:]
+		}
+		return file
+	default:
+		if idx := strings.LastIndex(file, "/"); idx != -1 {
+			return file[idx+1:]
+		}
+		return file
+	}
+}
+
 // getStackHeader retrieves the caller information for logging
-func getStackHeader(skip int) string {
+func getStackHeader(skip int, format CallerFormat) string {
 	pc, file, line, ok := runtime.Caller(skip)
 	if !ok {
 		return "unknown:0 "
 	}
 	fn := runtime.FuncForPC(pc)
-	// split base file name
-	basefile := file
-	if idx := strings.LastIndex(file, "/"); idx != -1 {
-		basefile = file[idx+1:]
-	}
+	basefile := trimCallerFile(file, format)
 	// split base function name (without package path)
 	fnName := fn.Name()
 	if idx := strings.LastIndex(fnName, "/"); idx != -1 {
@@ -232,8 +569,78 @@ func getStackHeader(skip int) string {
 	return fmt.Sprintf(" %s:%d(%s)", basefile, line, fnName)
 }
 
-// formatStack formats the current call stack for logging
-func formatStack(skip int) string {
+// callDepth returns the number of stack frames from the caller at the skip
+// level (as runtime.Caller) to the bottom of the goroutine
+func callDepth(skip int) int {
+	var buf [64]uintptr
+	depth := 0
+	for {
+		n := runtime.Callers(skip+depth+1, buf[:])
+		depth += n
+		if n < len(buf) {
+			return depth
+		}
+	}
+}
+
+// getLinkStackHeader retrieves the caller information as a link with the
+// full source path
+func getLinkStackHeader(skip int, scheme HyperlinkScheme) string {
+	pc, file, line, ok := runtime.Caller(skip)
+	if !ok {
+		return "unknown:0 "
+	}
+	fnName := runtime.FuncForPC(pc).Name()
+	if idx := strings.LastIndex(fnName, "/"); idx != -1 {
+		fnName = fnName[idx+1:]
+	}
+	// windows paths like C:/src need a leading slash in URLs
+	if !strings.HasPrefix(file, "/") {
+		file = "/" + file
+	}
+	prefix := "file://"
+	if scheme == HyperlinkVSCode {
+		prefix = "vscode://file"
+	}
+	return fmt.Sprintf(" %s%s:%d(%s)", prefix, file, line, fnName)
+}
+
+// callerKey identifies a call site and the path form of its call trace
+type callerKey struct {
+	pc     uintptr
+	format CallerFormat
+}
+
+// callerCache maps the callerKey of a call site to its formatted call trace
+var callerCache sync.Map
+
+// getCachedStackHeader is getStackHeader with the result cached by program
+// counter. the return PCs reported by runtime.Callers are distinct for each
+// logical frame, including inlined ones, so a PC identifies one call site.
+func getCachedStackHeader(skip int, format CallerFormat) string {
+	var pcs [1]uintptr
+	// +1 for runtime.Callers itself, which matches runtime.Caller(skip)
+	if runtime.Callers(skip+1, pcs[:]) == 0 {
+		return "unknown:0 "
+	}
+	key := callerKey{pc: pcs[0], format: format}
+	if s, ok := callerCache.Load(key); ok {
+		return s.(string)
+	}
+	frame, _ := runtime.CallersFrames(pcs[:]).Next()
+	basefile := trimCallerFile(frame.File, format)
+	fnName := frame.Function
+	if idx := strings.LastIndex(fnName, "/"); idx != -1 {
+		fnName = fnName[idx+1:]
+	}
+	s := fmt.Sprintf(" %s:%d(%s)", basefile, frame.Line, fnName)
+	callerCache.Store(key, s)
+	return s
+}
+
+// formatStack formats the current call stack for logging. the frames keep
+// the full path unless the format is CallerShort.
+func formatStack(skip int, format CallerFormat) string {
 	pc := make([]uintptr, 10)
 	n := runtime.Callers(skip, pc)
 	frames := runtime.CallersFrames(pc[:n])
@@ -241,8 +648,12 @@ func formatStack(skip int) string {
 	stack := make([]string, 0, n)
 	for {
 		frame, more := frames.Next()
+		file := frame.File
+		if format == CallerShort {
+			file = trimCallerFile(file, format)
+		}
 		stack = append(stack,
-			fmt.Sprintf(" %s:%d(%s)", frame.File, frame.Line, frame.Function))
+			fmt.Sprintf(" %s:%d(%s)", file, frame.Line, frame.Function))
 		if !more {
 			break
 		}
@@ -261,29 +672,121 @@ func (tid *traceID) String() string {
 	return fmt.Sprintf("<%s>", tid.id)
 }
 
+// headerFormatter builds the log message header. fields is the rendered
+// context fields, could be empty.
+type headerFormatter func(level LogLevel, tid *traceID, fields string) string
+
+// headerOptions holds the options used to construct the log message header
+type headerOptions struct {
+	timefmt        string
+	prefix         string
+	levelcalltrace LogLevel
+	// attach the call stack to regular log messages
+	withStack bool
+	// test mode: no call trace
+	testMode bool
+	// time source of timestamps
+	clock Clock
+	// use the call trace cache
+	callerCache bool
+	// path form of the call trace and stacks
+	callerFormat CallerFormat
+	// link form of the call trace of WARN and above levels
+	hyperlink HyperlinkScheme
+	// rendered process fields, without braces
+	procFields string
+	// attach the ts_nanos field
+	epochNanos bool
+	// attach the stack_depth field
+	stackDepth bool
+	// pad the level name to a fixed width
+	padLevel bool
+}
+
+// builtinFieldKeys returns the keys of the header fields attached by the
+// options, see LogConfig.IncludeProcessFields
+func (opts headerOptions) builtinFieldKeys() []string {
+	var keys []string
+	if opts.procFields != "" {
+		keys = append(keys, "pid", "host", "exe")
+	}
+	if opts.epochNanos {
+		keys = append(keys, "ts_nanos")
+	}
+	if opts.stackDepth {
+		keys = append(keys, "stack_depth")
+	}
+	return keys
+}
+
 // getHeaderFormatter constructs the log message header
-func getHeaderFormatter(
-	timefmt string,
-	prefix string,
-	levelcalltrace LogLevel,
-	tbskip int,
-) func(level LogLevel, tid *traceID) string {
-	return func(level LogLevel, tid *traceID) string {
+func getHeaderFormatter(opts headerOptions, tbskip int) headerFormatter {
+	timefmt := opts.timefmt
+	prefix := opts.prefix
+	levelcalltrace := opts.levelcalltrace
+	withStack := opts.withStack
+	testMode := opts.testMode
+	clock := opts.clock
+	stackHeader := getStackHeader
+	if opts.callerCache {
+		stackHeader = getCachedStackHeader
+	}
+	callerFormat := opts.callerFormat
+	hyperlink := opts.hyperlink
+	procFields := opts.procFields
+	epochNanos := opts.epochNanos
+	stackDepth := opts.stackDepth
+	padLevel := opts.padLevel
+	return func(level LogLevel, tid *traceID, fields string) string {
 		calltrace := level >= levelcalltrace
 		stackInfo := ""
-		if level >= PANIC {
-			stackInfo = formatStack(tbskip + 1)
-		} else if calltrace {
-			stackInfo = getStackHeader(tbskip)
-		}
-		timestr := time.Now().Format(timefmt)
-		// FORMAT: time [level], perfix<trace> calltrace -
-		return fmt.Sprintf("%s [%s], %s%s%s - ",
+		// test mode output must not depend on source paths
+		if !testMode {
+			if level >= PANIC || withStack {
+				stackInfo = formatStack(tbskip+1, callerFormat)
+			} else if calltrace {
+				if hyperlink != HyperlinkNone && level >= WARN {
+					stackInfo = getLinkStackHeader(tbskip, hyperlink)
+				} else {
+					stackInfo = stackHeader(tbskip, callerFormat)
+				}
+			}
+		}
+		now := clock.Now()
+		timestr := now.Format(timefmt)
+		extra := procFields
+		addExtra := func(f string) {
+			if extra == "" {
+				extra = f
+			} else {
+				extra = extra + " " + f
+			}
+		}
+		if epochNanos {
+			addExtra("ts_nanos=" + strconv.FormatInt(now.UnixNano(), 10))
+		}
+		if stackDepth {
+			addExtra("stack_depth=" + strconv.Itoa(callDepth(tbskip)))
+		}
+		if extra != "" {
+			if fields == "" {
+				fields = " {" + extra + "}"
+			} else {
+				fields = " {" + extra + " " + fields[2:]
+			}
+		}
+		levelstr := level.String()
+		if padLevel {
+			levelstr = fmt.Sprintf("%-*s", levelNameWidth, levelstr)
+		}
+		// FORMAT: time [level], perfix<trace> calltrace {fields} -
+		return fmt.Sprintf("%s [%s], %s%s%s%s - ",
 			timestr,
-			level.String(),
+			levelstr,
 			prefix,
 			tid.String(),
 			stackInfo,
+			fields,
 		)
 	}
 }
@@ -301,43 +804,221 @@ func New(name string, config LogConfig) Logger {
 	if name == "" {
 		name = "*"
 	}
+	var idgen func() string
+	clock := config.Clock
+	if config.TestMode {
+		idgen = sequenceIDGenerator()
+		if clock == nil {
+			clock = NewMockClock(testModeTime)
+		}
+	}
+	if clock == nil {
+		clock = RealClock
+	}
+	procFields := ""
+	if config.IncludeProcessFields {
+		procFields = processFieldsString(config.InstanceID)
+	}
 	return &logger{
 		logHandler: hander,
 		level:      config.Level,
+		levelct:    config.LevelWithTrace,
 		prefix:     name,
 		timefmt:    timefmt,
-		fmtHeader: getHeaderFormatter(
-			timefmt,
-			name,
-			config.LevelWithTrace,
-			4,
-		),
+		testMode:   config.TestMode,
+		idgen:      idgen,
+		clock:      clock,
+		callerc:    config.CallerCache,
+		callerFmt:  config.CallerFormat,
+		hyperlink:  config.CallerHyperlink,
+		procFields: procFields,
+		epochNanos: config.IncludeEpochNanos,
+		stackDepth: config.IncludeStackDepth,
+		padLevel:   config.PadLevel,
+		throttles:  &sync.Map{},
+
+		verboseErrors: config.VerboseErrors,
+		binaryEnc:     config.BinaryEncoding,
+		policy:        maps.Clone(config.LevelPolicy),
+		strictFields:  config.StrictFields,
+		debugConfig:   config.DebugConfig,
+		fmtHeader: getHeaderFormatter(headerOptions{
+			timefmt:        timefmt,
+			prefix:         name,
+			levelcalltrace: config.LevelWithTrace,
+			testMode:       config.TestMode,
+			clock:          clock,
+			callerCache:    config.CallerCache,
+			callerFormat:   config.CallerFormat,
+			hyperlink:      config.CallerHyperlink,
+			procFields:     procFields,
+			epochNanos:     config.IncludeEpochNanos,
+			stackDepth:     config.IncludeStackDepth,
+			padLevel:       config.PadLevel,
+		}, 4),
+	}
+}
+
+// headerOptions returns the header options of the logger. the caller should
+// hold the lock.
+func (l *logger) headerOptions() headerOptions {
+	return headerOptions{
+		timefmt:        l.timefmt,
+		prefix:         l.prefix,
+		levelcalltrace: l.levelct,
+		withStack:      l.withStack,
+		testMode:       l.testMode,
+		clock:          l.clock,
+		callerCache:    l.callerc,
+		callerFormat:   l.callerFmt,
+		hyperlink:      l.hyperlink,
+		procFields:     l.procFields,
+		epochNanos:     l.epochNanos,
+		stackDepth:     l.stackDepth,
+		padLevel:       l.padLevel,
 	}
 }
 
+// enabled reports whether a regular log message of the level should be
+// output, according to the level of the logger and the global pause
+func (l *logger) enabled(level LogLevel) bool {
+	return !paused.Load() &&
+		atomic.LoadInt32((*int32)(&l.level)) <= int32(level)
+}
+
 // getFmtHeader safely retrieves the fmtHeader function
-func (l *logger) getFmtHeader() func(level LogLevel, tid *traceID) string {
+func (l *logger) getFmtHeader() headerFormatter {
 	l.mtx.RLock()
 	defer l.mtx.RUnlock()
 	return l.fmtHeader
 }
 
+// args returns the message arguments passed to the log handler
+func (l *logger) args(message []any) []any {
+	if l.verboseErrors {
+		message = verboseArgs(message)
+	}
+	if l.binaryEnc != BinaryDefault {
+		message = binaryArgs(message, l.binaryEnc)
+	}
+	return message
+}
+
+// sprintf formats the message of the formatted output methods
+func (l *logger) sprintf(format string, args ...any) string {
+	return fmt.Sprintf(format, l.args(args)...)
+}
+
+// regularLog passes a regular log message to the log handler, along with
+// the metadata if the handler accepts it. fields are the header fields
+// rendered in header, for the checks of RequireFields.
+func (l *logger) regularLog(
+	level LogLevel, header string, fields []Field, message []any,
+) {
+	if len(l.required) > 0 {
+		defer l.checkRequiredFields(level, fields, message)
+	}
+	if len(l.meta) > 0 {
+		if mh, ok := l.logHandler.(MetaLogHandler); ok {
+			mh.RegularLogMeta(level, header, l.meta, l.args(message)...)
+			return
+		}
+	}
+	l.logHandler.RegularLog(level, header, l.args(message)...)
+}
+
+// checkRequiredFields outputs a WARN message if the header fields of the
+// regular log message lack any of the fields required by RequireFields
+func (l *logger) checkRequiredFields(
+	level LogLevel, fields []Field, message []any,
+) {
+	l.mtx.RLock()
+	opts := l.headerOptions()
+	handler := l.logHandler
+	l.mtx.RUnlock()
+	var missing []string
+	for _, k := range l.required {
+		if !slices.ContainsFunc(fields, func(f Field) bool {
+			return f.Key == k
+		}) && !slices.Contains(opts.builtinFieldKeys(), k) {
+			missing = append(missing, k)
+		}
+	}
+	if len(missing) == 0 || !l.enabled(WARN) {
+		return
+	}
+	opts.levelcalltrace = FATAL + 1 // the call trace would be misleading
+	opts.withStack = false
+	wh := getHeaderFormatter(opts, 0)(WARN, nil, "")
+	handler.RegularLog(WARN, wh, fmt.Sprintf(
+		"%s message missing required fields %s: %s",
+		level, strings.Join(missing, ","),
+		strings.TrimSuffix(fmt.Sprintln(message...), "\n")))
+}
+
 // outputRegularLog outputs a regular log message
 func (l *logger) outputRegularLog(level LogLevel, message ...any) {
-	header := l.getFmtHeader()(level, nil)
-	l.logHandler.RegularLog(level, header, message...)
+	header := l.getFmtHeader()(level, nil, "")
+	l.regularLog(level, header, nil, message)
+}
+
+// outputContextLog outputs a regular log message with the context fields
+func (l *logger) outputContextLog(
+	ctx context.Context, level LogLevel, message ...any,
+) {
+	fields := collectFields(nil, ctx)
+	header := l.getFmtHeader()(level, nil, renderFields(fields))
+	l.regularLog(level, header, fields, message)
+}
+
+// outputSkipLog outputs a regular log message, the call trace skips
+// additional frames. the header formatter is built for this call only.
+func (l *logger) outputSkipLog(level LogLevel, skip int, message ...any) {
+	l.mtx.RLock()
+	fh := getHeaderFormatter(l.headerOptions(), 4+max(skip, 0))
+	l.mtx.RUnlock()
+	header := fh(level, nil, "")
+	l.regularLog(level, header, nil, message)
 }
 
 // outputPanicLog outputs a panic log message
 func (l *logger) outputPanicLog(message ...any) {
-	header := l.getFmtHeader()(PANIC, nil)
-	l.logHandler.PanicLog(header, message...)
+	header := l.getFmtHeader()(PANIC, nil,
+		fieldsString(causeFields(message), nil))
+	if b, ok := l.policy[PANIC]; ok {
+		l.outputPolicyLog(PANIC, b, header, message)
+		return
+	}
+	l.logHandler.PanicLog(header, l.args(message)...)
 }
 
 // outputFatalLog outputs a fatal log message
 func (l *logger) outputFatalLog(message ...any) {
-	header := l.getFmtHeader()(FATAL, nil)
-	l.logHandler.FatalLog(header, message...)
+	header := l.getFmtHeader()(FATAL, nil,
+		fieldsString(causeFields(message), nil))
+	if b, ok := l.policy[FATAL]; ok {
+		l.outputPolicyLog(FATAL, b, header, message)
+		return
+	}
+	l.logHandler.FatalLog(header, l.args(message)...)
+}
+
+// outputPolicyLog outputs a log message as a regular one, then exits or
+// panics according to the level behavior
+func (l *logger) outputPolicyLog(
+	level LogLevel, b LevelBehavior, header string, message []any,
+) {
+	args := l.args(message)
+	l.logHandler.RegularLog(level, header, args...)
+	if !b.Exit && !b.Panic {
+		return
+	}
+	flushHandler(l.logHandler)
+	if b.Exit {
+		sysExit(b.ExitCode)
+		return
+	}
+	panic(fmt.Sprintln(args...))
 }
 
 // ------- implement RawWriter interface for logger -------
@@ -361,20 +1042,41 @@ func (l *logger) Write(p []byte) (n int, err error) {
 
 // ------- implement BasicLogger interface for logger -------
 
+func (l *logger) Trc(message ...any) {
+	if l.enabled(TRACE) {
+		l.outputRegularLog(TRACE, message...)
+	}
+}
+
+func (l *logger) Trcf(format string, args ...any) {
+	if l.enabled(TRACE) {
+		l.outputRegularLog(TRACE, l.sprintf(format, args...))
+	}
+}
+
+func (l *logger) TrcP() func(message ...any) {
+	if l.enabled(TRACE) {
+		return func(message ...any) {
+			l.outputRegularLog(TRACE, message...)
+		}
+	}
+	return nil
+}
+
 func (l *logger) Dbg(message ...any) {
-	if atomic.LoadUint32((*uint32)(&l.level)) <= uint32(DEBUG) {
+	if l.enabled(DEBUG) {
 		l.outputRegularLog(DEBUG, message...)
 	}
 }
 
 func (l *logger) Dbgf(format string, args ...any) {
-	if atomic.LoadUint32((*uint32)(&l.level)) <= uint32(DEBUG) {
-		l.outputRegularLog(DEBUG, fmt.Sprintf(format, args...))
+	if l.enabled(DEBUG) {
+		l.outputRegularLog(DEBUG, l.sprintf(format, args...))
 	}
 }
 
 func (l *logger) DbgP() func(message ...any) {
-	if atomic.LoadUint32((*uint32)(&l.level)) <= uint32(DEBUG) {
+	if l.enabled(DEBUG) {
 		return func(message ...any) {
 			l.outputRegularLog(DEBUG, message...)
 		}
@@ -382,20 +1084,86 @@ func (l *logger) DbgP() func(message ...any) {
 	return nil
 }
 
+// dbgPTimeoutSlots bounds the formatting goroutines of DbgPTimeout in
+// flight, including the ones still running after their timeout
+var dbgPTimeoutSlots = make(chan struct{}, 64)
+
+// hasFormatMethod reports whether fmt formats the value by its own method,
+// which might be slow
+func hasFormatMethod(v any) bool {
+	switch v.(type) {
+	case fmt.Formatter, fmt.Stringer, fmt.GoStringer, error:
+		return true
+	}
+	return false
+}
+
+func (l *logger) DbgPTimeout(d time.Duration) func(message ...any) {
+	if !l.enabled(DEBUG) {
+		return nil
+	}
+	return func(message ...any) {
+		// the arguments are snapshotted: the values without a formatting
+		// method are formatted right now, so only the values of the
+		// methods are read after the call returns
+		args := l.args(message)
+		snap := make([]any, len(args))
+		lazy := false
+		for i, a := range args {
+			if hasFormatMethod(message[i]) {
+				snap[i], lazy = a, true
+			} else {
+				snap[i] = fmt.Sprint(a)
+			}
+		}
+		sprint := func() string {
+			return strings.TrimSuffix(fmt.Sprintln(snap...), "\n")
+		}
+		if !lazy {
+			l.outputRegularLog(DEBUG, sprint())
+			return
+		}
+		slots := dbgPTimeoutSlots
+		select {
+		case slots <- struct{}{}:
+		default:
+			l.outputRegularLog(DEBUG, fmt.Sprintf(
+				"<message formatting skipped, %d formattings in flight>",
+				cap(slots)))
+			return
+		}
+		// buffered, so an abandoned formatting doesn't block its goroutine
+		done := make(chan string, 1)
+		go func() {
+			defer func() { <-slots }()
+			done <- sprint()
+		}()
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case msg := <-done:
+			l.outputRegularLog(DEBUG, msg)
+		case <-timer.C:
+			l.outputRegularLog(DEBUG,
+				fmt.Sprintf("<message formatting exceeded %v>", d))
+		}
+	}
+}
+
 func (l *logger) Inf(message ...any) {
-	if atomic.LoadUint32((*uint32)(&l.level)) <= uint32(INFO) {
+	if l.enabled(INFO) {
 		l.outputRegularLog(INFO, message...)
 	}
 }
 
 func (l *logger) Inff(format string, args ...any) {
-	if atomic.LoadUint32((*uint32)(&l.level)) <= uint32(INFO) {
-		l.outputRegularLog(INFO, fmt.Sprintf(format, args...))
+	if l.enabled(INFO) {
+		l.outputRegularLog(INFO, l.sprintf(format, args...))
 	}
 }
 
 func (l *logger) InfP() func(message ...any) {
-	if atomic.LoadUint32((*uint32)(&l.level)) <= uint32(INFO) {
+	if l.enabled(INFO) {
 		return func(message ...any) {
 			l.outputRegularLog(INFO, message...)
 		}
@@ -404,19 +1172,19 @@ func (l *logger) InfP() func(message ...any) {
 }
 
 func (l *logger) War(message ...any) {
-	if atomic.LoadUint32((*uint32)(&l.level)) <= uint32(WARN) {
+	if l.enabled(WARN) {
 		l.outputRegularLog(WARN, message...)
 	}
 }
 
 func (l *logger) Warf(format string, args ...any) {
-	if atomic.LoadUint32((*uint32)(&l.level)) <= uint32(WARN) {
-		l.outputRegularLog(WARN, fmt.Sprintf(format, args...))
+	if l.enabled(WARN) {
+		l.outputRegularLog(WARN, l.sprintf(format, args...))
 	}
 }
 
 func (l *logger) WarP() func(message ...any) {
-	if atomic.LoadUint32((*uint32)(&l.level)) <= uint32(WARN) {
+	if l.enabled(WARN) {
 		return func(message ...any) {
 			l.outputRegularLog(WARN, message...)
 		}
@@ -425,19 +1193,19 @@ func (l *logger) WarP() func(message ...any) {
 }
 
 func (l *logger) Err(message ...any) {
-	if atomic.LoadUint32((*uint32)(&l.level)) <= uint32(ERROR) {
+	if l.enabled(ERROR) {
 		l.outputRegularLog(ERROR, message...)
 	}
 }
 
 func (l *logger) Errf(format string, args ...any) {
-	if atomic.LoadUint32((*uint32)(&l.level)) <= uint32(ERROR) {
-		l.outputRegularLog(ERROR, fmt.Sprintf(format, args...))
+	if l.enabled(ERROR) {
+		l.outputRegularLog(ERROR, l.sprintf(format, args...))
 	}
 }
 
 func (l *logger) ErrP() func(message ...any) {
-	if atomic.LoadUint32((*uint32)(&l.level)) <= uint32(ERROR) {
+	if l.enabled(ERROR) {
 		return func(message ...any) {
 			l.outputRegularLog(ERROR, message...)
 		}
@@ -445,16 +1213,76 @@ func (l *logger) ErrP() func(message ...any) {
 	return nil
 }
 
+func (l *logger) TrcCtx(ctx context.Context, message ...any) {
+	if l.enabled(TRACE) {
+		l.outputContextLog(ctx, TRACE, message...)
+	}
+}
+
+func (l *logger) DbgCtx(ctx context.Context, message ...any) {
+	if l.enabled(DEBUG) {
+		l.outputContextLog(ctx, DEBUG, message...)
+	}
+}
+
+func (l *logger) InfCtx(ctx context.Context, message ...any) {
+	if l.enabled(INFO) {
+		l.outputContextLog(ctx, INFO, message...)
+	}
+}
+
+func (l *logger) WarCtx(ctx context.Context, message ...any) {
+	if l.enabled(WARN) {
+		l.outputContextLog(ctx, WARN, message...)
+	}
+}
+
+func (l *logger) ErrCtx(ctx context.Context, message ...any) {
+	if l.enabled(ERROR) {
+		l.outputContextLog(ctx, ERROR, message...)
+	}
+}
+
 // --------------------------------------------------------------
 
 // ------- implement Logger interface for logger -------
 
+func (l *logger) TrcSkip(skip int, message ...any) {
+	if l.enabled(TRACE) {
+		l.outputSkipLog(TRACE, skip, message...)
+	}
+}
+
+func (l *logger) DbgSkip(skip int, message ...any) {
+	if l.enabled(DEBUG) {
+		l.outputSkipLog(DEBUG, skip, message...)
+	}
+}
+
+func (l *logger) InfSkip(skip int, message ...any) {
+	if l.enabled(INFO) {
+		l.outputSkipLog(INFO, skip, message...)
+	}
+}
+
+func (l *logger) WarSkip(skip int, message ...any) {
+	if l.enabled(WARN) {
+		l.outputSkipLog(WARN, skip, message...)
+	}
+}
+
+func (l *logger) ErrSkip(skip int, message ...any) {
+	if l.enabled(ERROR) {
+		l.outputSkipLog(ERROR, skip, message...)
+	}
+}
+
 func (l *logger) Panic(message ...any) {
 	l.outputPanicLog(message...)
 }
 
 func (l *logger) Panicf(format string, args ...any) {
-	l.outputPanicLog(fmt.Sprintf(format, args...))
+	l.outputPanicLog(l.sprintf(format, args...))
 }
 
 func (l *logger) Fatal(message ...any) {
@@ -462,14 +1290,45 @@ func (l *logger) Fatal(message ...any) {
 }
 
 func (l *logger) Fatalf(format string, args ...any) {
-	l.outputFatalLog(fmt.Sprintf(format, args...))
+	l.outputFatalLog(l.sprintf(format, args...))
+}
+
+func (l *logger) ErrReturn(err error, message ...any) error {
+	if err == nil {
+		return nil
+	}
+	if l.enabled(ERROR) {
+		args := make([]any, 0, len(message)+1)
+		args = append(append(args, message...), err)
+		l.outputRegularLog(ERROR, args...)
+	}
+	return err
+}
+
+func (l *logger) Enter(name string) func() {
+	if l.enabled(DEBUG) {
+		l.outputRegularLog(DEBUG, "enter "+name)
+	}
+	start := l.clock.Now()
+	return func() {
+		if r := recover(); r != nil {
+			l.outputRegularLog(PANIC, fmt.Sprintf("panic %s (%s):", name,
+				l.clock.Since(start)), r)
+			panic(r)
+		}
+		if l.enabled(DEBUG) {
+			l.outputRegularLog(DEBUG, fmt.Sprintf("exit %s (%s)", name,
+				l.clock.Since(start)))
+		}
+	}
 }
 
 func (l *logger) Trace(name string) TraceLogger {
-	tid := newTraceID(name)
+	tid := newTraceID(name, l.idgen)
 	return &traceLogger{
 		parent: l,
 		tid:    tid,
+		start:  l.clock.Now(),
 	}
 }
 
@@ -480,46 +1339,184 @@ func (l *logger) Derive(pfx string) Logger {
 	if pfx != "" {
 		newPrefix = newPrefix + "." + pfx
 	}
+	var derivedParent *logger
+	var levelNoted *atomic.Bool
+	if l.debugConfig {
+		derivedParent, levelNoted = l, &atomic.Bool{}
+	}
 	return &logger{
 		logHandler: l.logHandler,
 		level:      l.level,
+		levelct:    l.levelct,
 		prefix:     newPrefix,
 		timefmt:    l.timefmt,
-		fmtHeader: getHeaderFormatter(
-			l.timefmt,
-			newPrefix,
-			l.levelct,
-			4,
-		),
+		testMode:   l.testMode,
+		idgen:      l.idgen,
+		clock:      l.clock,
+		callerc:    l.callerc,
+		callerFmt:  l.callerFmt,
+		hyperlink:  l.hyperlink,
+		procFields: l.procFields,
+		epochNanos: l.epochNanos,
+		stackDepth: l.stackDepth,
+		padLevel:   l.padLevel,
+		throttles:  l.throttles,
+
+		verboseErrors: l.verboseErrors,
+		binaryEnc:     l.binaryEnc,
+		policy:        l.policy,
+		meta:          l.meta,
+		strictFields:  l.strictFields,
+		required:      l.required,
+		debugConfig:   l.debugConfig,
+		parent:        derivedParent,
+		levelNoted:    levelNoted,
+		fmtHeader: getHeaderFormatter(headerOptions{
+			timefmt:        l.timefmt,
+			prefix:         newPrefix,
+			levelcalltrace: l.levelct,
+			testMode:       l.testMode,
+			clock:          l.clock,
+			callerCache:    l.callerc,
+			callerFormat:   l.callerFmt,
+			hyperlink:      l.hyperlink,
+			procFields:     l.procFields,
+			epochNanos:     l.epochNanos,
+			stackDepth:     l.stackDepth,
+			padLevel:       l.padLevel,
+		}, 4),
+	}
+}
+
+func (l *logger) Named(name string) Logger {
+	return l.Derive(name)
+}
+
+func (l *logger) Name() string {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+	return l.prefix
+}
+
+// sibling returns a copy of the logger with the same prefix. the caller
+// should rebuild fmtHeader if any header option is changed. must be called
+// with mtx held.
+func (l *logger) sibling() *logger {
+	return &logger{
+		logHandler: l.logHandler,
+		level:      LogLevel(atomic.LoadInt32((*int32)(&l.level))),
+		levelct:    l.levelct,
+		prefix:     l.prefix,
+		timefmt:    l.timefmt,
+		withStack:  l.withStack,
+		testMode:   l.testMode,
+		idgen:      l.idgen,
+		clock:      l.clock,
+		callerc:    l.callerc,
+		callerFmt:  l.callerFmt,
+		hyperlink:  l.hyperlink,
+		procFields: l.procFields,
+		epochNanos: l.epochNanos,
+		stackDepth: l.stackDepth,
+		padLevel:   l.padLevel,
+		fmtHeader:  l.fmtHeader,
+		throttles:  l.throttles,
+
+		verboseErrors: l.verboseErrors,
+		binaryEnc:     l.binaryEnc,
+		policy:        l.policy,
+		meta:          l.meta,
+		strictFields:  l.strictFields,
+		required:      l.required,
+		debugConfig:   l.debugConfig,
+		parent:        l.parent,
+		levelNoted:    l.levelNoted,
 	}
 }
 
+func (l *logger) WithStack() Logger {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+	nl := l.sibling()
+	nl.withStack = true
+	nl.fmtHeader = getHeaderFormatter(nl.headerOptions(), 4)
+	return nl
+}
+
+func (l *logger) WithMeta(key string, value any) Logger {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+	nl := l.sibling()
+	meta := make([]Field, 0, len(l.meta)+1)
+	for _, f := range l.meta {
+		if f.Key != key {
+			meta = append(meta, f)
+		}
+	}
+	nl.meta = append(meta, Field{Key: key, Value: fmt.Sprint(value)})
+	return nl
+}
+
+func (l *logger) RequireFields(keys ...string) Logger {
+	if !l.strictFields || len(keys) == 0 {
+		return l
+	}
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+	nl := l.sibling()
+	required := slices.Clone(l.required)
+	for _, k := range keys {
+		if !slices.Contains(required, k) {
+			required = append(required, k)
+		}
+	}
+	nl.required = required
+	return nl
+}
+
+// Level implements LevelReporter
+func (l *logger) Level() LogLevel {
+	return LogLevel(atomic.LoadInt32((*int32)(&l.level)))
+}
+
 func (l *logger) SetLevel(level LogLevel) {
-	atomic.StoreUint32((*uint32)(&l.level), uint32(level))
+	atomic.StoreInt32((*int32)(&l.level), int32(level))
+	if l.parent != nil {
+		l.noteLevelOverride(level)
+	}
+}
+
+// noteLevelOverride outputs the one-time DEBUG note of LogConfig.DebugConfig
+// if the level differs from the level of the parent
+func (l *logger) noteLevelOverride(level LogLevel) {
+	plevel := LogLevel(atomic.LoadInt32((*int32)(&l.parent.level)))
+	if level == plevel || paused.Load() ||
+		!l.levelNoted.CompareAndSwap(false, true) {
+		return
+	}
+	l.mtx.RLock()
+	opts := l.headerOptions()
+	handler := l.logHandler
+	l.mtx.RUnlock()
+	opts.levelcalltrace = FATAL + 1 // the call trace would be misleading
+	opts.withStack = false
+	header := getHeaderFormatter(opts, 0)(DEBUG, nil, "")
+	handler.RegularLog(DEBUG, header, fmt.Sprintf(
+		"derived %s at %s, parent at %s", opts.prefix, level, plevel))
 }
 
 func (l *logger) SetCallTraceLevel(level LogLevel) {
 	l.mtx.Lock()
 	defer l.mtx.Unlock()
 	l.levelct = level
-	l.fmtHeader = getHeaderFormatter(
-		l.timefmt,
-		l.prefix,
-		l.levelct,
-		4,
-	)
+	l.fmtHeader = getHeaderFormatter(l.headerOptions(), 4)
 }
 
 func (l *logger) SetTimeFormat(format string) {
 	l.mtx.Lock()
 	defer l.mtx.Unlock()
 	l.timefmt = format
-	l.fmtHeader = getHeaderFormatter(
-		l.timefmt,
-		l.prefix,
-		l.levelct,
-		4,
-	)
+	l.fmtHeader = getHeaderFormatter(l.headerOptions(), 4)
 }
 
 func (l *logger) SetLogHandler(handler LogHandler) {
@@ -537,22 +1534,27 @@ func (l *logger) WrapLogHandler(wrapper func(old LogHandler) LogHandler) {
 	}
 }
 
+func (l *logger) Flush() error {
+	l.mtx.RLock()
+	h := l.logHandler
+	l.mtx.RUnlock()
+	return flushHandler(h)
+}
+
 func (l *logger) GetWriter(level LogLevel, calltrace bool) io.StringWriter {
-	if atomic.LoadUint32((*uint32)(&l.level)) <= uint32(level) {
+	if l.enabled(level) {
 		ctlv := level
 		if !calltrace {
 			ctlv = ctlv + 1
 		}
-		fh := getHeaderFormatter(
-			l.timefmt,
-			l.prefix,
-			ctlv,
-			7,
-		)
+		opts := l.headerOptions()
+		opts.levelcalltrace = ctlv
+		fh := getHeaderFormatter(opts, 4)
 		return &levelWriter{
 			parent: l,
+			level:  level,
 			fmtHeader: func() string {
-				return fh(level, nil)
+				return fh(level, nil, "")
 			},
 		}
 	}
@@ -567,25 +1569,87 @@ func (l *logger) RawWriter() RawWriter {
 
 // ------- implement TraceLogger interface for traceLogger -------
 
+// getTraceID safely retrieves a snapshot of the traceID
+func (tl *traceLogger) getTraceID() traceID {
+	tl.mtx.RLock()
+	defer tl.mtx.RUnlock()
+	return tl.tid
+}
+
+// getBaggage returns a snapshot of the baggage
+func (tl *traceLogger) getBaggage() []Field {
+	tl.mtx.RLock()
+	defer tl.mtx.RUnlock()
+	return tl.baggage
+}
+
+// headerFields returns the baggage, followed by the breadcrumbs for ERROR
+// and above levels
+func (tl *traceLogger) headerFields(level LogLevel) []Field {
+	tl.mtx.RLock()
+	defer tl.mtx.RUnlock()
+	if level < ERROR || len(tl.crumbs) == 0 {
+		return tl.baggage
+	}
+	fields := make([]Field, 0, len(tl.baggage)+1)
+	fields = append(fields, tl.baggage...)
+	return append(fields, Field{
+		Key:   "breadcrumbs",
+		Value: strings.Join(tl.crumbs, " > "),
+	})
+}
+
 func (tl *traceLogger) regularLog(level LogLevel, message ...any) {
-	header := tl.parent.getFmtHeader()(level, &tl.tid)
-	tl.parent.logHandler.RegularLog(level, header, message...)
+	tid := tl.getTraceID()
+	fields := tl.headerFields(level)
+	header := tl.parent.getFmtHeader()(level, &tid, renderFields(fields))
+	tl.parent.regularLog(level, header, fields, message)
+}
+
+func (tl *traceLogger) contextLog(
+	ctx context.Context, level LogLevel, message ...any,
+) {
+	tid := tl.getTraceID()
+	fields := collectFields(tl.headerFields(level), ctx)
+	header := tl.parent.getFmtHeader()(level, &tid, renderFields(fields))
+	tl.parent.regularLog(level, header, fields, message)
+}
+
+func (tl *traceLogger) Trc(message ...any) {
+	if tl.parent.enabled(TRACE) {
+		tl.regularLog(TRACE, message...)
+	}
+}
+
+func (tl *traceLogger) Trcf(format string, args ...any) {
+	if tl.parent.enabled(TRACE) {
+		tl.regularLog(TRACE, tl.parent.sprintf(format, args...))
+	}
+}
+
+func (tl *traceLogger) TrcP() func(message ...any) {
+	if tl.parent.enabled(TRACE) {
+		return func(message ...any) {
+			tl.regularLog(TRACE, message...)
+		}
+	}
+	return nil
 }
 
 func (tl *traceLogger) Dbg(message ...any) {
-	if atomic.LoadUint32((*uint32)(&tl.parent.level)) <= uint32(DEBUG) {
+	if tl.parent.enabled(DEBUG) {
 		tl.regularLog(DEBUG, message...)
 	}
 }
 
 func (tl *traceLogger) Dbgf(format string, args ...any) {
-	if atomic.LoadUint32((*uint32)(&tl.parent.level)) <= uint32(DEBUG) {
-		tl.regularLog(DEBUG, fmt.Sprintf(format, args...))
+	if tl.parent.enabled(DEBUG) {
+		tl.regularLog(DEBUG, tl.parent.sprintf(format, args...))
 	}
 }
 
 func (tl *traceLogger) DbgP() func(message ...any) {
-	if atomic.LoadUint32((*uint32)(&tl.parent.level)) <= uint32(DEBUG) {
+	if tl.parent.enabled(DEBUG) {
 		return func(message ...any) {
 			tl.regularLog(DEBUG, message...)
 		}
@@ -594,19 +1658,19 @@ func (tl *traceLogger) DbgP() func(message ...any) {
 }
 
 func (tl *traceLogger) Inf(message ...any) {
-	if atomic.LoadUint32((*uint32)(&tl.parent.level)) <= uint32(INFO) {
+	if tl.parent.enabled(INFO) {
 		tl.regularLog(INFO, message...)
 	}
 }
 
 func (tl *traceLogger) Inff(format string, args ...any) {
-	if atomic.LoadUint32((*uint32)(&tl.parent.level)) <= uint32(INFO) {
-		tl.regularLog(INFO, fmt.Sprintf(format, args...))
+	if tl.parent.enabled(INFO) {
+		tl.regularLog(INFO, tl.parent.sprintf(format, args...))
 	}
 }
 
 func (tl *traceLogger) InfP() func(message ...any) {
-	if atomic.LoadUint32((*uint32)(&tl.parent.level)) <= uint32(INFO) {
+	if tl.parent.enabled(INFO) {
 		return func(message ...any) {
 			tl.regularLog(INFO, message...)
 		}
@@ -615,19 +1679,19 @@ func (tl *traceLogger) InfP() func(message ...any) {
 }
 
 func (tl *traceLogger) War(message ...any) {
-	if atomic.LoadUint32((*uint32)(&tl.parent.level)) <= uint32(WARN) {
+	if tl.parent.enabled(WARN) {
 		tl.regularLog(WARN, message...)
 	}
 }
 
 func (tl *traceLogger) Warf(format string, args ...any) {
-	if atomic.LoadUint32((*uint32)(&tl.parent.level)) <= uint32(WARN) {
-		tl.regularLog(WARN, fmt.Sprintf(format, args...))
+	if tl.parent.enabled(WARN) {
+		tl.regularLog(WARN, tl.parent.sprintf(format, args...))
 	}
 }
 
 func (tl *traceLogger) WarP() func(message ...any) {
-	if atomic.LoadUint32((*uint32)(&tl.parent.level)) <= uint32(WARN) {
+	if tl.parent.enabled(WARN) {
 		return func(message ...any) {
 			tl.regularLog(WARN, message...)
 		}
@@ -636,19 +1700,19 @@ func (tl *traceLogger) WarP() func(message ...any) {
 }
 
 func (tl *traceLogger) Err(message ...any) {
-	if atomic.LoadUint32((*uint32)(&tl.parent.level)) <= uint32(ERROR) {
+	if tl.parent.enabled(ERROR) {
 		tl.regularLog(ERROR, message...)
 	}
 }
 
 func (tl *traceLogger) Errf(format string, args ...any) {
-	if atomic.LoadUint32((*uint32)(&tl.parent.level)) <= uint32(ERROR) {
-		tl.regularLog(ERROR, fmt.Sprintf(format, args...))
+	if tl.parent.enabled(ERROR) {
+		tl.regularLog(ERROR, tl.parent.sprintf(format, args...))
 	}
 }
 
 func (tl *traceLogger) ErrP() func(message ...any) {
-	if atomic.LoadUint32((*uint32)(&tl.parent.level)) <= uint32(ERROR) {
+	if tl.parent.enabled(ERROR) {
 		return func(message ...any) {
 			tl.regularLog(ERROR, message...)
 		}
@@ -656,19 +1720,122 @@ func (tl *traceLogger) ErrP() func(message ...any) {
 	return nil
 }
 
+func (tl *traceLogger) TrcCtx(ctx context.Context, message ...any) {
+	if tl.parent.enabled(TRACE) {
+		tl.contextLog(ctx, TRACE, message...)
+	}
+}
+
+func (tl *traceLogger) DbgCtx(ctx context.Context, message ...any) {
+	if tl.parent.enabled(DEBUG) {
+		tl.contextLog(ctx, DEBUG, message...)
+	}
+}
+
+func (tl *traceLogger) InfCtx(ctx context.Context, message ...any) {
+	if tl.parent.enabled(INFO) {
+		tl.contextLog(ctx, INFO, message...)
+	}
+}
+
+func (tl *traceLogger) WarCtx(ctx context.Context, message ...any) {
+	if tl.parent.enabled(WARN) {
+		tl.contextLog(ctx, WARN, message...)
+	}
+}
+
+func (tl *traceLogger) ErrCtx(ctx context.Context, message ...any) {
+	if tl.parent.enabled(ERROR) {
+		tl.contextLog(ctx, ERROR, message...)
+	}
+}
+
 func (tl *traceLogger) TraceID() string {
 	return tl.tid.id
 }
 
 func (tl *traceLogger) TraceName() string {
+	tl.mtx.RLock()
+	defer tl.mtx.RUnlock()
 	return tl.tid.name
 }
 
+func (tl *traceLogger) Elapsed() time.Duration {
+	return tl.parent.clock.Since(tl.start)
+}
+
+func (tl *traceLogger) SetName(name string) {
+	tl.mtx.Lock()
+	defer tl.mtx.Unlock()
+	tl.tid.name = name
+}
+
+func (tl *traceLogger) SetBaggage(key, value string) {
+	tl.mtx.Lock()
+	defer tl.mtx.Unlock()
+	baggage := make([]Field, 0, len(tl.baggage)+1)
+	replaced := false
+	for _, f := range tl.baggage {
+		if f.Key == key {
+			f.Value = value
+			replaced = true
+		}
+		baggage = append(baggage, f)
+	}
+	if !replaced {
+		baggage = append(baggage, Field{Key: key, Value: value})
+	}
+	tl.baggage = baggage
+}
+
+func (tl *traceLogger) Breadcrumb(s string) {
+	tl.mtx.Lock()
+	defer tl.mtx.Unlock()
+	if len(tl.crumbs) == MaxBreadcrumbs {
+		copy(tl.crumbs, tl.crumbs[1:])
+		tl.crumbs[len(tl.crumbs)-1] = s
+		return
+	}
+	tl.crumbs = append(tl.crumbs, s)
+}
+
+func (tl *traceLogger) Child(name string) TraceLogger {
+	return &traceLogger{
+		parent:  tl.parent,
+		tid:     newTraceID(name, tl.parent.idgen),
+		start:   tl.parent.clock.Now(),
+		baggage: tl.getBaggage(),
+	}
+}
+
+func (tl *traceLogger) Span(name string, f func()) {
+	if tl.parent.enabled(DEBUG) {
+		tl.regularLog(DEBUG, "span start", name)
+	}
+	start := tl.parent.clock.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			tl.regularLog(PANIC, "span panic", name, r)
+			panic(r)
+		}
+	}()
+	f()
+	if tl.parent.enabled(INFO) {
+		tl.regularLog(INFO, "span end", name, tl.parent.clock.Since(start))
+	}
+}
+
 // ------- implement StringWriter interface for levelWriter -------
 
 func (lw *levelWriter) WriteString(s string) (n int, err error) {
-	lw.parent.logHandler.RegularWriter(INFO, func(w io.StringWriter) {
-		w.WriteString(lw.fmtHeader())
+	if paused.Load() {
+		return len(s), nil
+	}
+	// the header is built before calling the handler, so the call trace
+	// doesn't depend on the call depth inside the handler
+	header := lw.fmtHeader()
+	lw.parent.logHandler.RegularWriter(lw.level, func(w io.StringWriter) {
+		w.WriteString(header)
 		w.WriteString(s)
 		if !strings.HasSuffix(s, "\n") {
 			w.WriteString("\n")
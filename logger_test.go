@@ -1,11 +1,17 @@
 package nekomimi
 
 import (
+	"compress/gzip"
 	"context"
+	"encoding/json"
+	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 
@@ -35,6 +41,66 @@ func (tlh *testLogHandler) clean() {
 	tlh.tinyCalled = false
 }
 
+// captureLogHandler records every formatted log line for assertions
+type captureLogHandler struct {
+	mtx    sync.Mutex
+	lines  []string
+	levels []LogLevel
+}
+
+func (c *captureLogHandler) record(level LogLevel, pnt func(io.StringWriter)) {
+	sb := strings.Builder{}
+	pnt(&sb)
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.lines = append(c.lines, sb.String())
+	c.levels = append(c.levels, level)
+}
+
+// handler returns a LogHandler which never panics or terminates
+func (c *captureLogHandler) handler() LogHandler {
+	return &LogHandlerFunc{
+		RegularLogFunc: c.record,
+		PanicLogFunc: func(pnt func(io.StringWriter), info string) func() {
+			c.record(PANIC, pnt)
+			return nil
+		},
+		FatalLogFunc: func(pnt func(io.StringWriter)) func() {
+			c.record(FATAL, pnt)
+			return nil
+		},
+	}
+}
+
+func (c *captureLogHandler) last() string {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if len(c.lines) == 0 {
+		return ""
+	}
+	return c.lines[len(c.lines)-1]
+}
+
+func (c *captureLogHandler) count() int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return len(c.lines)
+}
+
+func (c *captureLogHandler) reset() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.lines = nil
+	c.levels = nil
+}
+
+// panicStringer is a buggy fmt.Stringer which panics
+type panicStringer struct{}
+
+func (panicStringer) String() string {
+	panic("broken stringer")
+}
+
 func TestLogger(t *testing.T) {
 	runtime.GOMAXPROCS(4)
 	tlh := &testLogHandler{}
@@ -521,5 +587,1173 @@ func TestLogger(t *testing.T) {
 				So(h.IsShutdown(), ShouldBeTrue)
 			})
 		})
+
+		Convey("Trace SetName test", func() {
+			rec := &captureLogHandler{}
+			l := New("App", LogConfig{Handler: rec.handler(), Level: DEBUG})
+			tlog := l.Trace("")
+			tid := tlog.TraceID()
+			tlog.Inf("before routing")
+			So(rec.last(), ShouldContainSubstring, "App<"+tid+">")
+			tlog.SetName("GetUser")
+			So(tlog.TraceName(), ShouldEqual, "GetUser")
+			So(tlog.TraceID(), ShouldEqual, tid)
+			tlog.Inf("after routing")
+			So(rec.last(), ShouldContainSubstring, "App<GetUser:"+tid+">")
+
+			// concurrent rename while logging
+			wg := sync.WaitGroup{}
+			for i := 0; i < 4; i++ {
+				wg.Add(2)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 100; j++ {
+						tlog.Dbg("concurrent")
+					}
+				}()
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 100; j++ {
+						tlog.SetName("Renamed")
+					}
+				}()
+			}
+			wg.Wait()
+			So(tlog.TraceName(), ShouldEqual, "Renamed")
+			So(tlog.TraceID(), ShouldEqual, tid)
+		})
+
+		Convey("WithStack test", func() {
+			rec := &captureLogHandler{}
+			l := New("App", LogConfig{
+				Handler:        rec.handler(),
+				LevelWithTrace: PANIC,
+			})
+			l.Err("plain error")
+			So(rec.last(), ShouldNotContainSubstring, ">> Stacks:")
+			sl := l.WithStack()
+			sl.Err("error with stack")
+			So(rec.last(), ShouldContainSubstring, "[ERROR], App >> Stacks:\n")
+			So(rec.last(), ShouldContainSubstring, "logger_test.go")
+			So(rec.last(), ShouldEndWith, "<<<< - error with stack\n")
+			sl.Inf("info with stack")
+			So(rec.last(), ShouldContainSubstring, "[INFO], App >> Stacks:\n")
+			// the original logger is not affected
+			l.Err("plain again")
+			So(rec.last(), ShouldNotContainSubstring, ">> Stacks:")
+			// decomposed as structured frames
+			var recs []Record
+			sl.SetLogHandler(RecordLogHandlerFunc(func(r Record) {
+				recs = append(recs, r)
+			}))
+			sl.Err("structured")
+			So(len(recs), ShouldEqual, 1)
+			So(len(recs[0].Stack), ShouldBeGreaterThan, 0)
+			So(recs[0].Stack[0], ShouldContainSubstring, "logger_test.go")
+			So(recs[0].Message, ShouldEqual, "structured")
+		})
+
+		Convey("Test mode output is byte-stable", func() {
+			run := func() []string {
+				rec := &captureLogHandler{}
+				l := New("App", LogConfig{
+					Handler:  rec.handler(),
+					TestMode: true,
+				})
+				l.Inf("started")
+				l.Derive("DB").War("slow query")
+				tl := l.Trace("REQ")
+				tl.Err("failed")
+				l.Derive("API").Trace("").Inf("second trace")
+				l.WithStack().Err("with stack")
+				l.Panic("panic")
+				return rec.lines
+			}
+			first := run()
+			time.Sleep(10 * time.Millisecond)
+			second := run()
+			So(second, ShouldResemble, first)
+			So(first[0], ShouldEqual,
+				"2000-01-01 00:00:00.000 [INFO], App - started\n")
+			So(first[2], ShouldEqual, "2000-01-01 00:00:00.000 [ERROR], "+
+				"App<REQ:00000000-0000-0000-0000-000000000001> - failed\n")
+			So(first[3], ShouldContainSubstring,
+				"App.API<00000000-0000-0000-0000-000000000002>")
+			for _, line := range first {
+				So(line, ShouldNotContainSubstring, ".go")
+			}
+		})
+
+		Convey("Handler enable/disable test", func() {
+			file := &captureLogHandler{}
+			console := &captureLogHandler{}
+			consoleHnd := &LogHandlerFunc{
+				RegularLogFunc: console.record,
+				Wrapper:        file.handler(),
+			}
+			l := New("App", LogConfig{Handler: consoleHnd})
+			var sw Switchable = consoleHnd
+			So(sw.Enabled(), ShouldBeTrue)
+			l.Inf("both")
+			So(console.count(), ShouldEqual, 1)
+			So(file.count(), ShouldEqual, 1)
+			sw.SetEnabled(false)
+			So(sw.Enabled(), ShouldBeFalse)
+			l.Inf("file only")
+			l.RawWriter().WriteString("raw file only\n")
+			So(console.count(), ShouldEqual, 1)
+			So(file.count(), ShouldEqual, 3)
+			So(file.last(), ShouldEqual, "raw file only\n")
+			sw.SetEnabled(true)
+			l.Inf("both again")
+			So(console.count(), ShouldEqual, 2)
+			So(file.count(), ShouldEqual, 4)
+		})
+
+		Convey("File handler enable/disable test", func() {
+			dir := t.TempDir()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			fh, err := NewFileAccessorLogHandler(ctx,
+				filepath.Join(dir, "app.log"))
+			So(err, ShouldBeNil)
+			defer fh.(io.Closer).Close()
+			rh, err := NewRotatingFileLogHandler(ctx,
+				filepath.Join(dir, "rotate.log"), 1<<20, 1)
+			So(err, ShouldBeNil)
+			defer rh.(io.Closer).Close()
+			gzh, err := NewGzipFileLogHandler(ctx,
+				filepath.Join(dir, "app.log.gz"))
+			So(err, ShouldBeNil)
+			defer gzh.(io.Closer).Close()
+			So(gzh, ShouldImplement, (*Switchable)(nil))
+
+			l := New("App", LogConfig{
+				Handler:  NewMultiHandler(fh, rh),
+				TestMode: true,
+			})
+			sw := fh.(Switchable)
+			So(sw.Enabled(), ShouldBeTrue)
+			l.Inf("both")
+			sw.SetEnabled(false)
+			So(sw.Enabled(), ShouldBeFalse)
+			l.Inf("rotate only")
+			l.GetWriter(WARN, false).WriteString("rotate only by writer")
+			sw.SetEnabled(true)
+			l.Inf("both again")
+			So(l.Flush(), ShouldBeNil)
+
+			data, err := os.ReadFile(filepath.Join(dir, "app.log"))
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual,
+				"2000-01-01 00:00:00.000 [INFO], App - both\n"+
+					"2000-01-01 00:00:00.000 [INFO], App - both again\n")
+			data, err = os.ReadFile(filepath.Join(dir, "rotate.log"))
+			So(err, ShouldBeNil)
+			So(strings.Count(string(data), "\n"), ShouldEqual, 4)
+
+			// a disabled wrapper still writes the panic messages
+			sw.SetEnabled(false)
+			sb := &strings.Builder{}
+			inner := &LogHandlerFunc{
+				Wrapper: fh,
+				RegularLogFunc: func(level LogLevel, pnt func(io.StringWriter)) {
+					pnt(sb)
+				},
+			}
+			inner.SetEnabled(false)
+			pl := New("App", LogConfig{
+				Handler: NewNativeLogHandlerWithConfig(context.Background(),
+					NativeConfig{Stdout: io.Discard, Stderr: io.Discard}, inner),
+				TestMode: true,
+			})
+			pl.Inf("skipped")
+			So(func() { pl.Panic("boom") }, ShouldPanic)
+			So(l.Flush(), ShouldBeNil)
+			So(sb.String(), ShouldEqual,
+				"2000-01-01 00:00:00.000 [PANIC], App - boom\n")
+			data, err = os.ReadFile(filepath.Join(dir, "app.log"))
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEndWith,
+				" - both again\n2000-01-01 00:00:00.000 [PANIC], App - boom\n")
+		})
+
+		Convey("Native handler continuation indent test", func() {
+			stdout := &strings.Builder{}
+			file := &captureLogHandler{}
+			l := New("App", LogConfig{
+				Handler: NewNativeLogHandlerWithConfig(
+					context.Background(),
+					NativeConfig{Stdout: stdout, IndentContinuation: true},
+					file.handler(),
+				),
+				LevelWithTrace: ERROR,
+				TimeFormat:     "15:04:05",
+			})
+			l.Inf("table:\nrow 1\nrow 2")
+			indent := strings.Repeat(" ", len("00:00:00 [INFO], App - "))
+			So(stdout.String()[8:], ShouldEqual, " [INFO], App - table:\n"+
+				indent+"row 1\n"+indent+"row 2\n")
+			// wrapped sink receives the original message
+			So(file.last(), ShouldEndWith, " - table:\nrow 1\nrow 2\n")
+			// single line messages and raw writes are unchanged
+			stdout.Reset()
+			l.Inf("single")
+			l.RawWriter().WriteString("raw\nlines\n")
+			So(stdout.String()[8:], ShouldEqual,
+				" [INFO], App - single\nraw\nlines\n")
+		})
+
+		Convey("Named and Name test", func() {
+			rec := &captureLogHandler{}
+			l := New("App", LogConfig{
+				Handler:        rec.handler(),
+				LevelWithTrace: ERROR,
+			})
+			So(l.Name(), ShouldEqual, "App")
+			nl := l.Named("Database")
+			So(nl.Name(), ShouldEqual, "App.Database")
+			So(nl.Named("x").Name(), ShouldEqual, "App.Database.x")
+			So(l.Named("").Name(), ShouldEqual, "App")
+			So(New("", LogConfig{}).Name(), ShouldEqual, "*")
+			// derived loggers keep the call trace level
+			nl.War("no call trace")
+			So(rec.last(), ShouldContainSubstring,
+				"[WARN], App.Database - no call trace")
+			nl.Err("with call trace")
+			So(rec.last(), ShouldContainSubstring, "logger_test.go")
+			l.SetTimeFormat("15:04")
+			l.War("still no call trace")
+			So(rec.last(), ShouldEndWith, "[WARN], App - still no call trace\n")
+		})
+
+		Convey("Per-call skip test", func() {
+			rec := &captureLogHandler{}
+			l := New("Test", LogConfig{Handler: rec.handler()})
+			helper := func(skip int, msg string) {
+				l.InfSkip(skip, msg)
+			}
+			_, _, line, _ := runtime.Caller(0)
+			helper(1, "skip helper")
+			So(rec.last(), ShouldContainSubstring,
+				fmt.Sprintf("logger_test.go:%d(", line+1))
+			helper(0, "no skip")
+			So(rec.last(), ShouldContainSubstring,
+				fmt.Sprintf("logger_test.go:%d(", line-2))
+			helper(-1, "negative skip")
+			So(rec.last(), ShouldContainSubstring,
+				fmt.Sprintf("logger_test.go:%d(", line-2))
+			l.SetLevel(WARN)
+			l.InfSkip(0, "filtered")
+			l.ErrSkip(0, "error skip")
+			So(rec.last(), ShouldContainSubstring, "[ERROR]")
+			So(rec.last(), ShouldContainSubstring, "error skip")
+		})
+
+		Convey("ErrReturn test", func() {
+			rec := &captureLogHandler{}
+			l := New("Test", LogConfig{
+				Handler:        rec.handler(),
+				LevelWithTrace: FATAL,
+			})
+			load := func() error {
+				return l.ErrReturn(os.ErrNotExist, "loading config:")
+			}
+			err := load()
+			So(err, ShouldEqual, os.ErrNotExist)
+			So(rec.count(), ShouldEqual, 1)
+			So(rec.last(), ShouldEndWith,
+				"[ERROR], Test - loading config: file does not exist\n")
+			So(l.ErrReturn(nil, "no error"), ShouldBeNil)
+			So(rec.count(), ShouldEqual, 1)
+			l.SetLevel(FATAL)
+			So(l.ErrReturn(os.ErrClosed), ShouldEqual, os.ErrClosed)
+			So(rec.count(), ShouldEqual, 1)
+		})
+
+		Convey("Trace baggage test", func() {
+			var recs []Record
+			l := New("App", LogConfig{
+				Handler: RecordLogHandlerFunc(func(rec Record) {
+					recs = append(recs, rec)
+				}),
+				LevelWithTrace: FATAL,
+			})
+			tl := l.Trace("REQ")
+			tl.Inf("no baggage")
+			tl.SetBaggage("tenant", "acme")
+			tl.SetBaggage("flag", "new ui")
+			tl.Inf("with baggage")
+			tl.SetBaggage("tenant", "globex")
+			child := tl.Child("SUB")
+			tl.SetBaggage("parent_only", "1")
+			child.War("in child")
+
+			So(len(recs), ShouldEqual, 3)
+			So(recs[0].Fields, ShouldBeEmpty)
+			So(recs[1].Fields, ShouldResemble, []Field{
+				{Key: "tenant", Value: "acme"},
+				{Key: "flag", Value: "new ui"},
+			})
+			So(recs[2].TraceName, ShouldEqual, "SUB")
+			So(recs[2].TraceID, ShouldEqual, child.TraceID())
+			So(recs[2].TraceID, ShouldNotEqual, tl.TraceID())
+			So(recs[2].Fields, ShouldResemble, []Field{
+				{Key: "tenant", Value: "globex"},
+				{Key: "flag", Value: "new ui"},
+			})
+		})
+
+		Convey("Panicking Stringer argument test", func() {
+			rec := &captureLogHandler{}
+			l := New("Test", LogConfig{
+				Handler:        rec.handler(),
+				LevelWithTrace: FATAL,
+			})
+			So(func() {
+				l.Inf("before", panicStringer{}, "after")
+			}, ShouldNotPanic)
+			So(rec.last(), ShouldEndWith,
+				"before %!v(PANIC=String method: broken stringer) after\n")
+			So(func() {
+				l.Errf("value: %s, next", panicStringer{})
+			}, ShouldNotPanic)
+			So(rec.last(), ShouldEndWith,
+				"value: %!s(PANIC=String method: broken stringer), next\n")
+		})
+
+		Convey("Caller cache test", func() {
+			rec := &captureLogHandler{}
+			plain := New("Test", LogConfig{Handler: rec.handler()})
+			cached := New("Test", LogConfig{
+				Handler:     rec.handler(),
+				CallerCache: true,
+			})
+			helper := func(l Logger, skip int) {
+				l.InfSkip(skip, "from helper")
+			}
+			header := func() string {
+				line := rec.last()
+				return line[strings.Index(line, " ["):]
+			}
+			for range 3 {
+				var outs [2][]string
+				for i, l := range []Logger{plain, cached} {
+					l.Inf("call trace")
+					outs[i] = append(outs[i], header())
+					l.Derive("sub").Err("call trace")
+					outs[i] = append(outs[i], header())
+					helper(l, 0)
+					outs[i] = append(outs[i], header())
+					helper(l, 1)
+					outs[i] = append(outs[i], header())
+				}
+				So(outs[1], ShouldResemble, outs[0])
+				So(outs[1][0], ShouldContainSubstring, "logger_test.go:")
+				So(outs[1][2], ShouldNotEqual, outs[1][3])
+			}
+		})
+
+		Convey("Native handler stderr level test", func() {
+			stdout := &strings.Builder{}
+			stderr := &strings.Builder{}
+			newLogger := func(cfg NativeConfig) Logger {
+				cfg.Stdout, cfg.Stderr = stdout, stderr
+				return New("App", LogConfig{
+					Handler: NewNativeLogHandlerWithConfig(
+						context.Background(), cfg, nil),
+					LevelWithTrace: FATAL,
+				})
+			}
+			l := newLogger(NativeConfig{StderrLevel: WARN})
+			l.Inf("info")
+			l.War("warn")
+			l.Err("error")
+			So(stdout.String(), ShouldContainSubstring, "[INFO], App - info\n")
+			So(stdout.String(), ShouldNotContainSubstring, "warn")
+			So(stderr.String(), ShouldContainSubstring, "[WARN], App - warn\n")
+			So(stderr.String(), ShouldContainSubstring,
+				"[ERROR], App - error\n")
+			So(func() { l.Panic("panic") }, ShouldPanic)
+			So(stderr.String(), ShouldContainSubstring, "- panic\n")
+
+			// default only sends panic and fatal to stderr
+			stdout.Reset()
+			stderr.Reset()
+			l = newLogger(NativeConfig{})
+			l.Err("error")
+			So(stdout.String(), ShouldContainSubstring, "- error\n")
+			So(stderr.String(), ShouldBeEmpty)
+
+			// every level goes to stderr from TRACE
+			stdout.Reset()
+			l = newLogger(NativeConfig{StderrLevel: TRACE})
+			l.SetLevel(TRACE)
+			l.Trc("trace")
+			l.Inf("info")
+			So(stdout.String(), ShouldBeEmpty)
+			So(stderr.String(), ShouldContainSubstring, "[TRACE], App - trace\n")
+			So(stderr.String(), ShouldContainSubstring, "[INFO], App - info\n")
+		})
+
+		Convey("Caller hyperlink test", func() {
+			rec := &captureLogHandler{}
+			_, file, _, _ := runtime.Caller(0)
+			newLogger := func(scheme HyperlinkScheme) Logger {
+				return New("App", LogConfig{
+					Handler:         rec.handler(),
+					CallerHyperlink: scheme,
+				})
+			}
+			l := newLogger(HyperlinkFile)
+			l.Inf("plain below warn")
+			So(rec.last(), ShouldContainSubstring, " logger_test.go:")
+			_, _, line, _ := runtime.Caller(0)
+			l.War("file link")
+			So(rec.last(), ShouldContainSubstring,
+				fmt.Sprintf(" file://%s:%d(nekomimi.TestLogger.", file, line+1))
+			l.Derive("sub").Err("derived")
+			So(rec.last(), ShouldContainSubstring, " file://"+file+":")
+
+			l = newLogger(HyperlinkVSCode)
+			l.Err("vscode link")
+			So(rec.last(), ShouldContainSubstring, " vscode://file"+file+":")
+
+			l = newLogger(HyperlinkNone)
+			l.Err("no link")
+			So(rec.last(), ShouldContainSubstring, " logger_test.go:")
+		})
+
+		Convey("Fallback handler test", func() {
+			fallback := &captureLogHandler{}
+			primary := &LogHandlerFunc{
+				RegularLogFunc: func(level LogLevel, pnt func(io.StringWriter)) {
+					panic("sink exploded")
+				},
+				Fallback: fallback.handler(),
+			}
+			l := New("App", LogConfig{
+				Handler:        primary,
+				LevelWithTrace: FATAL,
+			})
+			So(func() { l.Inf("survives") }, ShouldNotPanic)
+			So(fallback.count(), ShouldEqual, 1)
+			So(fallback.last(), ShouldEndWith, "[INFO], App - survives\n")
+
+			fw := &failingWriter{}
+			fw.fail.Store(true)
+			primary.RegularLogFunc = nil
+			primary.Wrapper = TinyLogHandlerFunc(
+				func(level LogLevel, pnt func(io.StringWriter)) {
+					pnt(fw)
+				})
+			l.War("write error")
+			So(fallback.count(), ShouldEqual, 2)
+			So(fallback.last(), ShouldEndWith, "[WARN], App - write error\n")
+
+			fw.fail.Store(false)
+			l.Inf("healthy")
+			So(fallback.count(), ShouldEqual, 2)
+			So(fw.sb.String(), ShouldEndWith, "[INFO], App - healthy\n")
+		})
+
+		Convey("Trace breadcrumb test", func() {
+			var recs []Record
+			l := New("App", LogConfig{
+				Handler: RecordLogHandlerFunc(func(rec Record) {
+					recs = append(recs, rec)
+				}),
+				LevelWithTrace: FATAL,
+			})
+			tl := l.Trace("REQ")
+			tl.SetBaggage("tenant", "acme")
+			tl.Err("no breadcrumb")
+			tl.Breadcrumb("auth")
+			tl.Breadcrumb("load cart")
+			tl.Inf("info")
+			tl.Err("failed")
+
+			So(len(recs), ShouldEqual, 3)
+			So(recs[0].Fields, ShouldResemble, []Field{
+				{Key: "tenant", Value: "acme"},
+			})
+			So(recs[1].Fields, ShouldResemble, []Field{
+				{Key: "tenant", Value: "acme"},
+			})
+			So(recs[2].Fields, ShouldResemble, []Field{
+				{Key: "tenant", Value: "acme"},
+				{Key: "breadcrumbs", Value: "auth > load cart"},
+			})
+
+			recs = nil
+			for i := range MaxBreadcrumbs + 2 {
+				tl.Breadcrumb(fmt.Sprint("step", i))
+			}
+			tl.Err("ring")
+			crumbs := strings.Split(recs[0].Fields[1].Value, " > ")
+			So(len(crumbs), ShouldEqual, MaxBreadcrumbs)
+			So(crumbs[0], ShouldEqual, "step2")
+			So(crumbs[MaxBreadcrumbs-1], ShouldEqual,
+				fmt.Sprint("step", MaxBreadcrumbs+1))
+		})
+
+		Convey("Level policy test", func() {
+			stdout, stderr := &strings.Builder{}, &strings.Builder{}
+			flushed := 0
+			handler := NewNativeLogHandlerWithConfig(context.Background(),
+				NativeConfig{Stdout: stdout, Stderr: stderr}, nil)
+			handler.(*LogHandlerFunc).FlushFunc = func() error {
+				flushed++
+				return nil
+			}
+			exitCode := -1
+			backupExit := sysExit
+			sysExit = func(code int) { exitCode = code }
+			defer func() { sysExit = backupExit }()
+
+			l := New("App", LogConfig{
+				Handler:  handler,
+				TestMode: true, // no stacks
+				LevelPolicy: map[LogLevel]LevelBehavior{
+					PANIC: {},
+					FATAL: {Exit: true, ExitCode: 3},
+				},
+			})
+			So(func() { l.Panic("not panicking") }, ShouldNotPanic)
+			So(stderr.String(), ShouldEndWith,
+				"[PANIC], App - not panicking\n")
+			So(flushed, ShouldEqual, 0)
+			So(exitCode, ShouldEqual, -1)
+
+			l.Derive("sub").Fatalf("custom code %d", 3)
+			So(stderr.String(), ShouldEndWith,
+				"[FATAL], App.sub - custom code 3\n")
+			So(flushed, ShouldEqual, 1)
+			So(exitCode, ShouldEqual, 3)
+			So(stdout.String(), ShouldBeEmpty)
+
+			// panic by policy, levels out of the policy use the handler
+			pl := New("App", LogConfig{
+				Handler: handler,
+				LevelPolicy: map[LogLevel]LevelBehavior{
+					FATAL: {Panic: true},
+				},
+			})
+			So(func() { pl.Fatal("panics instead") }, ShouldPanicWith,
+				"panics instead\n")
+			So(flushed, ShouldEqual, 2)
+			So(func() { pl.Panic("handler panic") }, ShouldPanicWith,
+				"handler panic\n")
+			So(exitCode, ShouldEqual, 3)
+		})
+
+		Convey("Trace level test", func() {
+			capture := &captureLogHandler{}
+			l := New("App", LogConfig{
+				Handler:        capture.handler(),
+				Level:          DEBUG,
+				LevelWithTrace: FATAL,
+			})
+			So(TRACE < DEBUG, ShouldBeTrue)
+			So(TRACE.String(), ShouldEqual, "TRACE")
+			lv, ok := levelFromName("TRACE")
+			So(ok, ShouldBeTrue)
+			So(lv, ShouldEqual, TRACE)
+
+			tl := l.Trace("PKT")
+			l.Trc("dropped")
+			l.Trcf("dropped %d", 1)
+			tl.Trc("dropped")
+			So(l.TrcP(), ShouldBeNil)
+			So(tl.TrcP(), ShouldBeNil)
+			So(capture.count(), ShouldEqual, 0)
+			l.Dbg("debug")
+			So(capture.count(), ShouldEqual, 1)
+
+			l.SetLevel(TRACE)
+			l.Trcf("packet %d", 7)
+			So(capture.last(), ShouldEndWith, "[TRACE], App - packet 7\n")
+			tl.Trc("packet")
+			So(capture.last(), ShouldContainSubstring, "[TRACE], App<PKT:")
+			l.TrcP()("deferred")
+			So(capture.last(), ShouldEndWith, "[TRACE], App - deferred\n")
+			l.Dbg("debug")
+			So(capture.count(), ShouldEqual, 5)
+			l.TrcCtx(context.Background(), "by context")
+			So(capture.last(), ShouldEndWith, "[TRACE], App - by context\n")
+			tl.TrcCtx(context.Background(), "by context")
+			So(capture.last(), ShouldContainSubstring, "[TRACE], App<PKT:")
+			l.TrcSkip(0, "by skip")
+			So(capture.last(), ShouldEndWith, "[TRACE], App - by skip\n")
+
+			// the other levels keep their values, the zero config is DEBUG
+			So(DEBUG, ShouldEqual, 0)
+			So(FATAL, ShouldEqual, 5)
+			So(TRACE, ShouldBeLessThan, 0)
+			So(New("App", LogConfig{}).(LevelReporter).Level(), ShouldEqual, DEBUG)
+			tracel := New("App", LogConfig{
+				Handler: capture.handler(),
+				Level:   TRACE,
+			})
+			So(tracel.(LevelReporter).Level(), ShouldEqual, TRACE)
+			tracel.Trc("explicit")
+			So(capture.last(), ShouldEndWith, " - explicit\n")
+		})
+
+		Convey("Trace span test", func() {
+			capture := &captureLogHandler{}
+			clock := NewMockClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+			l := New("App", LogConfig{
+				Handler:  capture.handler(),
+				Clock:    clock,
+				TestMode: true, // no call trace and stacks
+			})
+			tl := l.Trace("REQ")
+			tl.Span("load", func() {
+				So(capture.last(), ShouldEndWith, "[DEBUG], App<REQ:"+
+					tl.TraceID()+"> - span start load\n")
+				clock.Advance(1500 * time.Millisecond)
+			})
+			So(capture.count(), ShouldEqual, 2)
+			So(capture.last(), ShouldEndWith, "[INFO], App<REQ:"+
+				tl.TraceID()+"> - span end load 1.5s\n")
+
+			So(func() {
+				tl.Span("save", func() { panic("disk full") })
+			}, ShouldPanicWith, "disk full")
+			So(capture.count(), ShouldEqual, 4)
+			So(capture.last(), ShouldEndWith, "[PANIC], App<REQ:"+
+				tl.TraceID()+"> - span panic save disk full\n")
+
+			// call trace points to the caller of Span
+			capture.reset()
+			ll := New("App", LogConfig{Handler: capture.handler()})
+			ll.Trace("REQ").Span("noop", func() {})
+			So(capture.last(), ShouldContainSubstring, "logger_test.go:")
+		})
+
+		Convey("Parse level test", func() {
+			for name, want := range map[string]LogLevel{
+				"trace": TRACE, "Debug": DEBUG, "INFO": INFO, " warn ": WARN,
+				"warning": WARN, "ERROR": ERROR, "err": ERROR,
+				"panic": PANIC, "Fatal": FATAL,
+			} {
+				lv, err := ParseLevel(name)
+				So(err, ShouldBeNil)
+				So(lv, ShouldEqual, want)
+			}
+			for _, lv := range []LogLevel{TRACE, DEBUG, INFO, WARN, ERROR, PANIC, FATAL} {
+				So(MustParseLevel(lv.String()), ShouldEqual, lv)
+			}
+			for _, name := range []string{"", "verbose", "TINY_DONE", "UNKNOWN"} {
+				_, err := ParseLevel(name)
+				So(err, ShouldNotBeNil)
+			}
+			So(func() { MustParseLevel("verbose") }, ShouldPanic)
+		})
+
+		Convey("Pad level test", func() {
+			capture := &captureLogHandler{}
+			l := New("App", LogConfig{
+				Handler:  capture.handler(),
+				TestMode: true,
+				PadLevel: true,
+			})
+			l.SetLevel(TRACE)
+			l.Trc("m")
+			l.Dbg("m")
+			l.Inf("m")
+			l.War("m")
+			l.Err("m")
+			l.Panic("m")
+			l.Fatal("m")
+			So(capture.count(), ShouldEqual, 7)
+			col := strings.Index(capture.lines[0], "App - m")
+			for _, line := range capture.lines {
+				So(strings.Index(line, "App - m"), ShouldEqual, col)
+			}
+			So(capture.lines[2], ShouldContainSubstring, " [INFO ], App - m")
+			So(capture.lines[3], ShouldContainSubstring, " [WARN ], App - m")
+
+			rec := NewRecord(INFO, capture.lines[3], "")
+			So(rec.Level, ShouldEqual, WARN)
+			So(rec.Prefix, ShouldEqual, "App")
+			So(rec.Message, ShouldEqual, "m")
+		})
+
+		Convey("With meta test", func() {
+			jsonOut := &strings.Builder{}
+			jl := New("App", LogConfig{
+				Handler:  NewJSONLogHandler(jsonOut),
+				TestMode: true,
+			})
+			ml := jl.WithMeta("schema", 1).WithMeta("schema", 2).
+				WithMeta("source", "billing")
+			ml.Inf("structured")
+			rec := map[string]any{}
+			So(json.Unmarshal([]byte(jsonOut.String()), &rec), ShouldBeNil)
+			So(rec["meta"], ShouldResemble,
+				map[string]any{"schema": "2", "source": "billing"})
+			So(rec["msg"], ShouldEqual, "structured")
+			So(rec["fields"], ShouldBeNil)
+			So(ml.Name(), ShouldEqual, "App")
+
+			// derived and trace loggers keep the metadata
+			jsonOut.Reset()
+			ml.Derive("DB").Trace("REQ").War("nested")
+			So(jsonOut.String(), ShouldContainSubstring, `"meta":{"schema":"2"`)
+			jsonOut.Reset()
+			jl.Inf("no meta")
+			So(jsonOut.String(), ShouldNotContainSubstring, `"meta"`)
+
+			console := &strings.Builder{}
+			cl := New("App", LogConfig{
+				Handler: NewNativeLogHandlerWithConfig(context.Background(),
+					NativeConfig{Stdout: console}, nil),
+				TestMode: true,
+			}).WithMeta("schema", 2)
+			cl.Inf("human")
+			So(console.String(), ShouldEqual,
+				"2000-01-01 00:00:00.000 [INFO], App - human\n")
+		})
+
+		Convey("Require fields test", func() {
+			rec := &captureLogHandler{}
+			l := New("App", LogConfig{
+				Handler:      rec.handler(),
+				TestMode:     true,
+				StrictFields: true,
+			})
+			rl := l.RequireFields("tenant_id", "region")
+			rl.Inf("no context")
+			So(rec.levels, ShouldResemble, []LogLevel{INFO, WARN})
+			So(rec.last(), ShouldEqual, "2000-01-01 00:00:00.000 [WARN], App - "+
+				"INFO message missing required fields tenant_id,region: no context\n")
+
+			tl := rl.Derive("Req").Trace("REQ")
+			tl.SetBaggage("tenant_id", "acme")
+			tl.SetBaggage("region", "eu")
+			tl.Err("with context")
+			So(rec.count(), ShouldEqual, 3)
+			So(rec.last(), ShouldEndWith, "{tenant_id=acme region=eu} - with context\n")
+
+			tl2 := rl.Trace("OTHER")
+			tl2.SetBaggage("tenant_id", "acme")
+			tl2.War("only tenant")
+			So(rec.last(), ShouldEndWith,
+				"WARN message missing required fields region: only tenant\n")
+
+			l.Inf("not required")
+			So(rec.last(), ShouldEndWith, "[INFO], App - not required\n")
+
+			// the fields are checked before rendering, so the header text
+			// doesn't matter
+			odd := New("My App", LogConfig{
+				Handler:              rec.handler(),
+				TimeFormat:           "2006 [01] 02",
+				StrictFields:         true,
+				IncludeProcessFields: true,
+			}).RequireFields("tenant_id", "host")
+			otl := odd.Trace("a>b")
+			otl.SetBaggage("tenant_id", "acme")
+			rec.reset()
+			otl.Inf("odd header")
+			So(rec.count(), ShouldEqual, 1)
+			odd.Inf("no tenant")
+			So(rec.count(), ShouldEqual, 3)
+			So(rec.last(), ShouldEndWith,
+				"INFO message missing required fields tenant_id: no tenant\n")
+
+			// production mode is a no-op
+			pl := New("App", LogConfig{Handler: rec.handler(), TestMode: true})
+			So(pl.RequireFields("tenant_id"), ShouldEqual, pl)
+			rec.reset()
+			pl.RequireFields("tenant_id").Inf("unchecked")
+			So(rec.count(), ShouldEqual, 1)
+		})
+
+		Convey("Short trace id test", func() {
+			stdout := &strings.Builder{}
+			var recs []Record
+			l := New("App", LogConfig{
+				Handler: NewNativeLogHandlerWithConfig(
+					context.Background(),
+					NativeConfig{Stdout: stdout, TraceRender: TraceRenderShort},
+					RecordLogHandlerFunc(func(rec Record) {
+						recs = append(recs, rec)
+					}),
+				),
+				TestMode: true,
+			})
+			tl := l.Trace("REQ")
+			tl.Inf("short")
+			So(stdout.String(), ShouldEqual,
+				"2000-01-01 00:00:00.000 [INFO], App<REQ:00000000> - short\n")
+			So(tl.TraceID(), ShouldEqual, "00000000-0000-0000-0000-000000000001")
+			So(recs[0].TraceID, ShouldEqual, tl.TraceID())
+
+			stdout.Reset()
+			l.Inf("no trace <REQ:0190a1b2-c3d4>")
+			So(stdout.String(), ShouldEndWith,
+				"[INFO], App - no trace <REQ:0190a1b2-c3d4>\n")
+
+			// the first 8 hex digits of a random id are kept
+			stdout.Reset()
+			rl := New("App", LogConfig{
+				Handler: NewNativeLogHandlerWithConfig(context.Background(),
+					NativeConfig{Stdout: stdout, TraceRender: TraceRenderShort}, nil),
+				LevelWithTrace: FATAL,
+			})
+			rtl := rl.Trace("")
+			rtl.Inf("random")
+			So(stdout.String(), ShouldContainSubstring,
+				"], App<"+rtl.TraceID()[:8]+"> - random\n")
+		})
+
+		Convey("Caller format test", func() {
+			rec := &captureLogHandler{}
+			_, file, _, _ := runtime.Caller(0)
+			short := filepath.Base(filepath.Dir(file)) + "/logger_test.go:"
+			newLogger := func(format CallerFormat, cache bool) Logger {
+				return New("App", LogConfig{
+					Handler:      rec.handler(),
+					CallerFormat: format,
+					CallerCache:  cache,
+				})
+			}
+			for _, cache := range []bool{false, true} {
+				newLogger(CallerBase, cache).Inf("base")
+				So(rec.last(), ShouldContainSubstring, "], App logger_test.go:")
+				newLogger(CallerShort, cache).Inf("short")
+				So(rec.last(), ShouldContainSubstring, "], App "+short)
+				newLogger(CallerFull, cache).Derive("sub").Inf("full")
+				So(rec.last(), ShouldContainSubstring, " "+file+":")
+			}
+
+			// stacks keep the full path by default
+			l := newLogger(CallerBase, false).WithStack()
+			l.Inf("stack")
+			So(rec.last(), ShouldContainSubstring, " "+file+":")
+			l = newLogger(CallerShort, false).WithStack()
+			l.Inf("stack")
+			So(rec.last(), ShouldContainSubstring, "\n     "+short)
+			So(rec.last(), ShouldNotContainSubstring, file)
+		})
+
+		Convey("Debug config level override test", func() {
+			rec := &captureLogHandler{}
+			newLogger := func(debug bool) Logger {
+				return New("App", LogConfig{
+					Handler:     rec.handler(),
+					Level:       INFO,
+					TestMode:    true,
+					DebugConfig: debug,
+				})
+			}
+			l := newLogger(true)
+			cache := l.Derive("Cache")
+			cache.SetLevel(INFO) // same as the parent
+			So(rec.count(), ShouldEqual, 0)
+			cache.SetLevel(WARN)
+			So(rec.lines, ShouldResemble, []string{
+				"2000-01-01 00:00:00.000 [DEBUG], App.Cache - " +
+					"derived App.Cache at WARN, parent at INFO\n",
+			})
+			cache.SetLevel(ERROR)
+			cache.WithStack().SetLevel(WARN)
+			So(rec.count(), ShouldEqual, 1)
+			l.SetLevel(WARN) // the root logger has no parent
+			So(rec.count(), ShouldEqual, 1)
+
+			rec.reset()
+			newLogger(false).Derive("Cache").SetLevel(WARN)
+			So(rec.count(), ShouldEqual, 0)
+		})
+
+		Convey("Logger flush test", func() {
+			dir := t.TempDir()
+			gzPath := filepath.Join(dir, "app.log.gz")
+			gzh, err := NewGzipFileLogHandler(context.Background(), gzPath)
+			So(err, ShouldBeNil)
+			fh, err := NewFileAccessorLogHandler(context.Background(),
+				filepath.Join(dir, "app.log"))
+			So(err, ShouldBeNil)
+			l := New("App", LogConfig{
+				Handler: NewNativeLogHandlerWithConfig(context.Background(),
+					NativeConfig{Stdout: io.Discard},
+					NewMultiHandler(fh, gzh)),
+				TestMode: true,
+			})
+			l.Inf("flushed")
+			// readable before the periodic flush
+			So(l.Flush(), ShouldBeNil)
+			fp, err := os.Open(gzPath)
+			So(err, ShouldBeNil)
+			defer fp.Close()
+			zr, err := gzip.NewReader(fp)
+			So(err, ShouldBeNil)
+			data, _ := io.ReadAll(zr)
+			So(string(data), ShouldEqual,
+				"2000-01-01 00:00:00.000 [INFO], App - flushed\n")
+
+			So(fh.(io.Closer).Close(), ShouldBeNil)
+			l.Inf("dropped")
+			data, err = os.ReadFile(filepath.Join(dir, "app.log"))
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual,
+				"2000-01-01 00:00:00.000 [INFO], App - flushed\n")
+			So(gzh.(io.Closer).Close(), ShouldBeNil)
+		})
+
+		Convey("Enter and exit test", func() {
+			rec := &captureLogHandler{}
+			clock := NewMockClock(testModeTime)
+			l := New("App", LogConfig{
+				Handler:  rec.handler(),
+				Clock:    clock,
+				TestMode: true,
+			})
+			process := func() {
+				defer l.Enter("ProcessOrder")()
+				clock.Advance(12 * time.Millisecond)
+			}
+			process()
+			So(rec.lines, ShouldResemble, []string{
+				"2000-01-01 00:00:00.000 [DEBUG], App - enter ProcessOrder\n",
+				"2000-01-01 00:00:00.012 [DEBUG], App - exit ProcessOrder (12ms)\n",
+			})
+
+			rec.reset()
+			failing := func() {
+				defer l.Enter("Charge")()
+				clock.Advance(time.Second)
+				panic("card declined")
+			}
+			So(failing, ShouldPanicWith, "card declined")
+			So(rec.levels, ShouldResemble, []LogLevel{DEBUG, PANIC})
+			So(rec.last(), ShouldEndWith,
+				"[PANIC], App - panic Charge (1s): card declined\n")
+
+			rec.reset()
+			l.SetLevel(INFO)
+			process()
+			So(rec.count(), ShouldEqual, 0)
+		})
+
+		Convey("Fatal flushes the wrapper before exit test", func() {
+			path := filepath.Join(t.TempDir(), "app.log.gz")
+			gzh, err := NewGzipFileLogHandler(context.Background(), path)
+			So(err, ShouldBeNil)
+			defer gzh.(io.Closer).Close()
+			l := New("App", LogConfig{
+				Handler: NewNativeLogHandlerWithConfig(context.Background(),
+					NativeConfig{Stdout: io.Discard, Stderr: io.Discard}, gzh),
+				TestMode: true,
+			})
+			// the content of the file when the program exits
+			onDisk := ""
+			backupTm := sysTerminate
+			defer func() { sysTerminate = backupTm }()
+			sysTerminate = func() {
+				fp, err := os.Open(path)
+				if err != nil {
+					return
+				}
+				defer fp.Close()
+				zr, err := gzip.NewReader(fp)
+				if err != nil {
+					return
+				}
+				data, _ := io.ReadAll(zr) // no trailer before the close
+				onDisk = string(data)
+			}
+			l.Inf("working")
+			l.Fatal("disk on fire")
+			So(onDisk, ShouldEqual,
+				"2000-01-01 00:00:00.000 [INFO], App - working\n"+
+					"2000-01-01 00:00:00.000 [FATAL], App - disk on fire\n")
+		})
+
+		Convey("File reopen on signal test", func() {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "app.log")
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			fh, err := NewFileAccessorLogHandler(ctx, path)
+			So(err, ShouldBeNil)
+			defer fh.(io.Closer).Close()
+			So(ReopenOnSignal(ctx, NativeLogHandler), ShouldNotBeNil)
+			So(ReopenOnSignal(ctx, fh, syscall.SIGUSR1), ShouldBeNil)
+			l := New("App", LogConfig{
+				Handler: NewNativeLogHandlerWithConfig(context.Background(),
+					NativeConfig{Stdout: io.Discard}, fh),
+				TestMode: true,
+			})
+			l.Inf("before")
+			// rotated by an external tool, the renamed file still receives
+			// the messages until the reopen
+			So(os.Rename(path, path+".1"), ShouldBeNil)
+			l.Inf("renamed")
+			p, err := os.FindProcess(os.Getpid())
+			So(err, ShouldBeNil)
+			So(p.Signal(syscall.SIGUSR1), ShouldBeNil)
+			So(waitFor(func() bool {
+				_, err := os.Stat(path)
+				return err == nil
+			}), ShouldBeTrue)
+			time.Sleep(20 * time.Millisecond) // the swap follows the open
+			l.Inf("after")
+
+			data, err := os.ReadFile(path + ".1")
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual,
+				"2000-01-01 00:00:00.000 [INFO], App - before\n"+
+					"2000-01-01 00:00:00.000 [INFO], App - renamed\n")
+			data, err = os.ReadFile(path)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual,
+				"2000-01-01 00:00:00.000 [INFO], App - after\n")
+
+			// a closed handler isn't reopened
+			So(fh.(io.Closer).Close(), ShouldBeNil)
+			So(fh.(Reopener).Reopen(), ShouldEqual, os.ErrClosed)
+		})
+
+		Convey("File write errors test", func() {
+			if _, err := os.Stat("/dev/full"); err != nil {
+				SkipSo("/dev/full is not available")
+				return
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			fh, err := NewFileAccessorLogHandler(ctx, "/dev/full")
+			So(err, ShouldBeNil)
+			defer fh.(io.Closer).Close()
+			var errs []error
+			fh.(WriteErrorReporter).SetOnError(func(err error) {
+				errs = append(errs, err)
+			})
+			l := New("App", LogConfig{
+				Handler: NewNativeLogHandlerWithConfig(context.Background(),
+					NativeConfig{Stdout: io.Discard}, fh),
+				TestMode: true,
+			})
+			l.Inf("disk full")
+			l.Inf("still full")
+			So(fh.(WriteErrorReporter).WriteErrors(), ShouldEqual, 2)
+			So(len(errs), ShouldEqual, 2)
+			So(errs[0], ShouldWrap, syscall.ENOSPC)
+
+			fh.(WriteErrorReporter).SetOnError(nil)
+			l.Inf("unhooked")
+			So(fh.(WriteErrorReporter).WriteErrors(), ShouldEqual, 3)
+			So(len(errs), ShouldEqual, 2)
+		})
+
+		Convey("Deferred debug with timeout test", func() {
+			rec := &captureLogHandler{}
+			l := New("App", LogConfig{Handler: rec.handler(), TestMode: true})
+			f := l.DbgPTimeout(time.Second)
+			So(f, ShouldNotBeNil)
+			f("state:", slowStringer(0))
+			So(rec.last(), ShouldEqual,
+				"2000-01-01 00:00:00.000 [DEBUG], App - state: slow(0s)\n")
+
+			start := time.Now()
+			l.DbgPTimeout(10*time.Millisecond)("state:",
+				slowStringer(time.Second))
+			So(time.Since(start), ShouldBeLessThan, 500*time.Millisecond)
+			So(rec.last(), ShouldEqual, "2000-01-01 00:00:00.000 [DEBUG], "+
+				"App - <message formatting exceeded 10ms>\n")
+
+			// the arguments without formatting methods are snapshotted, so
+			// changing them after the timeout doesn't race the formatting
+			m := map[string]int{"k": 1}
+			buf := []byte("ab")
+			l.DbgPTimeout(10*time.Millisecond)("state:", m, buf,
+				slowStringer(100*time.Millisecond))
+			So(rec.last(), ShouldEndWith,
+				"App - <message formatting exceeded 10ms>\n")
+			m["k"] = 2
+			buf[0] = 'x'
+			time.Sleep(150 * time.Millisecond) // the formatting completes
+			l.DbgPTimeout(time.Second)("state:", m, []any{1})
+			So(rec.last(), ShouldEndWith, "App - state: map[k:2] [1]\n")
+
+			// the formattings in flight are bounded
+			oldSlots := dbgPTimeoutSlots
+			dbgPTimeoutSlots = make(chan struct{}, 2)
+			defer func() { dbgPTimeoutSlots = oldSlots }()
+			block := make(blockingStringer)
+			for range 2 {
+				l.DbgPTimeout(time.Millisecond)(block)
+				So(rec.last(), ShouldEndWith,
+					"App - <message formatting exceeded 1ms>\n")
+			}
+			l.DbgPTimeout(time.Millisecond)(block)
+			So(rec.last(), ShouldEndWith, "App - <message formatting "+
+				"skipped, 2 formattings in flight>\n")
+			close(block)
+			So(waitFor(func() bool { return len(dbgPTimeoutSlots) == 0 }),
+				ShouldBeTrue)
+			l.DbgPTimeout(time.Second)(block)
+			So(rec.last(), ShouldEndWith, "App - unblocked\n")
+
+			l.SetLevel(INFO)
+			So(l.DbgPTimeout(time.Second), ShouldBeNil)
+		})
+
+		Convey("Writer level test", func() {
+			// the handler receives the level of the writer, not INFO
+			rec := &captureLogHandler{}
+			l := New("App", LogConfig{Handler: rec.handler(), TestMode: true})
+			l.SetLevel(TRACE)
+			for _, lv := range []LogLevel{TRACE, DEBUG, INFO, WARN, ERROR} {
+				l.GetWriter(lv, false).WriteString("by writer")
+			}
+			So(rec.levels, ShouldResemble,
+				[]LogLevel{TRACE, DEBUG, INFO, WARN, ERROR})
+			So(rec.last(), ShouldEqual,
+				"2000-01-01 00:00:00.000 [ERROR], App - by writer\n")
+		})
+
+		Convey("Writer call trace test", func() {
+			// the writer reports the line of WriteString whatever the depth
+			// of the handler chain
+			rec := &captureLogHandler{}
+			chains := []LogHandler{
+				rec.handler(),
+				&LogHandlerFunc{Wrapper: &LogHandlerFunc{
+					Wrapper:  rec.handler(),
+					Fallback: NativeLogHandler,
+				}},
+			}
+			for _, h := range chains {
+				l := New("App", LogConfig{Handler: h})
+				_, _, line, _ := runtime.Caller(0)
+				l.GetWriter(INFO, true).WriteString("traced")
+				So(rec.last(), ShouldContainSubstring,
+					fmt.Sprintf(" logger_test.go:%d(", line+1))
+				l.GetWriter(INFO, false).WriteString("untraced")
+				So(rec.last(), ShouldNotContainSubstring, "logger_test.go")
+				So(rec.last(), ShouldEndWith, "[INFO], App - untraced\n")
+			}
+		})
 	})
 }
+
+// slowStringer takes the duration to format, see DbgPTimeout
+type slowStringer time.Duration
+
+func (s slowStringer) String() string {
+	time.Sleep(time.Duration(s))
+	return fmt.Sprintf("slow(%v)", time.Duration(s))
+}
+
+// blockingStringer formats once the channel is closed, see DbgPTimeout
+type blockingStringer chan struct{}
+
+func (b blockingStringer) String() string {
+	<-b
+	return "unblocked"
+}
@@ -2,12 +2,17 @@ package nekomimi
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
+	"unicode/utf8"
 )
 
 // sysTerminateCode is the exit code used when FatalLog is called
@@ -18,6 +23,10 @@ var sysTerminate = func() {
 	os.Exit(sysTerminateCode)
 }
 
+// sysExit is the function called to terminate the program with a custom
+// exit code, see LevelBehavior
+var sysExit = os.Exit
+
 // LogHandler represents the interface for handling log messages
 // Panic or Fatal log is supported. It's allowed output log message and raise
 // panic or terminate the program after logging. which like the standard log.
@@ -63,15 +72,153 @@ type LogHandlerFunc struct {
 	// raise panic
 	PanicLogFunc func(pnt func(io.StringWriter), info string) (fin func())
 	// should return a finalizer function that will be called after logging to
-	// terminate the program
+	// terminate the program. the handler is flushed (see Flush) before the
+	// finalizer is called.
 	FatalLogFunc func(func(io.StringWriter)) (fin func())
 	// optional wrapper LogHandler to chain calls
 	Wrapper LogHandler
+	// Fallback is an optional LogHandler receiving a regular log message
+	// when writing it to the Wrapper or by RegularLogFunc panics or the
+	// StringWriter returns error. the panic is recovered in this case.
+	// unlike Wrapper, which is for fan-out, Fallback is for failover, e.g.
+	// to stderr.
+	Fallback LogHandler
 	// IsShutdownFunc is an optional function that reports whether the
 	// handler-specific resources have been released. If nil, this
 	// handler has no self-awareness for its own resources, and
 	// IsShutdown() returns false regardless of the Wrapper's state.
 	IsShutdownFunc func() bool
+	// optional function to flush buffered data of the handler, called by
+	// Flush after flushing the Wrapper
+	FlushFunc func() error
+	// optional function to release the handler resources, called once by
+	// Close before closing the Wrapper
+	CloseFunc func() error
+
+	// closed is set by the first Close call
+	closed atomic.Bool
+	// disabled is toggled by SetEnabled. a disabled handler skips
+	// RegularLogFunc but still forwards to the Wrapper.
+	disabled atomic.Bool
+}
+
+// Switchable is implemented by handlers which can be enabled or disabled
+// at runtime. a disabled handler skips its own regular log writes but
+// still forwards log messages to its wrapper, so a single sink of a chain
+// can be silenced without rebuilding the logger. panic and fatal messages
+// are never skipped.
+type Switchable interface {
+	// SetEnabled enables or disables the handler
+	SetEnabled(enabled bool)
+	// Enabled reports whether the handler is enabled
+	Enabled() bool
+}
+
+// Flusher is implemented by handlers which buffer log messages. Flush
+// writes the buffered messages to the underlying storage synchronously.
+type Flusher interface {
+	Flush() error
+}
+
+// Reopener is implemented by file handlers which can reopen their file, e.g.
+// after an external tool such as logrotate renamed it. Reopen opens the
+// path again and switches the following messages to the new file.
+type Reopener interface {
+	Reopen() error
+}
+
+// errNotReopener is returned by ReopenOnSignal for the handlers without
+// Reopener
+var errNotReopener = errors.New("nekomimi: log handler can't be reopened")
+
+// ReopenOnSignal reopens the file of h (see Reopener) on each of the signals,
+// SIGHUP if none, until ctx is done. it's the counterpart of the
+// `postrotate` script of logrotate:
+//
+//	fh, _ := nekomimi.NewFileAccessorLogHandler(ctx, "/var/log/app.log")
+//	nekomimi.ReopenOnSignal(ctx, fh)
+//
+// returns error if h doesn't implement Reopener. a failed reopen keeps the
+// current file.
+func ReopenOnSignal(ctx context.Context, h LogHandler, sig ...os.Signal) error {
+	r, ok := h.(Reopener)
+	if !ok {
+		return errNotReopener
+	}
+	if len(sig) == 0 {
+		sig = []os.Signal{syscall.SIGHUP}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sig...)
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ch:
+				r.Reopen()
+			}
+		}
+	}()
+	return nil
+}
+
+// WriteErrorReporter is implemented by the handlers writing to a file or a
+// connection. the failed writes (e.g. on a full disk) are counted and passed
+// to the hook, so callers can alert on the lost messages.
+type WriteErrorReporter interface {
+	// SetOnError sets the hook called with each write error, nil removes
+	// it. the hook is called with the handler locked, it must not log
+	// through the same handler.
+	SetOnError(fn func(error))
+	// WriteErrors returns the number of failed writes so far
+	WriteErrors() uint64
+}
+
+// writeErrors implements WriteErrorReporter for the handlers embedding it
+type writeErrors struct {
+	count atomic.Uint64
+	hook  atomic.Pointer[func(error)]
+}
+
+func (we *writeErrors) SetOnError(fn func(error)) {
+	if fn == nil {
+		we.hook.Store(nil)
+		return
+	}
+	we.hook.Store(&fn)
+}
+
+func (we *writeErrors) WriteErrors() uint64 {
+	return we.count.Load()
+}
+
+// report counts the write error and calls the hook, nil is ignored
+func (we *writeErrors) report(err error) {
+	if err == nil {
+		return
+	}
+	we.count.Add(1)
+	if fn := we.hook.Load(); fn != nil {
+		(*fn)(err)
+	}
+}
+
+// flushHandler flushes the handler if it implements Flusher
+func flushHandler(h LogHandler) error {
+	if f, ok := h.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// closeHandler closes the handler if it implements io.Closer
+func closeHandler(h LogHandler) error {
+	if c, ok := h.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
 }
 
 // TinyLogHandlerFunc is a minimal implementation of LogHandler using a single
@@ -84,27 +231,114 @@ type LogHandlerFunc struct {
 // (which sends TINY_DONE with a marker pnt) to detect termination.
 type TinyLogHandlerFunc func(level LogLevel, pnt func(io.StringWriter))
 
+// NativeConfig provides the options of the native log handler
+type NativeConfig struct {
+	// Stdout receives log messages below StderrLevel. default is os.Stdout
+	Stdout io.Writer
+	// Stderr receives log messages at or above StderrLevel. default is
+	// os.Stderr
+	Stderr io.Writer
+	// StderrLevel is the lowest level written to Stderr, e.g. WARN to let
+	// error monitors catch warnings, or TRACE for every level. the zero
+	// value means PANIC, only panic and fatal messages go to Stderr.
+	StderrLevel LogLevel
+	// IndentContinuation indents the continuation lines of a multi-line
+	// message, so they are aligned under the message start instead of
+	// starting at column zero. only the console output is affected, the
+	// wrapped handler receives the original message.
+	IndentContinuation bool
+	// LevelName renames the `[LEVEL]` tag of the console output, e.g.
+	// strings.ToLower of LogLevel.String. nil keeps the header unchanged.
+	// the wrapped handler receives the original header.
+	LevelName func(LogLevel) string
+	// TraceRender selects the form of the trace id in the console output.
+	// the wrapped handler receives the full id.
+	TraceRender TraceRender
+}
+
+// TraceRender selects the form of the trace id in the log header, see
+// NativeConfig.TraceRender
+type TraceRender int
+
+const (
+	// TraceRenderFull renders the full trace id, e.g. `<REQ:0190...c4d>`
+	TraceRenderFull TraceRender = iota
+	// TraceRenderShort renders the first 8 hex digits of the trace id, e.g.
+	// `<REQ:1a2b3c4d>`, enough to find the full id in the structured output
+	TraceRenderShort
+)
+
+// shortTraceIDLen is the length of the trace id rendered by
+// TraceRenderShort
+const shortTraceIDLen = 8
+
 // NewNativeLogHandlerWithContext creates a new LogHandler that uses
 // std I/O for logging. The ctx is used by IsShutdown() to report
 // handler termination status.
 func NewNativeLogHandlerWithContext(
 	ctx context.Context, wrap LogHandler,
 ) LogHandler {
+	return NewNativeLogHandlerWithConfig(ctx, NativeConfig{}, wrap)
+}
+
+// NewNativeLogHandlerWithConfig creates a new LogHandler that writes to
+// std I/O (or the writers given in cfg) with the given options. The ctx
+// is used by IsShutdown() to report handler termination status.
+func NewNativeLogHandlerWithConfig(
+	ctx context.Context, cfg NativeConfig, wrap LogHandler,
+) LogHandler {
+	var stdout, stderr io.StringWriter = os.Stdout, os.Stderr
+	if cfg.Stdout != nil {
+		stdout = asStringWriter(cfg.Stdout)
+	}
+	if cfg.Stderr != nil {
+		stderr = asStringWriter(cfg.Stderr)
+	}
+	output := func(
+		w io.StringWriter, level LogLevel, pnt func(io.StringWriter),
+	) {
+		if !cfg.IndentContinuation && cfg.LevelName == nil &&
+			cfg.TraceRender == TraceRenderFull {
+			pnt(w)
+			return
+		}
+		sb := strings.Builder{}
+		pnt(&sb)
+		line := sb.String()
+		if cfg.LevelName != nil {
+			line = renameLevel(line, cfg.LevelName(level))
+		}
+		if cfg.TraceRender == TraceRenderShort {
+			line = shortenTraceID(line)
+		}
+		if cfg.IndentContinuation {
+			line = indentContinuation(line)
+		}
+		w.WriteString(line)
+	}
+	stderrLevel := cfg.StderrLevel
+	if stderrLevel == 0 {
+		stderrLevel = PANIC
+	}
 	return &LogHandlerFunc{
 		Lock: &sync.Mutex{},
 		RegularLogFunc: func(level LogLevel, pnt func(io.StringWriter)) {
-			pnt(os.Stdout)
+			if level >= stderrLevel {
+				output(stderr, level, pnt)
+				return
+			}
+			output(stdout, level, pnt)
 		},
 		PanicLogFunc: func(
 			pnt func(io.StringWriter), info string,
 		) func() {
-			pnt(os.Stderr)
+			output(stderr, PANIC, pnt)
 			return func() {
 				panic(info)
 			}
 		},
 		FatalLogFunc: func(pnt func(io.StringWriter)) func() {
-			pnt(os.Stderr)
+			output(stderr, FATAL, pnt)
 			return sysTerminate
 		},
 		Wrapper: wrap,
@@ -119,6 +353,110 @@ func NewNativeLogHandlerWithContext(
 	}
 }
 
+// stringWriter adapts an io.Writer to io.StringWriter
+type stringWriter struct {
+	w io.Writer
+}
+
+func (sw stringWriter) WriteString(s string) (int, error) {
+	return sw.w.Write([]byte(s))
+}
+
+// asStringWriter returns w itself if it implements io.StringWriter,
+// otherwise wraps it with an adapter
+func asStringWriter(w io.Writer) io.StringWriter {
+	if sw, ok := w.(io.StringWriter); ok {
+		return sw
+	}
+	return stringWriter{w}
+}
+
+// findLevelTag returns the positions of the brackets of the `[LEVEL]` tag
+// in a formatted log line. ok is false if the header is not recognizable.
+func findLevelTag(line string) (lb, rb int, ok bool) {
+	lb = strings.Index(line, " [")
+	if lb < 0 {
+		return 0, 0, false
+	}
+	lb++
+	rb = strings.Index(line[lb:], "]")
+	if rb < 0 {
+		return 0, 0, false
+	}
+	rb += lb
+	name := strings.TrimRight(line[lb+1:rb], " ")
+	if _, ok := levelFromName(name); !ok {
+		return 0, 0, false
+	}
+	return lb, rb, true
+}
+
+// renameLevel replaces the level name in the `[LEVEL]` tag of a formatted
+// log line. the line is returned unchanged if the tag is not recognizable.
+func renameLevel(line string, name string) string {
+	lb, rb, ok := findLevelTag(line)
+	if !ok {
+		return line
+	}
+	return line[:lb+1] + name + line[rb:]
+}
+
+// shortenTraceID renders the trace id of a formatted log line in the form
+// of TraceRenderShort. the line is returned unchanged if it has no trace.
+func shortenTraceID(line string) string {
+	lb := strings.Index(line, "], ")
+	if lb < 0 {
+		return line
+	}
+	start := lb + 3
+	end := strings.IndexAny(line[start:], "< ")
+	if end < 0 || line[start+end] != '<' {
+		return line
+	}
+	start += end + 1
+	te := strings.Index(line[start:], ">")
+	if te < 0 {
+		return line
+	}
+	te += start
+	if idx := strings.LastIndex(line[start:te], ":"); idx != -1 {
+		start += idx + 1
+	}
+	id := strings.ReplaceAll(line[start:te], "-", "")
+	if len(id) <= shortTraceIDLen {
+		return line
+	}
+	return line[:start] + id[:shortTraceIDLen] + line[te:]
+}
+
+// indentContinuation indents the continuation lines of the message in a
+// formatted log line, aligning them under the message start which follows
+// the header separator " - ". the line is returned unchanged if the header
+// is not recognizable.
+func indentContinuation(line string) string {
+	lb := strings.Index(line, "], ")
+	if lb < 0 {
+		return line
+	}
+	sep := strings.Index(line[lb:], " - ")
+	if sep < 0 {
+		return line
+	}
+	start := lb + sep + 3
+	body := line[start:]
+	trail := ""
+	if strings.HasSuffix(body, "\n") {
+		body, trail = body[:len(body)-1], "\n"
+	}
+	if !strings.Contains(body, "\n") {
+		return line
+	}
+	lineStart := strings.LastIndex(line[:start], "\n") + 1
+	indent := strings.Repeat(" ", utf8.RuneCountInString(line[lineStart:start]))
+	return line[:start] +
+		strings.ReplaceAll(body, "\n", "\n"+indent) + trail
+}
+
 // NewNativeLogHandler creates a new LogHandler that uses std I/O for
 // logging. It delegates to NewNativeLogHandlerWithContext with a
 // background context — IsShutdown() will never return true.
@@ -134,12 +472,15 @@ var NativeLogHandler LogHandler = NewNativeLogHandler(nil)
 // other LogHandlers.
 // This handler is not thread-safe by itself. Should ensure parent handler
 // have thread-safety if needed.
-// ctx is the context for file lifecycle management.
+// ctx is the context for file lifecycle management. the handler implements
+// Flusher and io.Closer to flush or close the file synchronously, e.g.
+// before os.Exit in short-lived tools, without waiting for the 2 seconds
+// periodic flush. the write errors are reported, see WriteErrorReporter.
 func NewFileAccessorLogHandler(
 	ctx context.Context, path string,
 ) (LogHandler, error) {
 	countwrt := atomic.Uint64{}
-	var lastflush uint64 = 0
+	lastflush := atomic.Uint64{}
 	fplock := &sync.RWMutex{}
 	fp, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
 	if err != nil {
@@ -147,29 +488,63 @@ func NewFileAccessorLogHandler(
 	}
 
 	// flush file
-	flush := func() {
+	flush := func() error {
 		fplock.RLock()
 		defer fplock.RUnlock()
 		if fp == nil {
-			return
+			return nil
 		}
 		c := countwrt.Load()
-		if c == lastflush {
-			return
+		if lastflush.Swap(c) == c {
+			return nil
+		}
+		return fp.Sync()
+	}
+
+	// final flush and close
+	closeFile := func() error {
+		fplock.Lock()
+		defer fplock.Unlock()
+		if fp == nil {
+			return nil
+		}
+		err := fp.Close()
+		fp = nil
+		return err
+	}
+
+	// open the path again and swap the file, the current file is kept if
+	// the path can't be opened
+	reopen := func() error {
+		nfp, err := os.OpenFile(
+			path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
 		}
-		lastflush = c
-		fp.Sync()
+		fplock.Lock()
+		defer fplock.Unlock()
+		if fp == nil {
+			nfp.Close()
+			return os.ErrClosed
+		}
+		ofp := fp
+		fp = nfp
+		return ofp.Close()
 	}
 
+	fh := &fileHandler{flush: flush, close: closeFile, reopen: reopen}
+
 	// tiny log handler function
-	handler := func(level LogLevel, pnt func(io.StringWriter)) {
+	fh.TinyLogHandlerFunc = func(level LogLevel, pnt func(io.StringWriter)) {
 		fplock.RLock()
 		defer fplock.RUnlock()
 		if fp == nil {
 			return
 		}
-		pnt(fp)
+		ew := errorWriter{w: fp}
+		pnt(&ew)
 		countwrt.Add(1)
+		fh.report(ew.err)
 	}
 
 	// file holder thread
@@ -177,12 +552,7 @@ func NewFileAccessorLogHandler(
 		for {
 			select {
 			case <-ctx.Done():
-				func() { // final flush and close
-					fplock.Lock()
-					defer fplock.Unlock()
-					fp.Close()
-					fp = nil
-				}()
+				closeFile()
 				return
 			case <-time.After(2 * time.Second):
 				flush() // periodic flush
@@ -190,7 +560,69 @@ func NewFileAccessorLogHandler(
 		}
 	}()
 
-	return TinyLogHandlerFunc(handler), nil
+	return fh, nil
+}
+
+// fileHandler is the TinyLogHandlerFunc of a file handler, which also
+// flushes and closes the file synchronously and reports the write errors
+type fileHandler struct {
+	TinyLogHandlerFunc
+	writeErrors
+	disabled atomic.Bool // see Switchable
+	flush    func() error
+	close    func() error
+	reopen   func() error // nil if the handler rotates the file itself
+}
+
+// Flush writes the file to the storage, without waiting for the periodic
+// flush
+func (fh *fileHandler) Flush() error {
+	return fh.flush()
+}
+
+// Close closes the file, the following messages are dropped
+func (fh *fileHandler) Close() error {
+	return fh.close()
+}
+
+// SetEnabled enables or disables writing the regular log messages to the
+// file. panic and fatal messages are always written.
+func (fh *fileHandler) SetEnabled(enabled bool) {
+	fh.disabled.Store(!enabled)
+}
+
+// Enabled reports whether the handler is enabled. handlers are enabled by
+// default.
+func (fh *fileHandler) Enabled() bool {
+	return !fh.disabled.Load()
+}
+
+func (fh *fileHandler) RegularWriter(
+	level LogLevel, pnt func(io.StringWriter),
+) {
+	if level < PANIC && fh.disabled.Load() {
+		return
+	}
+	fh.TinyLogHandlerFunc.RegularWriter(level, pnt)
+}
+
+func (fh *fileHandler) RegularLog(
+	level LogLevel, header string, message ...any,
+) {
+	if level < PANIC && fh.disabled.Load() {
+		return
+	}
+	fh.TinyLogHandlerFunc.RegularLog(level, header, message...)
+}
+
+// Reopen opens the path of the file again, so the following messages are
+// written to the new file after an external rotation. the rotating
+// handlers reopen the file by themselves, Reopen does nothing for them.
+func (fh *fileHandler) Reopen() error {
+	if fh.reopen == nil {
+		return nil
+	}
+	return fh.reopen()
 }
 
 // ------- implement LogHandler interface for LogHandlerFunc -------
@@ -209,6 +641,62 @@ func (lh *LogHandlerFunc) IsShutdown() bool {
 	return false
 }
 
+// SetEnabled enables or disables the RegularLogFunc of the handler for the
+// regular log messages. the Wrapper always receives log messages, panic and
+// fatal messages are always written.
+func (lh *LogHandlerFunc) SetEnabled(enabled bool) {
+	lh.disabled.Store(!enabled)
+}
+
+// Enabled reports whether the handler is enabled. handlers are enabled by
+// default.
+func (lh *LogHandlerFunc) Enabled() bool {
+	return !lh.disabled.Load()
+}
+
+// writes reports whether RegularLogFunc is called for a message of level,
+// panic and fatal messages are written even if the handler is disabled
+func (lh *LogHandlerFunc) writes(level LogLevel) bool {
+	return level >= PANIC || !lh.disabled.Load()
+}
+
+// Flush flushes the Wrapper (if it implements Flusher), then calls
+// FlushFunc.
+func (lh *LogHandlerFunc) Flush() error {
+	if lh.Lock != nil {
+		lh.Lock.Lock()
+		defer lh.Lock.Unlock()
+	}
+	var errw, errf error
+	if lh.Wrapper != nil {
+		errw = flushHandler(lh.Wrapper)
+	}
+	if lh.FlushFunc != nil {
+		errf = lh.FlushFunc()
+	}
+	return errors.Join(errw, errf)
+}
+
+// Close calls CloseFunc, then closes the Wrapper (if it implements
+// io.Closer). only the first call takes effect.
+func (lh *LogHandlerFunc) Close() error {
+	if !lh.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	if lh.Lock != nil {
+		lh.Lock.Lock()
+		defer lh.Lock.Unlock()
+	}
+	var errc, errw error
+	if lh.CloseFunc != nil {
+		errc = lh.CloseFunc()
+	}
+	if lh.Wrapper != nil {
+		errw = closeHandler(lh.Wrapper)
+	}
+	return errors.Join(errc, errw)
+}
+
 // rawWriteLogFunc provide a default method to formats the message body and writes
 // it using the provided i/o writer
 func (lh *LogHandlerFunc) rawWriteLogFunc(
@@ -232,6 +720,53 @@ func (lh *LogHandlerFunc) writeLogFunc(
 	return lh.rawWriteLogFunc(header, message...)
 }
 
+// regularWrite writes a regular log message to the Wrapper and
+// RegularLogFunc, fails over to the Fallback if any of them fails
+func (lh *LogHandlerFunc) regularWrite(
+	level LogLevel, pnt func(io.StringWriter),
+) {
+	if lh.Fallback == nil {
+		if lh.Wrapper != nil {
+			lh.Wrapper.RegularWriter(level, pnt)
+		}
+		if lh.RegularLogFunc != nil && lh.writes(level) {
+			lh.RegularLogFunc(level, pnt)
+		}
+		return
+	}
+	failed := false
+	if lh.Wrapper != nil {
+		failed = guardedWrite(lh.Wrapper.RegularWriter, level, pnt)
+	}
+	if lh.RegularLogFunc != nil && lh.writes(level) {
+		failed = guardedWrite(lh.RegularLogFunc, level, pnt) || failed
+	}
+	if failed {
+		lh.Fallback.RegularWriter(level, pnt)
+	}
+}
+
+// guardedWrite calls the write function, returns true if it panics or the
+// StringWriter returns error
+func guardedWrite(
+	write func(LogLevel, func(io.StringWriter)),
+	level LogLevel, pnt func(io.StringWriter),
+) (failed bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			failed = true
+		}
+	}()
+	write(level, func(w io.StringWriter) {
+		ew := &errorWriter{w: w}
+		pnt(ew)
+		if ew.err != nil {
+			failed = true
+		}
+	})
+	return failed
+}
+
 func (lh *LogHandlerFunc) RegularWriter(
 	level LogLevel, pnt func(io.StringWriter),
 ) {
@@ -239,12 +774,7 @@ func (lh *LogHandlerFunc) RegularWriter(
 		lh.Lock.Lock()
 		defer lh.Lock.Unlock()
 	}
-	if lh.Wrapper != nil {
-		lh.Wrapper.RegularWriter(level, pnt)
-	}
-	if lh.RegularLogFunc != nil {
-		lh.RegularLogFunc(level, pnt)
-	}
+	lh.regularWrite(level, pnt)
 }
 
 func (lh *LogHandlerFunc) RegularLog(
@@ -254,13 +784,7 @@ func (lh *LogHandlerFunc) RegularLog(
 		lh.Lock.Lock()
 		defer lh.Lock.Unlock()
 	}
-	pnt := lh.writeLogFunc(header, message...)
-	if lh.Wrapper != nil {
-		lh.Wrapper.RegularWriter(level, pnt)
-	}
-	if lh.RegularLogFunc != nil {
-		lh.RegularLogFunc(level, pnt)
-	}
+	lh.regularWrite(level, lh.writeLogFunc(header, message...))
 }
 
 func (lh *LogHandlerFunc) PanicLog(header string, message ...any) {
@@ -299,6 +823,9 @@ func (lh *LogHandlerFunc) FatalLog(header string, message ...any) {
 		return nil
 	}()
 	if fin != nil {
+		// the finalizer usually terminates the program, the buffered
+		// messages (including the fatal one) must reach the storage first
+		lh.Flush()
 		fin()
 	}
 }
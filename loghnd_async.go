@@ -0,0 +1,192 @@
+package nekomimi
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// AsyncMode selects the behavior of the async handler when its queue is
+// full, see NewAsyncLogHandlerWithMode
+type AsyncMode int
+
+const (
+	// AsyncBlock blocks the log call until the queue has room, no message
+	// is lost
+	AsyncBlock AsyncMode = iota
+	// AsyncDropOldest drops the oldest queued message to make room, the log
+	// call never blocks. the dropped messages are counted, see DropCounter.
+	AsyncDropOldest
+)
+
+// asyncEntry is a queued log message. a nil pnt is a barrier, done is
+// closed when the entries queued before it are processed.
+type asyncEntry struct {
+	level LogLevel
+	pnt   func(io.StringWriter)
+	done  chan struct{}
+}
+
+// asyncHandler is the LogHandler returned by NewAsyncLogHandler
+type asyncHandler struct {
+	wrap  LogHandler
+	mode  AsyncMode
+	queue chan asyncEntry
+
+	mtx     sync.RWMutex // write lock is held to close the queue
+	closed  bool
+	stopped chan struct{}
+	once    sync.Once
+
+	dropped atomic.Uint64
+}
+
+// NewAsyncLogHandler creates a LogHandler which moves the writes of
+// wrapped out of the log calls: regular log messages are rendered, queued
+// and forwarded to wrapped by a background goroutine. the log call blocks
+// while the queue (of queueSize) is full, see NewAsyncLogHandlerWithMode for
+// the drop mode.
+//
+// the returned function drains the queue and stops the goroutine, the
+// following messages are forwarded synchronously. panic and fatal messages
+// wait for the queue to be drained, then are forwarded synchronously, so
+// nothing queued is lost when the program dies. the handler implements
+// Flusher and io.Closer as well.
+func NewAsyncLogHandler(wrapped LogHandler, queueSize int) (LogHandler, func()) {
+	return NewAsyncLogHandlerWithMode(wrapped, queueSize, AsyncBlock)
+}
+
+// NewAsyncLogHandlerWithMode is like NewAsyncLogHandler, with the behavior
+// when the queue is full selected by mode
+func NewAsyncLogHandlerWithMode(
+	wrapped LogHandler, queueSize int, mode AsyncMode,
+) (LogHandler, func()) {
+	h := &asyncHandler{
+		wrap:    wrapped,
+		mode:    mode,
+		queue:   make(chan asyncEntry, max(queueSize, 1)),
+		stopped: make(chan struct{}),
+	}
+	go h.loop()
+	return h, h.stop
+}
+
+// loop forwards the queued messages until the queue is closed
+func (h *asyncHandler) loop() {
+	defer close(h.stopped)
+	for e := range h.queue {
+		if e.pnt == nil {
+			close(e.done)
+			continue
+		}
+		h.wrap.RegularWriter(e.level, e.pnt)
+	}
+}
+
+// enqueue queues the entry. returns false if the handler is stopped.
+func (h *asyncHandler) enqueue(e asyncEntry, block bool) bool {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+	if h.closed {
+		return false
+	}
+	if block || h.mode == AsyncBlock {
+		h.queue <- e
+		return true
+	}
+	for {
+		select {
+		case h.queue <- e:
+			return true
+		default:
+		}
+		select {
+		case old := <-h.queue:
+			if old.pnt == nil {
+				close(old.done) // never drop a barrier
+				continue
+			}
+			h.dropped.Add(1)
+		default:
+		}
+	}
+}
+
+// drain waits until the messages queued so far are forwarded
+func (h *asyncHandler) drain() {
+	done := make(chan struct{})
+	if h.enqueue(asyncEntry{done: done}, true) {
+		<-done
+	}
+}
+
+// stop drains the queue and stops the background goroutine
+func (h *asyncHandler) stop() {
+	h.once.Do(func() {
+		h.mtx.Lock()
+		h.closed = true
+		close(h.queue)
+		h.mtx.Unlock()
+	})
+	<-h.stopped
+}
+
+// Dropped returns the number of messages dropped in AsyncDropOldest mode
+func (h *asyncHandler) Dropped() uint64 {
+	return h.dropped.Load()
+}
+
+func (h *asyncHandler) IsShutdown() bool {
+	return h.wrap.IsShutdown()
+}
+
+func (h *asyncHandler) RegularWriter(
+	level LogLevel, pnt func(io.StringWriter),
+) {
+	// render now, pnt might refer to the data changed after the call
+	sb := strings.Builder{}
+	pnt(&sb)
+	line := sb.String()
+	wpnt := func(w io.StringWriter) {
+		w.WriteString(line)
+	}
+	if !h.enqueue(asyncEntry{level: level, pnt: wpnt}, false) {
+		h.wrap.RegularWriter(level, wpnt)
+	}
+}
+
+func (h *asyncHandler) RegularLog(
+	level LogLevel, header string, message ...any,
+) {
+	sp := fmt.Sprintln(message...)
+	h.RegularWriter(level, func(w io.StringWriter) {
+		w.WriteString(header)
+		w.WriteString(sp)
+	})
+}
+
+func (h *asyncHandler) PanicLog(header string, message ...any) {
+	h.drain()
+	h.wrap.PanicLog(header, message...)
+}
+
+func (h *asyncHandler) FatalLog(header string, message ...any) {
+	h.drain()
+	h.wrap.FatalLog(header, message...)
+}
+
+// Flush waits for the queued messages to be forwarded, then flushes the
+// wrapped handler
+func (h *asyncHandler) Flush() error {
+	h.drain()
+	return flushHandler(h.wrap)
+}
+
+// Close stops the handler like the function returned by
+// NewAsyncLogHandler, then closes the wrapped handler
+func (h *asyncHandler) Close() error {
+	h.stop()
+	return closeHandler(h.wrap)
+}
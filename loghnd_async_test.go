@@ -0,0 +1,106 @@
+package nekomimi
+
+import (
+	"io"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// gatedSink blocks the writes until the gate is opened
+type gatedSink struct {
+	captureLogHandler
+	gate chan struct{}
+}
+
+func newGatedSink() *gatedSink {
+	return &gatedSink{gate: make(chan struct{})}
+}
+
+func (gs *gatedSink) handler() LogHandler {
+	h := gs.captureLogHandler.handler().(*LogHandlerFunc)
+	h.RegularLogFunc = func(level LogLevel, pnt func(io.StringWriter)) {
+		<-gs.gate
+		gs.record(level, pnt)
+	}
+	return h
+}
+
+func TestAsyncLogHandler(t *testing.T) {
+	Convey("Async handler tests", t, func() {
+		Convey("Messages are forwarded in order", func() {
+			sink := &captureLogHandler{}
+			h, stop := NewAsyncLogHandler(sink.handler(), 4)
+			l := New("App", LogConfig{Handler: h, TestMode: true})
+			for i := range 100 {
+				l.Inff("message %d", i)
+			}
+			stop()
+			So(sink.count(), ShouldEqual, 100)
+			So(sink.lines[0], ShouldEndWith, "[INFO], App - message 0\n")
+			So(sink.last(), ShouldEndWith, "[INFO], App - message 99\n")
+
+			// stopped handler forwards synchronously
+			l.Inf("after stop")
+			So(sink.last(), ShouldEndWith, "[INFO], App - after stop\n")
+			stop() // no effect
+		})
+
+		Convey("Block mode waits for room", func() {
+			sink := newGatedSink()
+			h, stop := NewAsyncLogHandler(sink.handler(), 2)
+			l := New("App", LogConfig{Handler: h, TestMode: true})
+			wg := sync.WaitGroup{}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range 10 {
+					l.Inff("message %d", i)
+				}
+			}()
+			close(sink.gate)
+			wg.Wait()
+			stop()
+			So(sink.count(), ShouldEqual, 10)
+			So(h.(DropCounter).Dropped(), ShouldEqual, 0)
+		})
+
+		Convey("Drop mode drops the oldest", func() {
+			sink := newGatedSink()
+			h, stop := NewAsyncLogHandlerWithMode(
+				sink.handler(), 3, AsyncDropOldest)
+			l := New("App", LogConfig{Handler: h, TestMode: true})
+			l.Inf("first") // taken by the goroutine, blocked on the gate
+			So(waitFor(func() bool {
+				return len(h.(*asyncHandler).queue) == 0
+			}), ShouldBeTrue)
+			for i := range 10 {
+				l.Inff("message %d", i) // never blocks
+			}
+			So(h.(DropCounter).Dropped(), ShouldEqual, 7)
+			close(sink.gate)
+			stop()
+			So(sink.count(), ShouldEqual, 4)
+			So(sink.lines[1], ShouldEndWith, "[INFO], App - message 7\n")
+			So(sink.last(), ShouldEndWith, "[INFO], App - message 9\n")
+		})
+
+		Convey("Panic drains the queue first", func() {
+			sink := &captureLogHandler{}
+			h, stop := NewAsyncLogHandler(sink.handler(), 100)
+			defer stop()
+			l := New("App", LogConfig{Handler: h, TestMode: true})
+			for i := range 50 {
+				l.Inff("message %d", i)
+			}
+			l.Panic("boom")
+			So(sink.count(), ShouldEqual, 51)
+			So(sink.last(), ShouldEndWith, "[PANIC], App - boom\n")
+
+			l.Inf("flushed")
+			So(h.(Flusher).Flush(), ShouldBeNil)
+			So(sink.last(), ShouldEndWith, "[INFO], App - flushed\n")
+		})
+	})
+}
@@ -0,0 +1,171 @@
+package nekomimi
+
+import (
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ANSI colors of the level tags
+const (
+	colorReset  = "\x1b[0m"
+	colorGray   = "\x1b[90m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorRed    = "\x1b[31m"
+)
+
+// levelColor returns the ANSI color of the level tag
+func levelColor(level LogLevel) string {
+	switch {
+	case level <= DEBUG:
+		return colorGray
+	case level == INFO:
+		return colorGreen
+	case level == WARN:
+		return colorYellow
+	default:
+		return colorRed
+	}
+}
+
+// colorSupported reports whether w is a terminal and NO_COLOR is not set
+func colorSupported(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	st, err := f.Stat()
+	return err == nil && st.Mode()&os.ModeCharDevice != 0
+}
+
+// colorizeLevel wraps the `[LEVEL]` tag of a formatted log line with the
+// color of level. the line is returned unchanged if the tag is not
+// recognizable.
+func colorizeLevel(line string, level LogLevel) string {
+	lb, rb, ok := findLevelTag(line)
+	if !ok {
+		return line
+	}
+	return line[:lb] + levelColor(level) + line[lb:rb+1] +
+		colorReset + line[rb+1:]
+}
+
+// NewColorLogHandler creates a new LogHandler which writes log messages to
+// w with ANSI colored level tags: TRACE and DEBUG gray, INFO green, WARN
+// yellow, ERROR, PANIC and FATAL red. only the `[LEVEL]` tag is colored.
+//
+// colors are enabled only if w is a terminal and the NO_COLOR environment
+// variable is not set, so the output redirected to a file stays plain.
+// force enables colors regardless. like the native handler, PANIC messages
+// raise panic and FATAL messages terminate the program after logging.
+func NewColorLogHandler(w io.Writer, force bool) LogHandler {
+	color := force || colorSupported(w)
+	output := func(level LogLevel, pnt func(io.StringWriter)) {
+		sb := strings.Builder{}
+		pnt(&sb)
+		line := sb.String()
+		if color {
+			line = colorizeLevel(line, level)
+		}
+		io.WriteString(w, line)
+	}
+	return &LogHandlerFunc{
+		Lock:           &sync.Mutex{},
+		RegularLogFunc: output,
+		PanicLogFunc: func(
+			pnt func(io.StringWriter), info string,
+		) func() {
+			output(PANIC, pnt)
+			return func() {
+				panic(info)
+			}
+		},
+		FatalLogFunc: func(pnt func(io.StringWriter)) func() {
+			output(FATAL, pnt)
+			return sysTerminate
+		},
+	}
+}
+
+// ansiEscape matches the ANSI CSI sequences (colors, cursor moves...) and
+// the two-character escape sequences
+var ansiEscape = regexp.MustCompile(`\x1b(?:\[[0-9;?]*[ -/]*[@-~]|[@-Z\\-_])`)
+
+// stripANSI removes the ANSI escape sequences of s
+func stripANSI(s string) string {
+	if !strings.Contains(s, "\x1b") {
+		return s
+	}
+	return ansiEscape.ReplaceAllString(s, "")
+}
+
+// stripColorHandler is the LogHandler returned by NewStripColorLogHandler
+type stripColorHandler struct {
+	wrap LogHandler
+}
+
+// NewStripColorLogHandler creates a LogHandler which removes the ANSI escape
+// sequences (e.g. the colors of NewColorLogHandler with force) from the
+// formatted log messages before forwarding them to wrap. use it above a
+// file sink when a sibling console sink is colored, so the file stays plain
+// text.
+func NewStripColorLogHandler(wrap LogHandler) LogHandler {
+	return &stripColorHandler{wrap: wrap}
+}
+
+// stripArgs removes the ANSI escape sequences of the string arguments
+func stripArgs(message []any) []any {
+	args := make([]any, len(message))
+	for i, m := range message {
+		if s, ok := m.(string); ok {
+			m = stripANSI(s)
+		}
+		args[i] = m
+	}
+	return args
+}
+
+func (sc *stripColorHandler) IsShutdown() bool {
+	return sc.wrap.IsShutdown()
+}
+
+func (sc *stripColorHandler) RegularWriter(
+	level LogLevel, pnt func(io.StringWriter),
+) {
+	sb := strings.Builder{}
+	pnt(&sb)
+	line := stripANSI(sb.String())
+	sc.wrap.RegularWriter(level, func(w io.StringWriter) {
+		w.WriteString(line)
+	})
+}
+
+func (sc *stripColorHandler) RegularLog(
+	level LogLevel, header string, message ...any,
+) {
+	sc.wrap.RegularLog(level, stripANSI(header), stripArgs(message)...)
+}
+
+func (sc *stripColorHandler) PanicLog(header string, message ...any) {
+	sc.wrap.PanicLog(stripANSI(header), stripArgs(message)...)
+}
+
+func (sc *stripColorHandler) FatalLog(header string, message ...any) {
+	sc.wrap.FatalLog(stripANSI(header), stripArgs(message)...)
+}
+
+// Flush flushes the wrapped handler
+func (sc *stripColorHandler) Flush() error {
+	return flushHandler(sc.wrap)
+}
+
+// Close closes the wrapped handler
+func (sc *stripColorHandler) Close() error {
+	return closeHandler(sc.wrap)
+}
@@ -0,0 +1,99 @@
+package nekomimi
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestColorLogHandler(t *testing.T) {
+	Convey("Color handler tests", t, func() {
+		buf := &bytes.Buffer{}
+
+		Convey("Only the level tag is colored", func() {
+			l := New("App", LogConfig{
+				Handler:  NewColorLogHandler(buf, true),
+				TestMode: true,
+			})
+			l.Dbg("debug [x]")
+			l.Inf("info")
+			l.War("warn")
+			l.Err("error")
+			So(buf.String(), ShouldEqual,
+				"2000-01-01 00:00:00.000 \x1b[90m[DEBUG]\x1b[0m, App - debug [x]\n"+
+					"2000-01-01 00:00:00.000 \x1b[32m[INFO]\x1b[0m, App - info\n"+
+					"2000-01-01 00:00:00.000 \x1b[33m[WARN]\x1b[0m, App - warn\n"+
+					"2000-01-01 00:00:00.000 \x1b[31m[ERROR]\x1b[0m, App - error\n")
+
+			buf.Reset()
+			l.RawWriter().WriteString("raw [text] line\n")
+			So(buf.String(), ShouldEqual, "raw [text] line\n")
+
+			buf.Reset()
+			So(func() { l.Panic("boom") }, ShouldPanicWith, "boom\n")
+			So(buf.String(), ShouldEqual,
+				"2000-01-01 00:00:00.000 \x1b[31m[PANIC]\x1b[0m, App - boom\n")
+		})
+
+		Convey("Padded level tag is colored", func() {
+			l := New("App", LogConfig{
+				Handler:  NewColorLogHandler(buf, true),
+				TestMode: true,
+				PadLevel: true,
+			})
+			l.Inf("info")
+			So(buf.String(), ShouldEqual,
+				"2000-01-01 00:00:00.000 \x1b[32m[INFO ]\x1b[0m, App - info\n")
+		})
+
+		Convey("Colors are disabled when not a terminal", func() {
+			l := New("App", LogConfig{
+				Handler:  NewColorLogHandler(buf, false),
+				TestMode: true,
+			})
+			l.Err("plain")
+			So(buf.String(), ShouldEqual,
+				"2000-01-01 00:00:00.000 [ERROR], App - plain\n")
+
+			fp, err := os.Create(filepath.Join(t.TempDir(), "app.log"))
+			So(err, ShouldBeNil)
+			defer fp.Close()
+			So(colorSupported(fp), ShouldBeFalse)
+		})
+
+		Convey("Strip handler forwards plain text", func() {
+			file := &captureLogHandler{}
+			h := &LogHandlerFunc{
+				RegularLogFunc: NewColorLogHandler(buf, true).RegularWriter,
+				Wrapper: NewStripColorLogHandler(
+					NewLevelFilterHandler(TRACE, file.handler())),
+			}
+			l := New("App", LogConfig{Handler: h, TestMode: true})
+			l.War("\x1b[1mbold\x1b[0m message")
+			So(buf.String(), ShouldEqual, "2000-01-01 00:00:00.000 "+
+				"\x1b[33m[WARN]\x1b[0m, App - \x1b[1mbold\x1b[0m message\n")
+			So(file.last(), ShouldEqual,
+				"2000-01-01 00:00:00.000 [WARN], App - bold message\n")
+
+			// colored lines from an upstream writer
+			NewStripColorLogHandler(file.handler()).RegularWriter(INFO,
+				func(w io.StringWriter) {
+					w.WriteString("\x1b[32m[INFO]\x1b[0m, x - \x1b[2Kcleared\n")
+				})
+			So(file.last(), ShouldEqual, "[INFO], x - cleared\n")
+
+			NewStripColorLogHandler(file.handler()).PanicLog(
+				"\x1b[31m[PANIC]\x1b[0m - ", "\x1b[31mboom\x1b[0m", 42)
+			So(file.last(), ShouldEqual, "[PANIC] - boom 42\n")
+		})
+
+		Convey("NO_COLOR disables the detection", func() {
+			t.Setenv("NO_COLOR", "1")
+			So(colorSupported(os.Stdout), ShouldBeFalse)
+		})
+	})
+}
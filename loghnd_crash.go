@@ -0,0 +1,112 @@
+package nekomimi
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// crashRecentLogs is the number of recent log lines kept for crash reports
+const crashRecentLogs = 100
+
+// NewCrashReportLogHandler creates a new LogHandler that writes a crash
+// report file into dir on PANIC and FATAL messages, then raises panic or
+// terminates the program. the report contains the message, the call
+// stack, the recent regular log lines and runtime statistics.
+//
+// the report is written to a temporary file in dir, then renamed to
+// crash-<timestamp>.log, so readers never see a partial report. failures
+// of writing the report are ignored, the program crashes anyway.
+//
+// wrap receives all the log messages (including PANIC and FATAL) as
+// Wrapper, e.g. the native handler to print them to the console.
+func NewCrashReportLogHandler(dir string, wrap LogHandler) LogHandler {
+	recent := make([]string, 0, crashRecentLogs)
+	report := func(level LogLevel, pnt func(io.StringWriter)) {
+		sb := strings.Builder{}
+		pnt(&sb)
+		writeCrashReport(dir, level, sb.String(), recent)
+	}
+	return &LogHandlerFunc{
+		Lock: &sync.Mutex{},
+		RegularLogFunc: func(level LogLevel, pnt func(io.StringWriter)) {
+			sb := strings.Builder{}
+			pnt(&sb)
+			if len(recent) == crashRecentLogs {
+				copy(recent, recent[1:])
+				recent = recent[:crashRecentLogs-1]
+			}
+			recent = append(recent, strings.TrimSuffix(sb.String(), "\n"))
+		},
+		PanicLogFunc: func(pnt func(io.StringWriter), info string) func() {
+			report(PANIC, pnt)
+			return func() {
+				panic(info)
+			}
+		},
+		FatalLogFunc: func(pnt func(io.StringWriter)) func() {
+			report(FATAL, pnt)
+			return sysTerminate
+		},
+		Wrapper: wrap,
+	}
+}
+
+// writeCrashReport assembles the crash report and writes it atomically
+func writeCrashReport(
+	dir string, level LogLevel, line string, recent []string,
+) {
+	now := time.Now()
+	rec, _ := parseRecordLine(level, line)
+	sb := strings.Builder{}
+	fmt.Fprintf(&sb, "CRASH REPORT %s\n", now.Format(time.RFC3339Nano))
+	fmt.Fprintf(&sb, "\n=== %s ===\n%s", level, line)
+	if !strings.HasSuffix(line, "\n") {
+		sb.WriteByte('\n')
+	}
+	if len(rec.Stack) > 0 {
+		sb.WriteString("\n=== stack ===\n")
+		for _, fr := range rec.Stack {
+			sb.WriteString(fr + "\n")
+		}
+	}
+	sb.WriteString("\n=== recent logs ===\n")
+	for _, l := range recent {
+		sb.WriteString(l + "\n")
+	}
+	ms := runtime.MemStats{}
+	runtime.ReadMemStats(&ms)
+	sb.WriteString("\n=== runtime ===\n")
+	fmt.Fprintf(&sb, "go: %s %s/%s\n",
+		runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&sb, "pid: %d\n", os.Getpid())
+	fmt.Fprintf(&sb, "goroutines: %d\n", runtime.NumGoroutine())
+	fmt.Fprintf(&sb, "heap_alloc: %d\n", ms.HeapAlloc)
+	fmt.Fprintf(&sb, "heap_sys: %d\n", ms.HeapSys)
+	fmt.Fprintf(&sb, "num_gc: %d\n", ms.NumGC)
+
+	tmp, err := os.CreateTemp(dir, ".crash-*.tmp")
+	if err != nil {
+		return
+	}
+	_, err = tmp.WriteString(sb.String())
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		name := fmt.Sprintf("crash-%s.log",
+			now.Format("20060102-150405.000000000"))
+		err = os.Rename(tmp.Name(), filepath.Join(dir, name))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+}
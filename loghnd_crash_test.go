@@ -0,0 +1,79 @@
+package nekomimi
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// crashReports returns the files in dir
+func crashReports(dir string) []string {
+	entries, _ := os.ReadDir(dir)
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestCrashReportLogHandler(t *testing.T) {
+	Convey("Crash report log handler tests", t, func() {
+		dir := t.TempDir()
+		rec := &captureLogHandler{}
+		l := New("App", LogConfig{
+			Handler:        NewCrashReportLogHandler(dir, rec.handler()),
+			LevelWithTrace: FATAL,
+		})
+
+		Convey("Fatal writes a complete report", func() {
+			backupTm := sysTerminate
+			terminated := false
+			sysTerminate = func() {
+				terminated = true
+			}
+			defer func() {
+				sysTerminate = backupTm
+			}()
+
+			l.Inf("starting")
+			l.War("disk almost full")
+			So(crashReports(dir), ShouldBeEmpty)
+			l.Fatal("disk full")
+			So(terminated, ShouldBeTrue)
+			So(rec.last(), ShouldContainSubstring, "disk full")
+
+			names := crashReports(dir)
+			So(len(names), ShouldEqual, 1)
+			So(names[0], ShouldStartWith, "crash-")
+			So(names[0], ShouldEndWith, ".log")
+			data, err := os.ReadFile(filepath.Join(dir, names[0]))
+			So(err, ShouldBeNil)
+			report := string(data)
+			So(report, ShouldStartWith, "CRASH REPORT ")
+			So(report, ShouldContainSubstring, "=== FATAL ===\n")
+			So(report, ShouldContainSubstring, "[FATAL], App >> Stacks:")
+			So(report, ShouldContainSubstring, "- disk full\n")
+			So(report, ShouldContainSubstring, "=== stack ===\n")
+			So(report, ShouldContainSubstring, "loghnd_crash_test.go")
+			So(report, ShouldContainSubstring, "=== recent logs ===\n")
+			So(report, ShouldContainSubstring, "[INFO], App - starting\n")
+			So(report, ShouldContainSubstring,
+				"[WARN], App - disk almost full\n")
+			So(report, ShouldContainSubstring, "=== runtime ===\n")
+			So(report, ShouldContainSubstring, "goroutines: ")
+			So(report, ShouldEndWith, "\n")
+		})
+
+		Convey("Panic writes a report and panics", func() {
+			So(func() { l.Panic("bad state") }, ShouldPanicWith, "bad state\n")
+			names := crashReports(dir)
+			So(len(names), ShouldEqual, 1)
+			data, err := os.ReadFile(filepath.Join(dir, names[0]))
+			So(err, ShouldBeNil)
+			So(string(data), ShouldContainSubstring, "=== PANIC ===\n")
+			So(string(data), ShouldContainSubstring, "- bad state\n")
+		})
+	})
+}
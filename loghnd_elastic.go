@@ -0,0 +1,139 @@
+package nekomimi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// elasticBulkAction is the action line of each record in the bulk body.
+// the target index is given by the bulk endpoint (`/<index>/_bulk`).
+var elasticBulkAction = []byte(`{"index":{}}` + "\n")
+
+// elasticHandler is the LogHandler returned by NewElasticBulkLogHandler
+type elasticHandler struct {
+	RecordLogHandlerFunc
+	flush     func(ndjson []byte) error
+	batchSize int
+
+	mtx     sync.Mutex
+	buf     bytes.Buffer
+	pending uint64 // number of records in buf
+
+	done    chan struct{}
+	dropped atomic.Uint64
+}
+
+// NewElasticBulkLogHandler creates a LogHandler which accumulates the log
+// messages as Elasticsearch bulk NDJSON, an action line followed by the
+// JSON Record (see Record.MarshalJSON) for each message:
+//
+//	{"index":{}}
+//	{"time":"...","level":"INFO","prefix":"App","msg":"..."}
+//
+// the body is passed to flush when batchSize records are accumulated, and
+// every interval (if positive) in background. the Elasticsearch client is
+// kept out of this package, flush should send the body to the `_bulk`
+// endpoint of the index. when ctx is done, the remaining records are
+// flushed and the background task stops.
+//
+// if flush returns error, the records of the batch are dropped, the
+// returned handler implements DropCounter. use RetryPolicy.Run in flush
+// for bounded retries. the handler implements Flusher as well. like
+// RecordLogHandlerFunc, it never raises panic or terminates the program,
+// it should be used as Wrapper of other handlers.
+func NewElasticBulkLogHandler(
+	ctx context.Context,
+	flush func(ndjson []byte) error,
+	batchSize int,
+	interval time.Duration,
+) LogHandler {
+	h := &elasticHandler{
+		flush:     flush,
+		batchSize: max(batchSize, 1),
+		done:      make(chan struct{}),
+	}
+	h.RecordLogHandlerFunc = h.add
+	go h.loop(ctx, interval)
+	return h
+}
+
+// loop flushes the records every interval until ctx is done
+func (h *elasticHandler) loop(ctx context.Context, interval time.Duration) {
+	defer close(h.done)
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			h.Flush()
+			return
+		case <-tick:
+			h.Flush()
+		}
+	}
+}
+
+// add appends a record to the batch, flushes the batch if it's full
+func (h *elasticHandler) add(rec Record) {
+	source, err := json.Marshal(rec)
+	if err != nil {
+		h.dropped.Add(1) // marshal failure, drop log
+		return
+	}
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.buf.Write(elasticBulkAction)
+	h.buf.Write(source)
+	h.buf.WriteByte('\n')
+	h.pending++
+	if h.pending >= uint64(h.batchSize) {
+		h.send()
+	}
+}
+
+// send passes the accumulated batch to flush. must be called with mtx held.
+func (h *elasticHandler) send() error {
+	if h.pending == 0 {
+		return nil
+	}
+	body := bytes.Clone(h.buf.Bytes())
+	n := h.pending
+	h.buf.Reset()
+	h.pending = 0
+	err := h.flush(body)
+	if err != nil {
+		h.dropped.Add(n)
+	}
+	return err
+}
+
+// Flush sends the accumulated records immediately
+func (h *elasticHandler) Flush() error {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return h.send()
+}
+
+// Dropped returns the number of records dropped so far
+func (h *elasticHandler) Dropped() uint64 {
+	return h.dropped.Load()
+}
+
+// IsShutdown returns true after ctx is done and the remaining records are
+// flushed
+func (h *elasticHandler) IsShutdown() bool {
+	select {
+	case <-h.done:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,93 @@
+package nekomimi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestElasticBulkLogHandler(t *testing.T) {
+	Convey("Elasticsearch bulk log handler tests", t, func() {
+		var mtx sync.Mutex
+		var bodies []string
+		fail := false
+		flush := func(ndjson []byte) error {
+			mtx.Lock()
+			defer mtx.Unlock()
+			if fail {
+				return fmt.Errorf("bulk rejected")
+			}
+			bodies = append(bodies, string(ndjson))
+			return nil
+		}
+		batches := func() []string {
+			mtx.Lock()
+			defer mtx.Unlock()
+			return append([]string(nil), bodies...)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		Reset(cancel)
+
+		Convey("Flushes on batch size with bulk framing", func() {
+			h := NewElasticBulkLogHandler(ctx, flush, 2, 0)
+			l := New("App", LogConfig{Handler: h})
+			l.Inf("first")
+			So(batches(), ShouldBeEmpty)
+			l.Derive("DB").Err("second")
+			l.Inf("third")
+			So(len(batches()), ShouldEqual, 1)
+
+			lines := strings.Split(strings.TrimSuffix(batches()[0], "\n"), "\n")
+			So(len(lines), ShouldEqual, 4)
+			So(lines[0], ShouldEqual, `{"index":{}}`)
+			So(lines[2], ShouldEqual, `{"index":{}}`)
+			rec := map[string]any{}
+			So(json.Unmarshal([]byte(lines[3]), &rec), ShouldBeNil)
+			So(rec["level"], ShouldEqual, "ERROR")
+			So(rec["prefix"], ShouldEqual, "App.DB")
+			So(rec["msg"], ShouldEqual, "second")
+
+			So(h.(Flusher).Flush(), ShouldBeNil)
+			So(len(batches()), ShouldEqual, 2)
+			So(batches()[1], ShouldContainSubstring, `"msg":"third"`)
+			So(h.(Flusher).Flush(), ShouldBeNil)
+			So(len(batches()), ShouldEqual, 2)
+		})
+
+		Convey("Flushes on interval and on shutdown", func() {
+			h := NewElasticBulkLogHandler(ctx, flush, 100, 20*time.Millisecond)
+			l := New("App", LogConfig{Handler: h})
+			l.Inf("by interval")
+			So(waitFor(func() bool { return len(batches()) == 1 }), ShouldBeTrue)
+
+			h2 := NewElasticBulkLogHandler(ctx, flush, 100, 0)
+			New("App", LogConfig{Handler: h2}).Inf("by shutdown")
+			So(h2.IsShutdown(), ShouldBeFalse)
+			cancel()
+			So(waitFor(h2.IsShutdown), ShouldBeTrue)
+			So(batches()[len(batches())-1], ShouldContainSubstring,
+				`"msg":"by shutdown"`)
+		})
+
+		Convey("Failed batch is dropped and counted", func() {
+			h := NewElasticBulkLogHandler(ctx, flush, 3, 0)
+			l := New("App", LogConfig{Handler: h})
+			fail = true
+			for range 3 {
+				l.Inf("lost")
+			}
+			So(h.(DropCounter).Dropped(), ShouldEqual, 3)
+			fail = false
+			l.Inf("kept")
+			So(h.(Flusher).Flush(), ShouldBeNil)
+			So(len(batches()), ShouldEqual, 1)
+			So(batches()[0], ShouldNotContainSubstring, "lost")
+		})
+	})
+}
@@ -0,0 +1,295 @@
+package nekomimi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// recordWriterHandler is the LogHandler returned by newRecordWriterHandler
+type recordWriterHandler struct {
+	RecordLogHandlerFunc
+}
+
+// newRecordWriterHandler creates a LogHandler which writes each log message
+// to w as one line encoded from its Record. panic and fatal messages are
+// written as well, then raise panic or terminate the program like the
+// native handler.
+func newRecordWriterHandler(
+	w io.Writer, encode func(Record) ([]byte, error),
+) LogHandler {
+	mtx := &sync.Mutex{}
+	return recordWriterHandler{func(rec Record) {
+		line, err := encode(rec)
+		if err != nil {
+			return
+		}
+		mtx.Lock()
+		defer mtx.Unlock()
+		w.Write(append(line, '\n'))
+	}}
+}
+
+func (rh recordWriterHandler) PanicLog(header string, message ...any) {
+	rh.RecordLogHandlerFunc.PanicLog(header, message...)
+	panic(fmt.Sprintln(message...))
+}
+
+func (rh recordWriterHandler) FatalLog(header string, message ...any) {
+	rh.RecordLogHandlerFunc.FatalLog(header, message...)
+	sysTerminate()
+}
+
+// NewJSONLogHandler creates a LogHandler which writes each log message to w
+// as one JSON object per line (see Record.MarshalJSON), for log pipelines
+// such as Loki or ELK:
+//
+//	{"time":"...","level":"INFO","prefix":"App.DB","trace":"...","msg":"..."}
+//
+// the message arguments are joined like fmt.Sprintln into msg, the call
+// trace lands in caller, and the stack of panic/fatal messages in stack as
+// an array of `{"file":...,"line":...,"func":...}` frames (see StackFrame),
+// the innermost first, so the tools can group by the top frame.
+// like the native handler, it raises panic or terminates the program after
+// writing panic and fatal messages. as a Wrapper of other handlers it only
+// writes, the outer handler handles panic and fatal.
+func NewJSONLogHandler(w io.Writer) LogHandler {
+	return newRecordWriterHandler(w, encodeJSONRecord)
+}
+
+// JSONConfig provides the options of the JSON log handler
+type JSONConfig struct {
+	// LevelName renders the level of the records, e.g. "warning" instead of
+	// "WARN". nil means LogLevel.String.
+	LevelName func(LogLevel) string
+	// Flatten writes the nested objects as top-level keys joined by
+	// Delimiter, for the backends without nested JSON support, e.g.
+	// `{"fields":{"http.method":"GET"}}` becomes `{"fields.http.method":"GET"}`
+	// and the stack frames `{"stack.0.file":...}`. the dots of the field
+	// keys (e.g. the groups of slog) are regarded as namespaces and replaced
+	// by Delimiter as well.
+	Flatten bool
+	// Delimiter joins the flattened keys. empty means "."
+	Delimiter string
+}
+
+// NewJSONLogHandlerWithConfig creates a JSON log handler like
+// NewJSONLogHandler with the given options
+func NewJSONLogHandlerWithConfig(w io.Writer, cfg JSONConfig) LogHandler {
+	if cfg.LevelName == nil && !cfg.Flatten {
+		return NewJSONLogHandler(w)
+	}
+	levelName := cfg.LevelName
+	if levelName == nil {
+		levelName = LogLevel.String
+	}
+	delim := cfg.Delimiter
+	if delim == "" {
+		delim = "."
+	}
+	return newRecordWriterHandler(w, func(rec Record) ([]byte, error) {
+		v := rec.jsonValue(levelName(rec.Level))
+		if cfg.Flatten {
+			return encodeFlatJSON(v, delim)
+		}
+		return json.Marshal(v)
+	})
+}
+
+// NewLogfmtLogHandler creates a LogHandler which writes each log message to w
+// as logfmt `key=value` pairs per line, with the keys of the JSON encoding:
+//
+//	time="..." level=INFO prefix=App.DB trace=... msg="..."
+//
+// the header fields are written as their own keys before msg. like
+// NewJSONLogHandler, it raises panic or terminates the program after writing
+// panic and fatal messages.
+func NewLogfmtLogHandler(w io.Writer) LogHandler {
+	return newRecordWriterHandler(w, encodeLogfmtRecord)
+}
+
+// LogfmtConfig provides the options of the logfmt log handler
+type LogfmtConfig struct {
+	// LokiCompatible enforces the stricter rules of the logfmt parser of
+	// Grafana Loki: a key is written once with its last value (the metadata
+	// over the header fields), the header fields and metadata are sorted by
+	// key, the characters which end a key early are replaced by '_', and
+	// the values with '=', quotes, spaces or control characters are quoted.
+	LokiCompatible bool
+}
+
+// NewLogfmtLogHandlerWithConfig creates a logfmt log handler like
+// NewLogfmtLogHandler with the given options
+func NewLogfmtLogHandlerWithConfig(w io.Writer, cfg LogfmtConfig) LogHandler {
+	if !cfg.LokiCompatible {
+		return NewLogfmtLogHandler(w)
+	}
+	return newRecordWriterHandler(w, encodeLokiLogfmtRecord)
+}
+
+// encodeFlatJSON encodes the record as a JSON object without nesting, see
+// JSONConfig.Flatten. the keys are in the order of the nested encoding, the
+// fields and metadata sorted by key.
+func encodeFlatJSON(v recordJSON, delim string) ([]byte, error) {
+	buf := []byte{'{'}
+	write := func(key string, value any) {
+		// the values are strings and numbers, which always encode
+		k, _ := json.Marshal(key)
+		data, _ := json.Marshal(value)
+		if len(buf) > 1 {
+			buf = append(buf, ',')
+		}
+		buf = append(append(append(buf, k...), ':'), data...)
+	}
+	writeOpt := func(key, value string) {
+		if value != "" {
+			write(key, value)
+		}
+	}
+	writeMap := func(name string, m map[string]string) {
+		for _, k := range slices.Sorted(maps.Keys(m)) {
+			write(name+delim+strings.ReplaceAll(k, ".", delim), m[k])
+		}
+	}
+	writeOpt("time", v.Time)
+	write("level", v.Level)
+	writeOpt("prefix", v.Prefix)
+	writeOpt("trace_name", v.TraceName)
+	writeOpt("trace", v.TraceID)
+	writeOpt("caller", v.Caller)
+	for i, f := range v.Stack {
+		pfx := "stack" + delim + strconv.Itoa(i) + delim
+		write(pfx+"file", f.File)
+		write(pfx+"line", f.Line)
+		write(pfx+"func", f.Func)
+	}
+	writeMap("fields", v.Fields)
+	write("msg", v.Message)
+	writeOpt("error_verbose", v.ErrorVerb)
+	writeMap("meta", v.Meta)
+	return append(buf, '}'), nil
+}
+
+// encodeJSONRecord encodes the record as a JSON object
+func encodeJSONRecord(rec Record) ([]byte, error) {
+	return json.Marshal(rec)
+}
+
+// logfmtKeys are the keys of the record written by encodeLogfmtRecord
+var logfmtKeys = []string{
+	"time", "level", "prefix", "trace_name", "trace", "caller", "msg",
+	"error_verbose", "stack",
+}
+
+// encodeLogfmtRecord encodes the record as logfmt `key=value` pairs, using
+// the same keys as the JSON encoding. the header fields are placed before
+// msg, values are quoted if needed. the header fields and metadata named
+// like a key of the record are prefixed by "fields." or "meta.", e.g.
+// `fields.level=x`, like their path in the JSON encoding.
+func encodeLogfmtRecord(rec Record) ([]byte, error) {
+	return encodeLogfmt(rec, false)
+}
+
+// encodeLokiLogfmtRecord encodes the record like encodeLogfmtRecord with the
+// stricter rules of LogfmtConfig.LokiCompatible
+func encodeLokiLogfmtRecord(rec Record) ([]byte, error) {
+	return encodeLogfmt(rec, true)
+}
+
+// encodeLogfmt encodes the record as logfmt, see encodeLogfmtRecord and
+// LogfmtConfig.LokiCompatible
+func encodeLogfmt(rec Record, loki bool) ([]byte, error) {
+	quote := quoteFieldValue
+	if loki {
+		quote = quoteLokiValue
+	}
+	sb := strings.Builder{}
+	write := func(key, value string) {
+		if sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(key)
+		sb.WriteByte('=')
+		sb.WriteString(quote(value))
+	}
+	writeOpt := func(key, value string) {
+		if value != "" {
+			write(key, value)
+		}
+	}
+	writeOpt("time", rec.Time)
+	write("level", rec.Level.String())
+	writeOpt("prefix", rec.Prefix)
+	writeOpt("trace_name", rec.TraceName)
+	writeOpt("trace", rec.TraceID)
+	writeOpt("caller", rec.Caller)
+	fieldKey := func(group, key string) string {
+		if loki {
+			key = lokiKey(key)
+		}
+		if slices.Contains(logfmtKeys, key) {
+			return group + "." + key
+		}
+		return key
+	}
+	if loki {
+		// the last value of a key wins, the metadata over the fields
+		values := map[string]string{}
+		for _, f := range rec.Fields {
+			values[fieldKey("fields", f.Key)] = f.Value
+		}
+		for _, f := range rec.Meta {
+			values[fieldKey("meta", f.Key)] = f.Value
+		}
+		for _, k := range slices.Sorted(maps.Keys(values)) {
+			write(k, values[k])
+		}
+	} else {
+		for _, f := range rec.Fields {
+			write(fieldKey("fields", f.Key), f.Value)
+		}
+		for _, f := range rec.Meta {
+			write(fieldKey("meta", f.Key), f.Value)
+		}
+	}
+	write("msg", rec.Message)
+	writeOpt("error_verbose", rec.ErrorVerbose)
+	if len(rec.Stack) > 0 {
+		write("stack", strings.Join(rec.Stack, "\n"))
+	}
+	return []byte(sb.String()), nil
+}
+
+// lokiKey replaces the characters which would end a logfmt key early
+// (spaces, '=' and quotes) and the control characters by '_'
+func lokiKey(key string) string {
+	if key == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r == '=' || r == '"' || unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+// quoteLokiValue quotes the value if it is empty or contains '=', quotes,
+// backslashes, spaces or non-printable characters
+func quoteLokiValue(s string) string {
+	special := func(r rune) bool {
+		return r == '=' || r == '"' || r == '\\' ||
+			unicode.IsSpace(r) || !unicode.IsPrint(r)
+	}
+	if s == "" || !utf8.ValidString(s) || strings.ContainsFunc(s, special) {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,187 @@
+package nekomimi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestJSONLogHandler(t *testing.T) {
+	Convey("JSON log handler tests", t, func() {
+		buf := &bytes.Buffer{}
+		decode := func() []map[string]any {
+			var recs []map[string]any
+			for _, line := range strings.Split(
+				strings.TrimSuffix(buf.String(), "\n"), "\n") {
+				rec := map[string]any{}
+				So(json.Unmarshal([]byte(line), &rec), ShouldBeNil)
+				recs = append(recs, rec)
+			}
+			return recs
+		}
+
+		Convey("One object per line with separate fields", func() {
+			l := New("App", LogConfig{
+				Handler:        NewJSONLogHandler(buf),
+				LevelWithTrace: WARN,
+				TimeFormat:     "15:04:05",
+			})
+			l.Derive("DB").Inf("connected", 3, "pools")
+			tl := l.Trace("REQ")
+			tl.War("slow query")
+			recs := decode()
+			So(len(recs), ShouldEqual, 2)
+			So(recs[0]["level"], ShouldEqual, "INFO")
+			So(recs[0]["prefix"], ShouldEqual, "App.DB")
+			So(recs[0]["msg"], ShouldEqual, "connected 3 pools")
+			So(len(recs[0]["time"].(string)), ShouldEqual, 8)
+			So(recs[0], ShouldNotContainKey, "caller")
+			So(recs[1]["trace"], ShouldEqual, tl.TraceID())
+			So(recs[1]["trace_name"], ShouldEqual, "REQ")
+			So(recs[1]["caller"], ShouldContainSubstring, "loghnd_format_test.go:")
+		})
+
+		Convey("Panic is written with stack, then raised", func() {
+			l := New("App", LogConfig{Handler: NewJSONLogHandler(buf)})
+			So(func() { l.Panic("boom") }, ShouldPanicWith, "boom\n")
+			recs := decode()
+			So(len(recs), ShouldEqual, 1)
+			So(recs[0]["level"], ShouldEqual, "PANIC")
+			So(recs[0]["msg"], ShouldEqual, "boom")
+			stack, ok := recs[0]["stack"].([]any)
+			So(ok, ShouldBeTrue)
+			So(stack, ShouldNotBeEmpty)
+			top := stack[0].(map[string]any)
+			So(top["file"], ShouldEndWith, "/loghnd_format_test.go")
+			So(top["line"], ShouldBeGreaterThan, 0)
+			So(top["func"], ShouldStartWith,
+				"github.com/fiathux/nekomimi.TestJSONLogHandler.")
+		})
+
+		Convey("Works as Wrapper of another handler", func() {
+			handler := NewNativeLogHandlerWithConfig(context.Background(),
+				NativeConfig{Stdout: io.Discard, Stderr: io.Discard},
+				NewJSONLogHandler(buf))
+			l := New("App", LogConfig{
+				Handler:        handler,
+				LevelWithTrace: FATAL,
+			})
+			l.Err("wrapped")
+			l.GetWriter(INFO, false).WriteString("by writer")
+			So(func() { l.Panic("outer panics") }, ShouldPanic)
+			recs := decode()
+			So(len(recs), ShouldEqual, 3)
+			So(recs[0]["level"], ShouldEqual, "ERROR")
+			So(recs[0]["msg"], ShouldEqual, "wrapped")
+			So(recs[1]["msg"], ShouldEqual, "by writer")
+			So(recs[2]["level"], ShouldEqual, "PANIC")
+		})
+
+		Convey("Level names are per handler", func() {
+			console := &bytes.Buffer{}
+			syslog := &bytes.Buffer{}
+			handler := NewNativeLogHandlerWithConfig(context.Background(),
+				NativeConfig{Stdout: console},
+				NewNativeLogHandlerWithConfig(context.Background(),
+					NativeConfig{
+						Stdout: syslog,
+						LevelName: func(level LogLevel) string {
+							return map[LogLevel]string{WARN: "4", ERROR: "3"}[level]
+						},
+					},
+					NewJSONLogHandlerWithConfig(buf, JSONConfig{
+						LevelName: func(level LogLevel) string {
+							if level == WARN {
+								return "warning"
+							}
+							return strings.ToLower(level.String())
+						},
+					})))
+			l := New("App", LogConfig{Handler: handler, TestMode: true})
+			l.War("disk low")
+			So(console.String(), ShouldEqual,
+				"2000-01-01 00:00:00.000 [WARN], App - disk low\n")
+			So(syslog.String(), ShouldEqual,
+				"2000-01-01 00:00:00.000 [4], App - disk low\n")
+			recs := decode()
+			So(recs[0]["level"], ShouldEqual, "warning")
+			So(recs[0]["msg"], ShouldEqual, "disk low")
+		})
+
+		Convey("Flatten writes nested fields as top-level keys", func() {
+			l := New("App", LogConfig{
+				Handler:  NewJSONLogHandlerWithConfig(buf, JSONConfig{Flatten: true}),
+				TestMode: true,
+			})
+			slog.New(AsSlogHandler(l)).WithGroup("http").
+				Info("done", slog.Group("request", "method", "GET"))
+			l.WithMeta("schema", 2).Inf("meta")
+			So(buf.String(), ShouldStartWith, `{"time":"2000-01-01 00:00:00.000",`+
+				`"level":"INFO","prefix":"App","fields.http.request.method":"GET",`+
+				`"msg":"done"}`+"\n")
+			recs := decode()
+			So(recs[1]["meta.schema"], ShouldEqual, "2")
+			So(recs[1], ShouldNotContainKey, "meta")
+		})
+
+		Convey("Flatten with a delimiter also flattens the stack", func() {
+			l := New("App", LogConfig{
+				Handler: NewJSONLogHandlerWithConfig(buf, JSONConfig{
+					Flatten: true, Delimiter: "_",
+				}),
+			})
+			So(func() { l.Panic("boom") }, ShouldPanic)
+			recs := decode()
+			So(recs[0], ShouldNotContainKey, "stack")
+			So(recs[0]["stack_0_file"], ShouldEndWith, "/loghnd_format_test.go")
+			So(recs[0]["stack_0_line"], ShouldBeGreaterThan, 0)
+		})
+	})
+}
+
+func TestLogfmtLogHandler(t *testing.T) {
+	Convey("Logfmt log handler tests", t, func() {
+		buf := &bytes.Buffer{}
+		newLogger := func(cfg LogfmtConfig) Logger {
+			return New("App", LogConfig{
+				Handler:  NewLogfmtLogHandlerWithConfig(buf, cfg),
+				TestMode: true,
+			})
+		}
+		const head = `time="2000-01-01 00:00:00.000" level=INFO prefix=App `
+
+		Convey("Basic handler keeps the fields as they come", func() {
+			l := newLogger(LogfmtConfig{})
+			slog.New(AsSlogHandler(l)).Info("done", "user", "a", "user", "b")
+			So(buf.String(), ShouldEqual,
+				head+"user=a user=b msg=done\n")
+		})
+
+		Convey("Loki compatible handler dedups keys, last wins", func() {
+			l := newLogger(LogfmtConfig{LokiCompatible: true})
+			slog.New(AsSlogHandler(l)).Info("done",
+				"user", "a", "zone", "z", "user", "b", "id", 1)
+			slog.New(AsSlogHandler(l.WithMeta("user", "c"))).
+				Info("meta", "user", "a")
+			So(buf.String(), ShouldEqual,
+				head+"id=1 user=b zone=z msg=done\n"+
+					head+"user=c msg=meta\n")
+		})
+
+		Convey("Loki compatible handler quotes special characters", func() {
+			l := newLogger(LogfmtConfig{LokiCompatible: true})
+			slog.New(AsSlogHandler(l)).Info(`say "hi"`,
+				"eq", "a=b", "sp", "two words", "q", `x"y`, "bs", `c:\tmp`,
+				"empty", "", "my key", "v", "level", "x")
+			So(buf.String(), ShouldEqual, head+`bs="c:\\tmp" empty="" `+
+				`eq="a=b" fields.level=x my_key=v q="x\"y" sp="two words" `+
+				`msg="say \"hi\""`+"\n")
+		})
+	})
+}
@@ -0,0 +1,110 @@
+package nekomimi
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// NewGzipFileLogHandler creates a new LogHandler that writes logs to a
+// gzip-compressed file, like NewFileAccessorLogHandler. the gzip buffer is
+// flushed on the same periodic tick (every 2 seconds), so the file can be
+// read by `zcat` while it's written. an existing file is appended as a new
+// gzip member, which the gzip readers concatenate.
+//
+// when ctx is done, the gzip stream is finalized (the trailer is written)
+// and the file is closed. writes, flushes and the finalization are
+// serialized, so the trailer is never written in the middle of a message,
+// the following messages are dropped. the handler implements Flusher and
+// io.Closer, Close finalizes the stream immediately.
+//
+// the handler doesn't rotate, handlers/filerotate compresses the rotated
+// archives for long-running daemons.
+// ctx is the context for file lifecycle management.
+func NewGzipFileLogHandler(
+	ctx context.Context, path string,
+) (LogHandler, error) {
+	countwrt := atomic.Uint64{}
+	var lastflush uint64 = 0
+	fplock := &sync.Mutex{}
+	fp, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	gz := gzip.NewWriter(fp)
+	closed := atomic.Bool{}
+
+	// flush the gzip buffer to the file
+	flush := func() error {
+		fplock.Lock()
+		defer fplock.Unlock()
+		if closed.Load() {
+			return nil
+		}
+		c := countwrt.Load()
+		if c == lastflush {
+			return nil
+		}
+		lastflush = c
+		if err := gz.Flush(); err != nil {
+			return err
+		}
+		return fp.Sync()
+	}
+
+	// finalize the gzip stream and close the file
+	finalize := func() error {
+		fplock.Lock()
+		defer fplock.Unlock()
+		if closed.Swap(true) {
+			return nil
+		}
+		return errors.Join(gz.Close(), fp.Close())
+	}
+
+	// tiny log handler function
+	handler := func(level LogLevel, pnt func(io.StringWriter)) {
+		fplock.Lock()
+		defer fplock.Unlock()
+		if closed.Load() {
+			return
+		}
+		pnt(stringWriter{w: gz})
+		countwrt.Add(1)
+	}
+
+	// file holder thread
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				finalize()
+				return
+			case <-time.After(2 * time.Second):
+				flush() // periodic flush
+			}
+		}
+	}()
+
+	return &LogHandlerFunc{
+		RegularLogFunc: handler,
+		PanicLogFunc: func(
+			pnt func(io.StringWriter), info string,
+		) func() {
+			handler(PANIC, pnt)
+			return nil
+		},
+		FatalLogFunc: func(pnt func(io.StringWriter)) func() {
+			handler(FATAL, pnt)
+			return nil
+		},
+		IsShutdownFunc: closed.Load,
+		FlushFunc:      flush,
+		CloseFunc:      finalize,
+	}, nil
+}
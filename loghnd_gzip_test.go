@@ -0,0 +1,85 @@
+package nekomimi
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGzipFileLogHandler(t *testing.T) {
+	Convey("Gzip file handler tests", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		path := filepath.Join(t.TempDir(), "app.log.gz")
+		// readAll decompresses the file. strict requires the complete
+		// stream with the trailer.
+		readAll := func(strict bool) (string, error) {
+			fp, err := os.Open(path)
+			if err != nil {
+				return "", err
+			}
+			defer fp.Close()
+			zr, err := gzip.NewReader(fp)
+			if err != nil {
+				return "", err
+			}
+			data, err := io.ReadAll(zr)
+			if !strict && err == io.ErrUnexpectedEOF {
+				err = nil
+			}
+			return string(data), err
+		}
+		newLogger := func(h LogHandler) Logger {
+			return New("App", LogConfig{
+				Handler:  &LogHandlerFunc{Wrapper: h},
+				TestMode: true,
+			})
+		}
+
+		Convey("Flush makes the data readable, cancel finalizes", func() {
+			h, err := NewGzipFileLogHandler(ctx, path)
+			So(err, ShouldBeNil)
+			l := newLogger(h)
+			l.Inf("first")
+			l.War("second")
+			So(h.(Flusher).Flush(), ShouldBeNil)
+			data, err := readAll(false)
+			So(err, ShouldBeNil)
+			So(data, ShouldEqual,
+				"2000-01-01 00:00:00.000 [INFO], App - first\n"+
+					"2000-01-01 00:00:00.000 [WARN], App - second\n")
+			_, err = readAll(true)
+			So(err, ShouldNotBeNil) // no trailer yet
+
+			cancel()
+			So(waitFor(h.IsShutdown), ShouldBeTrue)
+			l.Inf("dropped")
+			data, err = readAll(true)
+			So(err, ShouldBeNil)
+			So(data, ShouldNotContainSubstring, "dropped")
+		})
+
+		Convey("Close finalizes and reopening appends a member", func() {
+			h, err := NewGzipFileLogHandler(ctx, path)
+			So(err, ShouldBeNil)
+			newLogger(h).Inf("one")
+			So(h.(io.Closer).Close(), ShouldBeNil)
+			So(h.(io.Closer).Close(), ShouldBeNil)
+
+			h, err = NewGzipFileLogHandler(ctx, path)
+			So(err, ShouldBeNil)
+			newLogger(h).Inf("two")
+			So(h.(io.Closer).Close(), ShouldBeNil)
+			data, err := readAll(true)
+			So(err, ShouldBeNil)
+			So(data, ShouldEqual,
+				"2000-01-01 00:00:00.000 [INFO], App - one\n"+
+					"2000-01-01 00:00:00.000 [INFO], App - two\n")
+		})
+	})
+}
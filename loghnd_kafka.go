@@ -0,0 +1,75 @@
+package nekomimi
+
+import (
+	"encoding/json"
+	"sync/atomic"
+)
+
+// kafkaHandler is the LogHandler returned by NewKafkaLogHandler
+type kafkaHandler struct {
+	RecordLogHandlerFunc
+	dropped atomic.Uint64
+}
+
+// NewKafkaLogHandler creates a new LogHandler that serializes each log
+// message as a JSON Record and publishes it via the producer callback.
+// the Kafka client is kept out of this package, producer should send the
+// value to the topic with the given key as partition key.
+//
+// keyFn selects the partition key of a record. if nil, the trace ID is used,
+// so all logs of a trace land on one partition. records without trace ID
+// get a nil key (which usually means round-robin partitioning).
+//
+// a record is dropped if producer returns error, the returned handler
+// implements DropCounter. like RecordLogHandlerFunc, the handler never
+// raises panic or terminates the program, it should be used as Wrapper of
+// other handlers.
+func NewKafkaLogHandler(
+	producer func(key, value []byte) error,
+	keyFn func(Record) []byte,
+) LogHandler {
+	return NewKafkaLogHandlerWithRetry(producer, keyFn, RetryPolicy{})
+}
+
+// NewKafkaLogHandlerWithRetry is like NewKafkaLogHandler, but a failed
+// record is retried according to the retry policy before being dropped.
+// the retries are performed synchronously in the log call.
+func NewKafkaLogHandlerWithRetry(
+	producer func(key, value []byte) error,
+	keyFn func(Record) []byte,
+	retry RetryPolicy,
+) LogHandler {
+	if keyFn == nil {
+		keyFn = kafkaTraceKey
+	}
+	h := &kafkaHandler{}
+	h.RecordLogHandlerFunc = func(rec Record) {
+		value, err := json.Marshal(rec)
+		if err != nil {
+			h.dropped.Add(1) // marshal failure, drop log
+			return
+		}
+		key := keyFn(rec)
+		err = retry.Run(func(int) error {
+			return producer(key, value)
+		})
+		if err != nil {
+			h.dropped.Add(1)
+		}
+	}
+	return h
+}
+
+// Dropped returns the number of records dropped so far
+func (h *kafkaHandler) Dropped() uint64 {
+	return h.dropped.Load()
+}
+
+// kafkaTraceKey is the default partition key selector of
+// NewKafkaLogHandler
+func kafkaTraceKey(rec Record) []byte {
+	if rec.TraceID == "" {
+		return nil
+	}
+	return []byte(rec.TraceID)
+}
@@ -0,0 +1,52 @@
+package nekomimi
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestKafkaLogHandler(t *testing.T) {
+	Convey("Kafka log handler tests", t, func() {
+		type message struct {
+			key   []byte
+			value []byte
+		}
+		var sent []message
+		producer := func(key, value []byte) error {
+			sent = append(sent, message{key, value})
+			return nil
+		}
+
+		Convey("Partition key defaults to trace ID", func() {
+			l := New("App", LogConfig{
+				Handler: NewKafkaLogHandler(producer, nil),
+			})
+			tl := l.Trace("REQ")
+			tl.Inf("first")
+			tl.Err("second")
+			l.Inf("no trace")
+			So(len(sent), ShouldEqual, 3)
+			So(string(sent[0].key), ShouldEqual, tl.TraceID())
+			So(string(sent[1].key), ShouldEqual, tl.TraceID())
+			So(sent[2].key, ShouldBeNil)
+			rec := map[string]any{}
+			So(json.Unmarshal(sent[1].value, &rec), ShouldBeNil)
+			So(rec["level"], ShouldEqual, "ERROR")
+			So(rec["trace"], ShouldEqual, tl.TraceID())
+			So(rec["msg"], ShouldEqual, "second")
+		})
+
+		Convey("Custom key selector", func() {
+			l := New("App", LogConfig{
+				Handler: NewKafkaLogHandler(producer, func(r Record) []byte {
+					return []byte(r.Prefix)
+				}),
+			})
+			l.Derive("DB").War("slow query")
+			So(len(sent), ShouldEqual, 1)
+			So(string(sent[0].key), ShouldEqual, "App.DB")
+		})
+	})
+}
@@ -0,0 +1,132 @@
+package nekomimi
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Entry is a log message captured by MemoryLog
+type Entry struct {
+	// log level of the message
+	Level LogLevel
+	// log header, as passed to the handler, e.g.
+	// `2026-01-01 10:00:00.000 [INFO], App - `
+	Header string
+	// formatted message body, without the trailing newline
+	Message string
+}
+
+// MemoryLog holds the messages captured by the handler of NewMemoryHandler.
+// it's safe to read while logging happens.
+type MemoryLog struct {
+	mtx     sync.Mutex
+	entries []Entry
+}
+
+// memoryHandler is the LogHandler returned by NewMemoryHandler
+type memoryHandler struct {
+	log *MemoryLog
+}
+
+// NewMemoryHandler creates a LogHandler which captures the log messages in
+// memory, for asserting on the log output in tests. panic and fatal
+// messages are captured as well, but neither panic nor terminate the
+// program.
+func NewMemoryHandler() (LogHandler, *MemoryLog) {
+	ml := &MemoryLog{}
+	return &memoryHandler{log: ml}, ml
+}
+
+// splitHeader splits a formatted log line into the header (up to the ` - `
+// after the level tag) and the body. the header is empty if it's not
+// recognizable.
+func splitHeader(line string) (string, string) {
+	line = strings.TrimSuffix(line, "\n")
+	if _, rb, ok := findLevelTag(line); ok {
+		if idx := strings.Index(line[rb:], " - "); idx >= 0 {
+			return line[:rb+idx+3], line[rb+idx+3:]
+		}
+	}
+	return "", line
+}
+
+func (ml *MemoryLog) add(level LogLevel, header string, body string) {
+	ml.mtx.Lock()
+	defer ml.mtx.Unlock()
+	ml.entries = append(ml.entries, Entry{
+		Level:   level,
+		Header:  header,
+		Message: strings.TrimSuffix(body, "\n"),
+	})
+}
+
+// Entries returns a copy of the captured messages in logging order
+func (ml *MemoryLog) Entries() []Entry {
+	ml.mtx.Lock()
+	defer ml.mtx.Unlock()
+	return slices.Clone(ml.entries)
+}
+
+// Len returns the number of the captured messages
+func (ml *MemoryLog) Len() int {
+	ml.mtx.Lock()
+	defer ml.mtx.Unlock()
+	return len(ml.entries)
+}
+
+// Contains reports whether a captured message of the level contains substr
+// in its body
+func (ml *MemoryLog) Contains(level LogLevel, substr string) bool {
+	ml.mtx.Lock()
+	defer ml.mtx.Unlock()
+	return slices.ContainsFunc(ml.entries, func(e Entry) bool {
+		return e.Level == level && strings.Contains(e.Message, substr)
+	})
+}
+
+// Reset forgets all the captured messages
+func (ml *MemoryLog) Reset() {
+	ml.mtx.Lock()
+	defer ml.mtx.Unlock()
+	ml.entries = nil
+}
+
+// Replay passes the captured messages to dst in logging order, with their
+// level and header, e.g. to test a new handler against the messages of a
+// realistic run. PANIC and FATAL messages are replayed by RegularLog as
+// well, so dst neither panics nor terminates the program.
+func (ml *MemoryLog) Replay(dst LogHandler) {
+	for _, e := range ml.Entries() {
+		dst.RegularLog(e.Level, e.Header, e.Message)
+	}
+}
+
+func (mh *memoryHandler) IsShutdown() bool {
+	return false
+}
+
+func (mh *memoryHandler) RegularWriter(
+	level LogLevel, pnt func(io.StringWriter),
+) {
+	sb := strings.Builder{}
+	pnt(&sb)
+	header, body := splitHeader(sb.String())
+	mh.log.add(level, header, body)
+}
+
+func (mh *memoryHandler) RegularLog(
+	level LogLevel, header string, message ...any,
+) {
+	mh.log.add(level, header, fmt.Sprintln(message...))
+}
+
+func (mh *memoryHandler) PanicLog(header string, message ...any) {
+	mh.log.add(PANIC, header, fmt.Sprintln(message...))
+}
+
+func (mh *memoryHandler) FatalLog(header string, message ...any) {
+	mh.log.add(FATAL, header, fmt.Sprintln(message...))
+}
@@ -0,0 +1,105 @@
+package nekomimi
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMemoryHandler(t *testing.T) {
+	Convey("Memory handler tests", t, func() {
+		h, ml := NewMemoryHandler()
+		l := New("App", LogConfig{Handler: h, TestMode: true})
+
+		Convey("Messages are captured as entries", func() {
+			l.Inf("hello", 42)
+			l.Derive("DB").Errf("query %s failed", "q1")
+			l.Panic("boom")
+			l.Fatal("bye")
+			So(ml.Entries(), ShouldResemble, []Entry{
+				{
+					Level:   INFO,
+					Header:  "2000-01-01 00:00:00.000 [INFO], App - ",
+					Message: "hello 42",
+				},
+				{
+					Level:   ERROR,
+					Header:  "2000-01-01 00:00:00.000 [ERROR], App.DB - ",
+					Message: "query q1 failed",
+				},
+				{
+					Level:   PANIC,
+					Header:  "2000-01-01 00:00:00.000 [PANIC], App - ",
+					Message: "boom",
+				},
+				{
+					Level:   FATAL,
+					Header:  "2000-01-01 00:00:00.000 [FATAL], App - ",
+					Message: "bye",
+				},
+			})
+			So(ml.Contains(ERROR, "q1"), ShouldBeTrue)
+			So(ml.Contains(INFO, "q1"), ShouldBeFalse)
+
+			ml.Reset()
+			So(ml.Len(), ShouldEqual, 0)
+			So(ml.Entries(), ShouldBeEmpty)
+		})
+
+		Convey("Writer messages are split", func() {
+			l.GetWriter(WARN, false).WriteString("from writer")
+			So(ml.Entries(), ShouldResemble, []Entry{{
+				Level:   WARN,
+				Header:  "2000-01-01 00:00:00.000 [WARN], App - ",
+				Message: "from writer",
+			}})
+		})
+
+		Convey("Captured messages are replayed into another handler", func() {
+			l.Inf("hello", 42)
+			l.Derive("DB").Warf("slow query %s", "q1")
+			l.Trace("REQ").Err("failed")
+			l.Panic("boom")
+
+			dst, replayed := NewMemoryHandler()
+			ml.Replay(dst)
+			So(replayed.Entries(), ShouldResemble, ml.Entries())
+
+			// a structured sink parses the replayed headers
+			buf := &bytes.Buffer{}
+			ml.Replay(NewJSONLogHandler(buf))
+			lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+			So(len(lines), ShouldEqual, 4)
+			rec := map[string]any{}
+			So(json.Unmarshal([]byte(lines[1]), &rec), ShouldBeNil)
+			So(rec["level"], ShouldEqual, "WARN")
+			So(rec["prefix"], ShouldEqual, "App.DB")
+			So(rec["msg"], ShouldEqual, "slow query q1")
+			So(json.Unmarshal([]byte(lines[3]), &rec), ShouldBeNil)
+			So(rec["level"], ShouldEqual, "PANIC")
+		})
+
+		Convey("Reading while logging", func() {
+			wg := sync.WaitGroup{}
+			for range 4 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for range 100 {
+						l.Dbg("concurrent")
+						ml.Contains(DEBUG, "concurrent")
+					}
+				}()
+			}
+			for ml.Len() < 400 {
+				_ = ml.Entries()
+			}
+			wg.Wait()
+			So(ml.Len(), ShouldEqual, 400)
+		})
+	})
+}
@@ -0,0 +1,286 @@
+package nekomimi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// netBufferLines is the number of lines buffered by the network handler
+// while disconnected
+const netBufferLines = 1024
+
+// netBackoffMin and netBackoffMax bound the delay between the reconnection
+// attempts of the network handler, replaced in tests
+var (
+	netBackoffMin = 100 * time.Millisecond
+	netBackoffMax = 30 * time.Second
+)
+
+// netWriteTimeout is the write deadline of a line sent by the network
+// handler
+const netWriteTimeout = 2 * time.Second
+
+// errNetworkDisconnected is returned by the network handler's Flush if the
+// buffered lines can't be sent
+var errNetworkDisconnected = errors.New("nekomimi: network log connection is lost")
+
+// networkHandler is the LogHandler returned by NewNetworkLogHandler
+type networkHandler struct {
+	LogHandlerFunc
+	writeErrors
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	done    chan struct{} // closed when the background loop exits
+	network string
+	addr    string
+	retry   *RetryPolicy // bounds the reconnection, nil retries forever
+
+	mtx     sync.Mutex
+	conn    net.Conn
+	pending []string      // lines buffered while disconnected, oldest first
+	lost    chan struct{} // signals the background loop to reconnect
+
+	dropped  atomic.Uint64
+	shutdown atomic.Bool
+}
+
+// NewNetworkLogHandler creates a new LogHandler that writes each formatted
+// log line to a network connection, e.g. a TCP or UDP socket of a remote
+// collector. network and addr are passed to net.Dial, the initial dial
+// must succeed.
+//
+// when a write fails the connection is dropped and redialed in the
+// background, with an exponential backoff from 100ms up to 30s. up to 1024
+// lines are buffered while disconnected and sent in order once the
+// connection is back. when the buffer is full during an outage the oldest
+// buffered line is discarded for each new one, so the most recent lines
+// survive; the discarded lines are counted, see DropCounter.
+//
+// ctx is the context for the connection lifecycle, like the file handler:
+// when it's done the buffered lines are discarded (and counted), the
+// connection is closed and the handler is shut down. like the native
+// handler, PANIC messages raise panic and FATAL messages terminate the
+// program after being written (or buffered). the handler implements
+// Flusher and io.Closer, Close sends the buffered lines if possible, then
+// shuts down the handler without waiting for ctx. the failed writes which
+// drop the connection are reported, see WriteErrorReporter.
+//
+// unlike handlers/netlog, which retries each message synchronously and
+// drops it after RetryPolicy, the lines are never retried in the log call:
+// they are buffered and the connection is redialed in the background, so
+// an outage doesn't add latency to the callers. see
+// NewNetworkLogHandlerWithRetry to bound the retries by a RetryPolicy.
+func NewNetworkLogHandler(
+	ctx context.Context, network, addr string,
+) (LogHandler, error) {
+	return newNetworkLogHandler(ctx, network, addr, nil)
+}
+
+// NewNetworkLogHandlerWithRetry is like NewNetworkLogHandler, but the
+// reconnection follows the retry policy shared with the other network
+// handlers: the collector is redialed up to retry.MaxAttempts times,
+// retry.Backoff apart (the exponential backoff if zero). when the attempts
+// are exhausted, the buffered lines are dropped and counted like the
+// messages of handlers/netlog, then the handler keeps redialing for the
+// following lines with the same policy.
+func NewNetworkLogHandlerWithRetry(
+	ctx context.Context, network, addr string, retry RetryPolicy,
+) (LogHandler, error) {
+	return newNetworkLogHandler(ctx, network, addr, &retry)
+}
+
+func newNetworkLogHandler(
+	ctx context.Context, network, addr string, retry *RetryPolicy,
+) (LogHandler, error) {
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	h := &networkHandler{
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		network: network,
+		addr:    addr,
+		retry:   retry,
+		conn:    conn,
+		lost:    make(chan struct{}, 1),
+	}
+	h.LogHandlerFunc = LogHandlerFunc{
+		Lock:           &sync.Mutex{},
+		RegularLogFunc: h.output,
+		PanicLogFunc: func(
+			pnt func(io.StringWriter), info string,
+		) func() {
+			h.output(PANIC, pnt)
+			return func() {
+				panic(info)
+			}
+		},
+		FatalLogFunc: func(pnt func(io.StringWriter)) func() {
+			h.output(FATAL, pnt)
+			return sysTerminate
+		},
+		IsShutdownFunc: h.shutdown.Load,
+	}
+	go h.bgLoop()
+	return h, nil
+}
+
+// Dropped returns the number of lines discarded while disconnected
+func (h *networkHandler) Dropped() uint64 {
+	return h.dropped.Load()
+}
+
+// Flush sends the buffered lines if the connection is available
+func (h *networkHandler) Flush() error {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	for h.conn != nil && len(h.pending) > 0 {
+		if !h.write(h.pending[0]) {
+			return errNetworkDisconnected
+		}
+		h.pending = h.pending[1:]
+	}
+	if len(h.pending) > 0 {
+		return errNetworkDisconnected
+	}
+	return nil
+}
+
+// Close shuts down the handler synchronously, like the cancellation of
+// ctx. the buffered lines are sent first if the connection is available.
+func (h *networkHandler) Close() error {
+	err := h.Flush()
+	h.cancel()
+	<-h.done
+	return err
+}
+
+// output writes a log line to the connection, or buffers it while
+// disconnected
+func (h *networkHandler) output(level LogLevel, pnt func(io.StringWriter)) {
+	sb := strings.Builder{}
+	pnt(&sb)
+	line := sb.String()
+
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	if h.shutdown.Load() {
+		h.dropped.Add(1)
+		return
+	}
+	if h.conn != nil && h.write(line) {
+		return
+	}
+	h.buffer(line)
+}
+
+// write sends a line, the connection is dropped on failure. must be called
+// with mtx held.
+func (h *networkHandler) write(line string) bool {
+	h.conn.SetWriteDeadline(time.Now().Add(netWriteTimeout))
+	if _, err := io.WriteString(h.conn, line); err != nil {
+		h.report(err)
+		h.conn.Close()
+		h.conn = nil
+		select {
+		case h.lost <- struct{}{}:
+		default:
+		}
+		return false
+	}
+	return true
+}
+
+// buffer appends a line to the pending lines, discarding the oldest one if
+// the buffer is full. must be called with mtx held.
+func (h *networkHandler) buffer(line string) {
+	if len(h.pending) >= netBufferLines {
+		h.pending = h.pending[1:]
+		h.dropped.Add(1)
+	}
+	h.pending = append(h.pending, line)
+}
+
+// discardPending drops and counts the buffered lines, after the reconnection
+// attempts of the retry policy are exhausted
+func (h *networkHandler) discardPending() {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.dropped.Add(uint64(len(h.pending)))
+	h.pending = nil
+}
+
+// reconnected installs a new connection and sends the pending lines. the
+// lines left by a failure stay pending.
+func (h *networkHandler) reconnected(conn net.Conn) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.conn = conn
+	for len(h.pending) > 0 {
+		if !h.write(h.pending[0]) {
+			return
+		}
+		h.pending = h.pending[1:]
+	}
+	h.pending = nil
+}
+
+// bgLoop redials the lost connection with backoff until ctx is done, then
+// closes the connection. the dial is done without the lock, so the log
+// calls are not blocked by an unreachable collector.
+func (h *networkHandler) bgLoop() {
+	defer close(h.done)
+	defer func() {
+		h.mtx.Lock()
+		defer h.mtx.Unlock()
+		if h.conn != nil {
+			h.conn.Close()
+			h.conn = nil
+		}
+		h.dropped.Add(uint64(len(h.pending)))
+		h.pending = nil
+		h.shutdown.Store(true)
+	}()
+	for {
+		select {
+		case <-h.ctx.Done():
+			return
+		case <-h.lost:
+		}
+		backoff := netBackoffMin
+		for attempt := 1; ; attempt++ {
+			d := net.Dialer{Timeout: netWriteTimeout}
+			conn, err := d.DialContext(h.ctx, h.network, h.addr)
+			if err == nil {
+				h.reconnected(conn)
+				break
+			}
+			wait := backoff
+			if h.retry != nil {
+				if attempt >= max(h.retry.MaxAttempts, 1) {
+					h.discardPending()
+					attempt = 0
+				}
+				if h.retry.Backoff > 0 {
+					wait = h.retry.Backoff
+				}
+			}
+			select {
+			case <-h.ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+			backoff = min(backoff*2, netBackoffMax)
+		}
+	}
+}
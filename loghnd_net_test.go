@@ -0,0 +1,106 @@
+package nekomimi
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNetworkLogHandler(t *testing.T) {
+	Convey("Network handler tests", t, func() {
+		oldMin := netBackoffMin
+		netBackoffMin = 10 * time.Millisecond
+		defer func() { netBackoffMin = oldMin }()
+
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		defer ln.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		h, err := NewNetworkLogHandler(ctx, "tcp", ln.Addr().String())
+		So(err, ShouldBeNil)
+		nh := h.(*networkHandler)
+		l := New("App", LogConfig{Handler: h, TestMode: true})
+		// breakConn closes the connection under the handler, so the next
+		// write fails
+		breakConn := func() {
+			nh.mtx.Lock()
+			defer nh.mtx.Unlock()
+			nh.conn.Close()
+		}
+
+		c1, err := ln.Accept()
+		So(err, ShouldBeNil)
+		defer c1.Close()
+		r1 := bufio.NewReader(c1)
+		l.Inf("one")
+		line, _ := r1.ReadString('\n')
+		So(line, ShouldEqual, "2000-01-01 00:00:00.000 [INFO], App - one\n")
+
+		Convey("Lines are buffered and sent on reconnect", func() {
+			var werr error
+			h.(WriteErrorReporter).SetOnError(func(err error) { werr = err })
+			breakConn()
+			l.Inf("two")
+			So(h.(WriteErrorReporter).WriteErrors(), ShouldEqual, 1)
+			So(werr, ShouldWrap, net.ErrClosed)
+			l.War("three")
+			c2, err := ln.Accept()
+			So(err, ShouldBeNil)
+			defer c2.Close()
+			r2 := bufio.NewReader(c2)
+			line, _ := r2.ReadString('\n')
+			So(line, ShouldEndWith, "[INFO], App - two\n")
+			line, _ = r2.ReadString('\n')
+			So(line, ShouldEndWith, "[WARN], App - three\n")
+			l.Inf("four")
+			line, _ = r2.ReadString('\n')
+			So(line, ShouldEndWith, "[INFO], App - four\n")
+			So(h.(DropCounter).Dropped(), ShouldEqual, 0)
+		})
+
+		Convey("Buffered lines are dropped after the retry policy", func() {
+			rh, err := NewNetworkLogHandlerWithRetry(ctx, "tcp",
+				ln.Addr().String(),
+				RetryPolicy{MaxAttempts: 2, Backoff: 5 * time.Millisecond})
+			So(err, ShouldBeNil)
+			rc, err := ln.Accept()
+			So(err, ShouldBeNil)
+			rc.Close()
+			ln.Close()
+			rl := New("App", LogConfig{Handler: rh, TestMode: true})
+			rnh := rh.(*networkHandler)
+			rnh.mtx.Lock()
+			rnh.conn.Close()
+			rnh.mtx.Unlock()
+			rl.Inf("lost")
+			rl.Inf("lost too")
+			So(waitFor(func() bool { return rh.(DropCounter).Dropped() == 2 }),
+				ShouldBeTrue)
+			rnh.mtx.Lock()
+			So(rnh.pending, ShouldBeEmpty)
+			rnh.mtx.Unlock()
+		})
+
+		Convey("Oldest lines are discarded on overflow", func() {
+			ln.Close()
+			breakConn()
+			for i := range netBufferLines + 6 {
+				l.Inf(fmt.Sprint("line ", i))
+			}
+			So(h.(DropCounter).Dropped(), ShouldEqual, 6)
+			nh.mtx.Lock()
+			So(nh.pending[0], ShouldEndWith, "- line 6\n")
+			nh.mtx.Unlock()
+
+			cancel()
+			So(waitFor(h.IsShutdown), ShouldBeTrue)
+			So(h.(DropCounter).Dropped(), ShouldEqual, netBufferLines+6)
+		})
+	})
+}
@@ -0,0 +1,55 @@
+package nekomimi
+
+import (
+	"encoding/binary"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// protobufHandler is the LogHandler returned by NewProtobufLogHandler
+type protobufHandler struct {
+	RecordLogHandlerFunc
+	mtx     sync.Mutex
+	dropped atomic.Uint64
+}
+
+// NewProtobufLogHandler creates a new LogHandler that serializes each log
+// message as a Record via the marshal callback, and writes it to w with a
+// length prefix. the schema and marshaler are kept out of this package,
+// so the core has no protobuf dependency.
+//
+// the length prefix is an unsigned varint, the same framing as the
+// delimited protobuf messages (e.g. protodelim in google.golang.org/protobuf),
+// each frame is written by a single Write call.
+//
+// a record is dropped if marshal or the write fails, the returned handler
+// implements DropCounter. like RecordLogHandlerFunc, the handler never
+// raises panic or terminates the program, it should be used as Wrapper of
+// other handlers.
+func NewProtobufLogHandler(
+	w io.Writer, marshal func(Record) ([]byte, error),
+) LogHandler {
+	h := &protobufHandler{}
+	h.RecordLogHandlerFunc = func(rec Record) {
+		data, err := marshal(rec)
+		if err != nil {
+			h.dropped.Add(1)
+			return
+		}
+		frame := make([]byte, 0, binary.MaxVarintLen64+len(data))
+		frame = binary.AppendUvarint(frame, uint64(len(data)))
+		frame = append(frame, data...)
+		h.mtx.Lock()
+		defer h.mtx.Unlock()
+		if _, err := w.Write(frame); err != nil {
+			h.dropped.Add(1)
+		}
+	}
+	return h
+}
+
+// Dropped returns the number of records dropped so far
+func (h *protobufHandler) Dropped() uint64 {
+	return h.dropped.Load()
+}
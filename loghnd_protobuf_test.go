@@ -0,0 +1,75 @@
+package nekomimi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// fakeMarshal encodes the record as "LEVEL|prefix|trace|message"
+func fakeMarshal(rec Record) ([]byte, error) {
+	if rec.Message == "unmarshalable" {
+		return nil, fmt.Errorf("marshal failed")
+	}
+	return []byte(strings.Join([]string{
+		rec.Level.String(), rec.Prefix, rec.TraceID, rec.Message,
+	}, "|")), nil
+}
+
+// readFrames splits the length-prefixed frames
+func readFrames(data []byte) ([]string, error) {
+	r := bytes.NewReader(data)
+	var frames []string
+	for r.Len() > 0 {
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		frames = append(frames, string(buf))
+	}
+	return frames, nil
+}
+
+// errWriter fails all writes
+type errWriter struct{}
+
+func (errWriter) Write(p []byte) (int, error) {
+	return 0, fmt.Errorf("write failed")
+}
+
+func TestProtobufLogHandler(t *testing.T) {
+	Convey("Protobuf log handler tests", t, func() {
+		Convey("Records are written length-prefixed", func() {
+			buf := &bytes.Buffer{}
+			h := NewProtobufLogHandler(buf, fakeMarshal)
+			l := New("App", LogConfig{Handler: h})
+			tl := l.Derive("DB").Trace("REQ")
+			tl.War("slow query")
+			l.Inf(strings.Repeat("x", 200)) // multi-byte length prefix
+			l.Inf("unmarshalable")
+
+			frames, err := readFrames(buf.Bytes())
+			So(err, ShouldBeNil)
+			So(frames, ShouldResemble, []string{
+				"WARN|App.DB|" + tl.TraceID() + "|slow query",
+				"INFO|App||" + strings.Repeat("x", 200),
+			})
+			So(h.(DropCounter).Dropped(), ShouldEqual, 1)
+		})
+
+		Convey("Write failure drops the record", func() {
+			h := NewProtobufLogHandler(errWriter{}, fakeMarshal)
+			New("App", LogConfig{Handler: h}).Inf("lost")
+			So(h.(DropCounter).Dropped(), ShouldEqual, 1)
+		})
+	})
+}
@@ -0,0 +1,346 @@
+package nekomimi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// sizeWriter counts the bytes written to the underlying writer
+type sizeWriter struct {
+	w    io.StringWriter
+	size *int64
+}
+
+func (sw sizeWriter) WriteString(s string) (int, error) {
+	n, err := sw.w.WriteString(s)
+	*sw.size += int64(n)
+	return n, err
+}
+
+// rotateBackups shifts the backups of path: path.N-1 to path.N ... path to
+// path.1. the oldest backup beyond maxBackups is overwritten. path is
+// removed if maxBackups is not positive.
+func rotateBackups(path string, maxBackups int) {
+	if maxBackups <= 0 {
+		os.Remove(path)
+		return
+	}
+	for i := maxBackups - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", path, i), fmt.Sprintf("%s.%d", path, i+1))
+	}
+	os.Rename(path, path+".1")
+}
+
+// pruneBackups deletes the backups of path last written before the oldest
+// time
+func pruneBackups(path string, maxBackups int, oldest time.Time) {
+	for i := 1; i <= maxBackups; i++ {
+		name := fmt.Sprintf("%s.%d", path, i)
+		if st, err := os.Stat(name); err == nil && st.ModTime().Before(oldest) {
+			os.Remove(name)
+		}
+	}
+}
+
+// sameDate reports whether a and b are on the same local date
+func sameDate(a, b time.Time) bool {
+	ay, am, ad := a.Local().Date()
+	by, bm, bd := b.Local().Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// RotatePolicy is the rotation policy of NewPolicyRotatingLogHandler. the
+// file is rotated by whichever trigger fires first, the backups are named
+// the same way for both triggers and the retention applies to all of them.
+type RotatePolicy struct {
+	// MaxBytes rotates the file once it reaches the size. 0 means no limit.
+	MaxBytes int64
+	// Daily rotates the file at the first write after the local date
+	// changed since the file was opened (or last written, for an existing
+	// file)
+	Daily bool
+	// MaxBackups is the number of the backups kept, the older ones are
+	// deleted
+	MaxBackups int
+	// MaxAge deletes the backups last written longer than MaxAge ago, at
+	// each rotation. 0 means no limit.
+	MaxAge time.Duration
+}
+
+// NewRotatingFileLogHandler creates a new LogHandler that writes logs to a
+// file like NewFileAccessorLogHandler, and rotates it once it reaches
+// maxBytes: the file is renamed to `path.1` (the former `path.1` to
+// `path.2` and so on) and a new file is opened. at most maxBackups backups
+// are kept, the older ones are deleted.
+//
+// writes and rotation are serialized by the handler, so it's safe for
+// concurrent use. if the new file can't be opened, the following messages
+// are dropped.
+// ctx is the context for file lifecycle management. like the file accessor,
+// the handler implements Flusher, io.Closer and WriteErrorReporter.
+func NewRotatingFileLogHandler(
+	ctx context.Context, path string, maxBytes int64, maxBackups int,
+) (LogHandler, error) {
+	return newPolicyRotatingLogHandler(ctx, path, RotatePolicy{
+		MaxBytes:   maxBytes,
+		MaxBackups: maxBackups,
+	}, RealClock)
+}
+
+// NewPolicyRotatingLogHandler is like NewRotatingFileLogHandler, with the
+// combined size and daily policy, e.g. "daily or at 100MB, whichever comes
+// first" with the backups of the last 30 days:
+//
+//	nekomimi.NewPolicyRotatingLogHandler(ctx, "app.log", nekomimi.RotatePolicy{
+//		MaxBytes:   100 << 20,
+//		Daily:      true,
+//		MaxBackups: 60,
+//		MaxAge:     30 * 24 * time.Hour,
+//	})
+//
+// the backups are named `path.1`, `path.2`... whatever the trigger, unlike
+// NewDailyRotatingLogHandler which writes a file per date.
+func NewPolicyRotatingLogHandler(
+	ctx context.Context, path string, policy RotatePolicy,
+) (LogHandler, error) {
+	return newPolicyRotatingLogHandler(ctx, path, policy, RealClock)
+}
+
+// newPolicyRotatingLogHandler creates the rotating handler with the clock
+// providing the current time
+func newPolicyRotatingLogHandler(
+	ctx context.Context, path string, policy RotatePolicy, clock Clock,
+) (LogHandler, error) {
+	countwrt := atomic.Uint64{}
+	lastflush := atomic.Uint64{}
+	fplock := &sync.RWMutex{}
+	fp, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	var size int64
+	opened := clock.Now()
+	if st, err := fp.Stat(); err == nil && st.Size() > 0 {
+		size = st.Size()
+		opened = st.ModTime()
+	}
+	closed := false
+
+	// reopen the file. must be called with fplock held.
+	reopen := func() {
+		nfp, err := os.OpenFile(
+			path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return // drop logs, retry on the next write
+		}
+		fp = nfp
+		size = 0
+		opened = clock.Now()
+	}
+
+	// rotate the file. must be called with fplock held.
+	rotate := func() {
+		fp.Close()
+		fp = nil
+		rotateBackups(path, policy.MaxBackups)
+		if policy.MaxAge > 0 {
+			pruneBackups(path, policy.MaxBackups,
+				clock.Now().Add(-policy.MaxAge))
+		}
+		reopen()
+	}
+
+	// flush file
+	flush := func() error {
+		fplock.RLock()
+		defer fplock.RUnlock()
+		if fp == nil {
+			return nil
+		}
+		c := countwrt.Load()
+		if lastflush.Swap(c) == c {
+			return nil
+		}
+		return fp.Sync()
+	}
+
+	// final flush and close
+	closeFile := func() error {
+		fplock.Lock()
+		defer fplock.Unlock()
+		var err error
+		if fp != nil {
+			err = fp.Close()
+		}
+		fp = nil
+		closed = true
+		return err
+	}
+
+	fh := &fileHandler{flush: flush, close: closeFile}
+
+	// tiny log handler function
+	fh.TinyLogHandlerFunc = func(level LogLevel, pnt func(io.StringWriter)) {
+		fplock.Lock()
+		defer fplock.Unlock()
+		if closed {
+			return
+		}
+		if fp == nil {
+			if reopen(); fp == nil {
+				return
+			}
+		}
+		if policy.Daily && size > 0 && !sameDate(opened, clock.Now()) {
+			if rotate(); fp == nil {
+				return
+			}
+		}
+		ew := errorWriter{w: sizeWriter{w: fp, size: &size}}
+		pnt(&ew)
+		countwrt.Add(1)
+		fh.report(ew.err)
+		if policy.MaxBytes > 0 && size >= policy.MaxBytes {
+			rotate()
+		}
+	}
+
+	// file holder thread
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				closeFile()
+				return
+			case <-time.After(2 * time.Second):
+				flush() // periodic flush
+			}
+		}
+	}()
+
+	return fh, nil
+}
+
+// NewDailyRotatingLogHandler creates a new LogHandler that writes logs to a
+// daily file like NewFileAccessorLogHandler. pathPattern is formatted with
+// the current local time as a Go time layout, e.g. "logs/app-2006-01-02.log"
+// gives "logs/app-2024-06-01.log". note the whole pattern is formatted, the
+// directories and names should not contain other layout elements (digits
+// or names like "Jan" and "Mon").
+//
+// at the first write after the formatted path changes (e.g. at local
+// midnight), the old file is closed and the new one is opened, the parent
+// directories are created if needed. writes and rotation are serialized by the handler, so it's safe
+// for concurrent use.
+// ctx is the context for file lifecycle management. like the file accessor,
+// the handler implements Flusher, io.Closer and WriteErrorReporter.
+func NewDailyRotatingLogHandler(
+	ctx context.Context, pathPattern string,
+) (LogHandler, error) {
+	return newDailyRotatingLogHandler(ctx, pathPattern, RealClock)
+}
+
+// newDailyRotatingLogHandler creates the daily rotating handler with the
+// clock providing the current time
+func newDailyRotatingLogHandler(
+	ctx context.Context, pathPattern string, clock Clock,
+) (LogHandler, error) {
+	countwrt := atomic.Uint64{}
+	lastflush := atomic.Uint64{}
+	fplock := &sync.RWMutex{}
+	var fp *os.File
+	curpath := ""
+	closed := false
+
+	// open the file of the current date if the path changes. must be
+	// called with fplock held.
+	open := func() error {
+		path := clock.Now().Format(pathPattern)
+		if path == curpath && fp != nil {
+			return nil
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		nfp, err := os.OpenFile(
+			path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		if fp != nil {
+			fp.Close()
+		}
+		fp, curpath = nfp, path
+		return nil
+	}
+	if err := open(); err != nil {
+		return nil, err
+	}
+
+	// flush file
+	flush := func() error {
+		fplock.RLock()
+		defer fplock.RUnlock()
+		if fp == nil {
+			return nil
+		}
+		c := countwrt.Load()
+		if lastflush.Swap(c) == c {
+			return nil
+		}
+		return fp.Sync()
+	}
+
+	// final flush and close
+	closeFile := func() error {
+		fplock.Lock()
+		defer fplock.Unlock()
+		var err error
+		if fp != nil {
+			err = fp.Close()
+		}
+		fp = nil
+		closed = true
+		return err
+	}
+
+	fh := &fileHandler{flush: flush, close: closeFile}
+
+	// tiny log handler function
+	fh.TinyLogHandlerFunc = func(level LogLevel, pnt func(io.StringWriter)) {
+		fplock.Lock()
+		defer fplock.Unlock()
+		if closed {
+			return
+		}
+		if open() != nil && fp == nil {
+			return
+		}
+		// keep writing the old file if the new one can't be opened
+		ew := errorWriter{w: fp}
+		pnt(&ew)
+		countwrt.Add(1)
+		fh.report(ew.err)
+	}
+
+	// file holder thread
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				closeFile()
+				return
+			case <-time.After(2 * time.Second):
+				flush() // periodic flush
+			}
+		}
+	}()
+
+	return fh, nil
+}
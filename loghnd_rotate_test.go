@@ -0,0 +1,201 @@
+package nekomimi
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRotatingFileLogHandler(t *testing.T) {
+	Convey("Rotating file handler tests", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		path := filepath.Join(t.TempDir(), "app.log")
+		fileSize := func(p string) int64 {
+			st, err := os.Stat(p)
+			if err != nil {
+				return -1
+			}
+			return st.Size()
+		}
+
+		Convey("Rotates at max bytes and keeps max backups", func() {
+			fh, err := NewRotatingFileLogHandler(ctx, path, 100, 2)
+			So(err, ShouldBeNil)
+			l := New("App", LogConfig{
+				Handler:  &LogHandlerFunc{Wrapper: fh},
+				TestMode: true,
+			})
+			// each line is 50 bytes, 2 lines per file
+			line := strings.Repeat("x", 50-len("2000-01-01 00:00:00.000 [INFO], App - \n"))
+			for range 7 {
+				l.Inf(line)
+			}
+			So(fileSize(path), ShouldEqual, 50)
+			So(fileSize(path+".1"), ShouldEqual, 100)
+			So(fileSize(path+".2"), ShouldEqual, 100)
+			So(fileSize(path+".3"), ShouldEqual, -1)
+
+			cancel()
+			So(waitFor(fh.IsShutdown), ShouldBeTrue)
+			l.Inf("after close")
+			So(fileSize(path), ShouldEqual, 50)
+		})
+
+		Convey("Concurrent writes are not lost", func() {
+			fh, err := NewRotatingFileLogHandler(ctx, path, 1000, 100)
+			So(err, ShouldBeNil)
+			l := New("App", LogConfig{
+				Handler:  &LogHandlerFunc{Wrapper: fh},
+				TestMode: true,
+			})
+			wg := sync.WaitGroup{}
+			for g := range 8 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := range 50 {
+						l.Inff("goroutine %d message %d", g, i)
+					}
+				}()
+			}
+			wg.Wait()
+			cancel()
+			So(waitFor(fh.IsShutdown), ShouldBeTrue)
+
+			lines := 0
+			files := []string{path}
+			for i := 1; i <= 100; i++ {
+				files = append(files, fmt.Sprintf("%s.%d", path, i))
+			}
+			for _, f := range files {
+				data, err := os.ReadFile(f)
+				if err != nil {
+					continue
+				}
+				So(len(data), ShouldBeLessThan, 1000+100)
+				for _, ln := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+					So(ln, ShouldContainSubstring, "[INFO], App - goroutine ")
+					lines++
+				}
+			}
+			So(lines, ShouldEqual, 400)
+		})
+
+		Convey("Existing file size is counted", func() {
+			So(os.WriteFile(path, []byte(strings.Repeat("y", 90)), 0o644), ShouldBeNil)
+			fh, err := NewRotatingFileLogHandler(ctx, path, 100, 1)
+			So(err, ShouldBeNil)
+			l := New("App", LogConfig{Handler: &LogHandlerFunc{Wrapper: fh}})
+			l.Inf("crosses the limit")
+			So(fileSize(path), ShouldEqual, 0)
+			So(fileSize(path+".1"), ShouldBeGreaterThan, 100)
+		})
+	})
+}
+
+func TestDailyRotatingLogHandler(t *testing.T) {
+	Convey("Daily rotating file handler tests", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// the whole pattern is formatted, the temp dir has digits
+		dir := t.TempDir()
+		t.Chdir(dir)
+		pattern := filepath.Join("logs", "2006-01", "app-2006-01-02.log")
+		clock := NewMockClock(time.Date(2024, 5, 31, 23, 59, 58, 0, time.Local))
+		readFile := func(p string) string {
+			data, _ := os.ReadFile(p)
+			return string(data)
+		}
+
+		fh, err := newDailyRotatingLogHandler(ctx, pattern, clock)
+		So(err, ShouldBeNil)
+		l := New("App", LogConfig{
+			Handler:  &LogHandlerFunc{Wrapper: fh},
+			Clock:    clock,
+			TestMode: true,
+		})
+		l.Inf("before midnight")
+		clock.Advance(time.Second)
+		l.Inf("still May")
+		clock.Advance(2 * time.Second)
+		l.Inf("after midnight")
+
+		may := readFile(filepath.Join(dir, "logs", "2024-05", "app-2024-05-31.log"))
+		june := readFile(filepath.Join(dir, "logs", "2024-06", "app-2024-06-01.log"))
+		So(strings.Count(may, "\n"), ShouldEqual, 2)
+		So(may, ShouldContainSubstring, "still May")
+		So(june, ShouldEndWith, "[INFO], App - after midnight\n")
+		So(strings.Count(june, "\n"), ShouldEqual, 1)
+
+		cancel()
+		So(waitFor(fh.IsShutdown), ShouldBeTrue)
+
+		_, err = NewDailyRotatingLogHandler(
+			context.Background(), filepath.Join(pattern, "\x00"))
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestPolicyRotatingLogHandler(t *testing.T) {
+	Convey("Policy rotating file handler tests", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		path := filepath.Join(t.TempDir(), "app.log")
+		clock := NewMockClock(time.Date(2024, 5, 31, 23, 0, 0, 0, time.Local))
+		fileSize := func(p string) int64 {
+			st, err := os.Stat(p)
+			if err != nil {
+				return -1
+			}
+			return st.Size()
+		}
+
+		fh, err := newPolicyRotatingLogHandler(ctx, path, RotatePolicy{
+			MaxBytes:   100,
+			Daily:      true,
+			MaxBackups: 5,
+			MaxAge:     48 * time.Hour,
+		}, clock)
+		So(err, ShouldBeNil)
+		l := New("App", LogConfig{
+			Handler:  &LogHandlerFunc{Wrapper: fh},
+			TestMode: true,
+		})
+		// each line is 50 bytes, 2 lines per file
+		line := strings.Repeat("x", 50-len("2000-01-01 00:00:00.000 [INFO], App - \n"))
+
+		// rotated by size
+		l.Inf(line)
+		l.Inf(line)
+		l.Inf(line)
+		So(fileSize(path), ShouldEqual, 50)
+		So(fileSize(path+".1"), ShouldEqual, 100)
+
+		// rotated by date before the size is reached
+		clock.Advance(2 * time.Hour)
+		l.Inf(line)
+		So(fileSize(path), ShouldEqual, 50)
+		So(fileSize(path+".1"), ShouldEqual, 50)
+		So(fileSize(path+".2"), ShouldEqual, 100)
+
+		// the backups older than MaxAge are deleted at the next rotation
+		old := clock.Now().Add(-72 * time.Hour)
+		So(os.Chtimes(path+".2", old, old), ShouldBeNil)
+		l.Inf(line)
+		So(fileSize(path), ShouldEqual, 0)
+		So(fileSize(path+".1"), ShouldEqual, 100)
+		So(fileSize(path+".2"), ShouldEqual, 50)
+		So(fileSize(path+".3"), ShouldEqual, -1)
+
+		cancel()
+		So(waitFor(fh.IsShutdown), ShouldBeTrue)
+	})
+}
@@ -0,0 +1,246 @@
+package nekomimi
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+)
+
+// slogHandler is the slog.Handler returned by NewSlogHandler
+type slogHandler struct {
+	h LogHandler
+	// rendered attributes of WithAttrs, with the leading space
+	attrs string
+	// group prefix of the following attributes, with the trailing dot
+	group string
+}
+
+// NewSlogHandler creates a slog.Handler which forwards the slog records to
+// h, so the nekomimi handlers (file rotation, composition...) apply to the
+// log/slog output. the slog levels are mapped to the nearest LogLevel
+// (below slog.LevelDebug is TRACE, slog.LevelError and above is ERROR).
+//
+// the message is followed by the attributes as `key=value`, the keys are
+// qualified by the groups, e.g. `request done http.status=200`. the header
+// has the time of the record and the prefix "slog". all levels are enabled,
+// filter them by slog.HandlerOptions of the caller or by the handler.
+func NewSlogHandler(h LogHandler) slog.Handler {
+	return &slogHandler{h: h}
+}
+
+// slogLevel maps the slog level to LogLevel
+func slogLevel(level slog.Level) LogLevel {
+	switch {
+	case level < slog.LevelDebug:
+		return TRACE
+	case level < slog.LevelInfo:
+		return DEBUG
+	case level < slog.LevelWarn:
+		return INFO
+	case level < slog.LevelError:
+		return WARN
+	default:
+		return ERROR
+	}
+}
+
+// walkSlogAttr calls fn with the key qualified by the groups and the value
+// of each attribute, groups are flattened
+func walkSlogAttr(group string, a slog.Attr, fn func(key, value string)) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			group += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			walkSlogAttr(group, ga, fn)
+		}
+		return
+	}
+	fn(group+a.Key, a.Value.String())
+}
+
+// appendSlogAttr renders the attribute to sb as ` key=value`
+func appendSlogAttr(sb *strings.Builder, group string, a slog.Attr) {
+	walkSlogAttr(group, a, func(key, value string) {
+		sb.WriteByte(' ')
+		sb.WriteString(key)
+		sb.WriteByte('=')
+		sb.WriteString(quoteFieldValue(value))
+	})
+}
+
+func (sh *slogHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (sh *slogHandler) Handle(_ context.Context, r slog.Record) error {
+	sb := strings.Builder{}
+	sb.WriteString(r.Message)
+	sb.WriteString(sh.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		appendSlogAttr(&sb, sh.group, a)
+		return true
+	})
+	tm := r.Time
+	if tm.IsZero() {
+		tm = time.Now()
+	}
+	level := slogLevel(r.Level)
+	// FORMAT: time [level], slog -
+	header := tm.Format("2006-01-02 15:04:05.000") +
+		" [" + level.String() + "], slog - "
+	sh.h.RegularLog(level, header, sb.String())
+	return nil
+}
+
+func (sh *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return sh
+	}
+	sb := strings.Builder{}
+	sb.WriteString(sh.attrs)
+	for _, a := range attrs {
+		appendSlogAttr(&sb, sh.group, a)
+	}
+	return &slogHandler{h: sh.h, attrs: sb.String(), group: sh.group}
+}
+
+func (sh *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return sh
+	}
+	return &slogHandler{h: sh.h, attrs: sh.attrs, group: sh.group + name + "."}
+}
+
+// loggerSlogHandler is the slog.Handler returned by AsSlogHandler
+type loggerSlogHandler struct {
+	l *logger
+	// fields of WithAttrs
+	fields []Field
+	// group prefix of the following attributes, with the trailing dot
+	group string
+}
+
+// AsSlogHandler creates a slog.Handler which logs the slog records with l,
+// so `slog.New(nekomimi.AsSlogHandler(l))` reaches the sinks of l. the slog
+// levels are mapped like NewSlogHandler and filtered by the level of l. the
+// attributes become header fields (qualified by the groups, e.g.
+// `{http.status=200}`), so structured handlers receive them as the Fields
+// of the Record. the header has no call trace.
+func AsSlogHandler(l Logger) slog.Handler {
+	lg, ok := l.(*logger)
+	if !ok {
+		return &slogLoggerHandler{l: l}
+	}
+	return &loggerSlogHandler{l: lg}
+}
+
+// appendSlogFields appends the attribute to the fields
+func appendSlogFields(fields []Field, group string, a slog.Attr) []Field {
+	walkSlogAttr(group, a, func(key, value string) {
+		fields = append(fields, Field{Key: key, Value: value})
+	})
+	return fields
+}
+
+func (sh *loggerSlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return sh.l.enabled(slogLevel(level))
+}
+
+func (sh *loggerSlogHandler) Handle(_ context.Context, r slog.Record) error {
+	level := slogLevel(r.Level)
+	if !sh.l.enabled(level) {
+		return nil
+	}
+	fields := slices.Clone(sh.fields)
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendSlogFields(fields, sh.group, a)
+		return true
+	})
+	sh.l.mtx.RLock()
+	opts := sh.l.headerOptions()
+	sh.l.mtx.RUnlock()
+	opts.levelcalltrace = FATAL + 1 // the call depth is unknown
+	opts.withStack = false
+	header := getHeaderFormatter(opts, 0)(level, nil, renderFields(fields))
+	sh.l.regularLog(level, header, fields, []any{r.Message})
+	return nil
+}
+
+func (sh *loggerSlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return sh
+	}
+	fields := slices.Clone(sh.fields)
+	for _, a := range attrs {
+		fields = appendSlogFields(fields, sh.group, a)
+	}
+	return &loggerSlogHandler{l: sh.l, fields: fields, group: sh.group}
+}
+
+func (sh *loggerSlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return sh
+	}
+	return &loggerSlogHandler{
+		l: sh.l, fields: sh.fields, group: sh.group + name + ".",
+	}
+}
+
+// slogLoggerHandler is the slog.Handler returned by AsSlogHandler for the
+// Logger implementations of other packages, the attributes follow the
+// message like NewSlogHandler
+type slogLoggerHandler struct {
+	l     Logger
+	attrs string
+	group string
+}
+
+func (sh *slogLoggerHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (sh *slogLoggerHandler) Handle(_ context.Context, r slog.Record) error {
+	sb := strings.Builder{}
+	sb.WriteString(r.Message)
+	sb.WriteString(sh.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		appendSlogAttr(&sb, sh.group, a)
+		return true
+	})
+	switch slogLevel(r.Level) {
+	case TRACE:
+		sh.l.Trc(sb.String())
+	case DEBUG:
+		sh.l.Dbg(sb.String())
+	case INFO:
+		sh.l.Inf(sb.String())
+	case WARN:
+		sh.l.War(sb.String())
+	default:
+		sh.l.Err(sb.String())
+	}
+	return nil
+}
+
+func (sh *slogLoggerHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	sb := strings.Builder{}
+	sb.WriteString(sh.attrs)
+	for _, a := range attrs {
+		appendSlogAttr(&sb, sh.group, a)
+	}
+	return &slogLoggerHandler{l: sh.l, attrs: sb.String(), group: sh.group}
+}
+
+func (sh *slogLoggerHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return sh
+	}
+	return &slogLoggerHandler{l: sh.l, attrs: sh.attrs, group: sh.group + name + "."}
+}
@@ -0,0 +1,97 @@
+package nekomimi
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSlogHandler(t *testing.T) {
+	Convey("Slog handler tests", t, func() {
+		capture := &captureLogHandler{}
+		sl := slog.New(NewSlogHandler(capture.handler()))
+
+		Convey("Levels are mapped", func() {
+			sl.Log(context.Background(), slog.LevelDebug-4, "trace")
+			sl.Debug("debug")
+			sl.Info("info")
+			sl.Warn("warn")
+			sl.Error("error")
+			sl.Log(context.Background(), slog.LevelError+4, "above error")
+			So(capture.levels, ShouldResemble, []LogLevel{
+				TRACE, DEBUG, INFO, WARN, ERROR, ERROR})
+			So(capture.lines[2], ShouldEndWith, "[INFO], slog - info\n")
+		})
+
+		Convey("Attributes are flattened into the message", func() {
+			sl.With("service", "api").
+				WithGroup("http").
+				With("method", "GET").
+				Info("request done", "status", 200,
+					slog.Group("client", "ip", "10.0.0.1"),
+					"path", "/a b", slog.Group("empty"))
+			So(capture.last(), ShouldEndWith,
+				`[INFO], slog - request done service=api http.method=GET `+
+					`http.status=200 http.client.ip=10.0.0.1 http.path="/a b"`+"\n")
+		})
+
+		Convey("Records are parsed by record handlers", func() {
+			var recs []Record
+			rl := slog.New(NewSlogHandler(RecordLogHandlerFunc(func(rec Record) {
+				recs = append(recs, rec)
+			})))
+			rl.Warn("disk low", "free", "1%")
+			So(len(recs), ShouldEqual, 1)
+			So(recs[0].Level, ShouldEqual, WARN)
+			So(recs[0].Message, ShouldEqual, "disk low free=1%")
+		})
+	})
+}
+
+func TestAsSlogHandler(t *testing.T) {
+	Convey("Logger as slog handler tests", t, func() {
+		var recs []Record
+		l := New("App", LogConfig{
+			Handler: RecordLogHandlerFunc(func(rec Record) {
+				recs = append(recs, rec)
+			}),
+			TestMode: true,
+		})
+		sl := slog.New(AsSlogHandler(l.Derive("Slog")))
+
+		Convey("Attributes become record fields", func() {
+			sl.With("service", "api").WithGroup("http").
+				Warn("request done", "status", 503, slog.Group("client", "ip", "10.0.0.1"))
+			So(len(recs), ShouldEqual, 1)
+			So(recs[0].Level, ShouldEqual, WARN)
+			So(recs[0].Prefix, ShouldEqual, "App.Slog")
+			So(recs[0].Message, ShouldEqual, "request done")
+			So(recs[0].Fields, ShouldResemble, []Field{
+				{Key: "service", Value: "api"},
+				{Key: "http.status", Value: "503"},
+				{Key: "http.client.ip", Value: "10.0.0.1"},
+			})
+		})
+
+		Convey("Level of the logger filters the records", func() {
+			l.SetLevel(INFO)
+			sl := slog.New(AsSlogHandler(l))
+			So(sl.Enabled(context.Background(), slog.LevelDebug), ShouldBeFalse)
+			sl.Debug("filtered")
+			sl.Info("kept")
+			So(len(recs), ShouldEqual, 1)
+			So(recs[0].Level, ShouldEqual, INFO)
+			So(recs[0].Fields, ShouldBeEmpty)
+		})
+
+		Convey("Console shows the fields in the header", func() {
+			capture := &captureLogHandler{}
+			cl := New("App", LogConfig{Handler: capture.handler(), TestMode: true})
+			slog.New(AsSlogHandler(cl)).Error("failed", "err", "disk full")
+			So(capture.last(), ShouldEqual,
+				`2000-01-01 00:00:00.000 [ERROR], App {err="disk full"} - failed`+"\n")
+		})
+	})
+}
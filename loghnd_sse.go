@@ -0,0 +1,142 @@
+package nekomimi
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// sseClientBuffer is the number of records buffered for each SSE client
+const sseClientBuffer = 256
+
+// sseHandler is the LogHandler returned by NewSSELogHandler
+type sseHandler struct {
+	RecordLogHandlerFunc
+	mtx     sync.Mutex
+	clients map[chan []byte]struct{}
+	done    chan struct{}
+	closed  atomic.Bool
+	dropped atomic.Uint64
+}
+
+// NewSSELogHandler creates a LogHandler which streams the log messages to
+// the clients connected to the returned http.HandlerFunc, as Server-Sent
+// Events. each event carries one JSON Record (see Record.MarshalJSON):
+//
+//	data: {"time":"...","level":"INFO","prefix":"App","msg":"..."}
+//
+// each client has a bounded buffer, records are dropped for a slow client
+// instead of blocking the logger. the returned handler implements
+// DropCounter to count the dropped records of all clients. a client is
+// unsubscribed when its request context is done.
+//
+// the handler implements io.Closer, Close disconnects all clients and
+// rejects new connections. like RecordLogHandlerFunc, it never raises panic
+// or terminates the program, it should be used as Wrapper of other
+// handlers.
+func NewSSELogHandler() (LogHandler, http.HandlerFunc) {
+	h := &sseHandler{
+		clients: map[chan []byte]struct{}{},
+		done:    make(chan struct{}),
+	}
+	h.RecordLogHandlerFunc = h.broadcast
+	return h, h.serve
+}
+
+// broadcast sends the record to all connected clients
+func (h *sseHandler) broadcast(rec Record) {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		h.dropped.Add(1) // marshal failure, drop log
+		return
+	}
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	for ch := range h.clients {
+		select {
+		case ch <- data:
+		default:
+			h.dropped.Add(1) // slow client
+		}
+	}
+}
+
+// subscribe registers a new client, returns nil if the handler is closed
+func (h *sseHandler) subscribe() chan []byte {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	if h.closed.Load() {
+		return nil
+	}
+	ch := make(chan []byte, sseClientBuffer)
+	h.clients[ch] = struct{}{}
+	return ch
+}
+
+func (h *sseHandler) unsubscribe(ch chan []byte) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	delete(h.clients, ch)
+}
+
+// serve streams the records to a client until it disconnects
+func (h *sseHandler) serve(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	ch := h.subscribe()
+	if ch == nil {
+		http.Error(w, "log stream closed", http.StatusServiceUnavailable)
+		return
+	}
+	defer h.unsubscribe(ch)
+
+	hdr := w.Header()
+	hdr.Set("Content-Type", "text/event-stream")
+	hdr.Set("Cache-Control", "no-cache")
+	hdr.Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-h.done:
+			return
+		case data := <-ch:
+			if _, err := w.Write([]byte("data: ")); err != nil {
+				return
+			}
+			w.Write(data)
+			if _, err := w.Write([]byte("\n\n")); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// Dropped returns the number of records dropped so far
+func (h *sseHandler) Dropped() uint64 {
+	return h.dropped.Load()
+}
+
+// IsShutdown returns true after Close
+func (h *sseHandler) IsShutdown() bool {
+	return h.closed.Load()
+}
+
+// Close disconnects all clients, the records logged after Close are
+// discarded
+func (h *sseHandler) Close() error {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	if h.closed.CompareAndSwap(false, true) {
+		close(h.done)
+	}
+	return nil
+}
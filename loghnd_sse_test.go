@@ -0,0 +1,93 @@
+package nekomimi
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// clientCount returns the number of connected SSE clients
+func (h *sseHandler) clientCount() int {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return len(h.clients)
+}
+
+func TestSSELogHandler(t *testing.T) {
+	Convey("SSE log handler tests", t, func() {
+		h, serve := NewSSELogHandler()
+		sh := h.(*sseHandler)
+		srv := httptest.NewServer(serve)
+		Reset(srv.Close)
+		l := New("App", LogConfig{Handler: &LogHandlerFunc{Wrapper: h}})
+
+		Convey("Client receives emitted records", func() {
+			resp, err := http.Get(srv.URL)
+			So(err, ShouldBeNil)
+			defer resp.Body.Close()
+			So(resp.Header.Get("Content-Type"), ShouldEqual, "text/event-stream")
+			So(waitFor(func() bool { return sh.clientCount() == 1 }), ShouldBeTrue)
+
+			l.Inf("first")
+			l.Derive("DB").Err("second")
+
+			sc := bufio.NewScanner(resp.Body)
+			var recs []map[string]any
+			for len(recs) < 2 && sc.Scan() {
+				line := sc.Text()
+				if !strings.HasPrefix(line, "data: ") {
+					continue
+				}
+				rec := map[string]any{}
+				So(json.Unmarshal([]byte(line[len("data: "):]), &rec), ShouldBeNil)
+				recs = append(recs, rec)
+			}
+			So(len(recs), ShouldEqual, 2)
+			So(recs[0]["level"], ShouldEqual, "INFO")
+			So(recs[0]["msg"], ShouldEqual, "first")
+			So(recs[1]["prefix"], ShouldEqual, "App.DB")
+			So(recs[1]["msg"], ShouldEqual, "second")
+		})
+
+		Convey("Slow client drops records", func() {
+			ch := sh.subscribe()
+			for range sseClientBuffer + 5 {
+				l.Inf("flood")
+			}
+			So(len(ch), ShouldEqual, sseClientBuffer)
+			So(h.(DropCounter).Dropped(), ShouldEqual, 5)
+			sh.unsubscribe(ch)
+		})
+
+		Convey("Disconnected client is unsubscribed", func() {
+			resp, err := http.Get(srv.URL)
+			So(err, ShouldBeNil)
+			So(waitFor(func() bool { return sh.clientCount() == 1 }), ShouldBeTrue)
+			resp.Body.Close()
+			So(waitFor(func() bool {
+				l.Inf("probe") // write to detect the disconnect
+				return sh.clientCount() == 0
+			}), ShouldBeTrue)
+		})
+
+		Convey("Close disconnects clients", func() {
+			resp, err := http.Get(srv.URL)
+			So(err, ShouldBeNil)
+			defer resp.Body.Close()
+			So(waitFor(func() bool { return sh.clientCount() == 1 }), ShouldBeTrue)
+			So(closeHandler(h), ShouldBeNil)
+			So(h.IsShutdown(), ShouldBeTrue)
+			So(waitFor(func() bool { return sh.clientCount() == 0 }), ShouldBeTrue)
+
+			resp2, err := http.Get(srv.URL)
+			So(err, ShouldBeNil)
+			resp2.Body.Close()
+			So(resp2.StatusCode, ShouldEqual, http.StatusServiceUnavailable)
+		})
+	})
+}
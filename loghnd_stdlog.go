@@ -0,0 +1,56 @@
+package nekomimi
+
+import (
+	"io"
+	"log"
+	"strings"
+	"sync"
+)
+
+// NewStdLogSink creates a new LogHandler which forwards each formatted log
+// message to std.Output, reusing the output, prefix and flags of an
+// existing standard logger. if the standard logger adds a date or time by
+// its flags, the timestamp of nekomimi is removed from the message.
+//
+// like the native handler, PANIC messages raise panic and FATAL messages
+// terminate the program after logging. wrap is the optional Wrapper.
+func NewStdLogSink(std *log.Logger, wrap LogHandler) LogHandler {
+	output := func(pnt func(io.StringWriter)) {
+		sb := strings.Builder{}
+		pnt(&sb)
+		line := sb.String()
+		if std.Flags()&(log.Ldate|log.Ltime|log.Lmicroseconds) != 0 {
+			line = trimTimestamp(line)
+		}
+		std.Output(3, line)
+	}
+	return &LogHandlerFunc{
+		Lock: &sync.Mutex{},
+		RegularLogFunc: func(level LogLevel, pnt func(io.StringWriter)) {
+			output(pnt)
+		},
+		PanicLogFunc: func(
+			pnt func(io.StringWriter), info string,
+		) func() {
+			output(pnt)
+			return func() {
+				panic(info)
+			}
+		},
+		FatalLogFunc: func(pnt func(io.StringWriter)) func() {
+			output(pnt)
+			return sysTerminate
+		},
+		Wrapper: wrap,
+	}
+}
+
+// trimTimestamp removes the leading timestamp of a formatted log line. the
+// line is returned unchanged if the header is not recognizable.
+func trimTimestamp(line string) string {
+	lb, _, ok := findLevelTag(line)
+	if !ok {
+		return line
+	}
+	return line[lb:]
+}
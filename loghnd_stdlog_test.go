@@ -0,0 +1,54 @@
+package nekomimi
+
+import (
+	"bytes"
+	"log"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestStdLogSink(t *testing.T) {
+	Convey("Standard logger sink tests", t, func() {
+		buf := &bytes.Buffer{}
+
+		Convey("Timestamp is not duplicated", func() {
+			std := log.New(buf, "app: ", log.LstdFlags|log.Lmsgprefix)
+			file := &captureLogHandler{}
+			l := New("App", LogConfig{
+				Handler:        NewStdLogSink(std, file.handler()),
+				LevelWithTrace: FATAL,
+			})
+			l.Inf("hello")
+			out := buf.String()
+			// date and time by the standard logger, then the message prefix
+			So(out, ShouldEndWith, " app: [INFO], App - hello\n")
+			So(len(out), ShouldEqual,
+				len("2006/01/02 15:04:05 app: [INFO], App - hello\n"))
+			// wrapped handler receives the full line
+			So(file.last(), ShouldNotStartWith, "[INFO]")
+			So(file.last(), ShouldEndWith, " [INFO], App - hello\n")
+		})
+
+		Convey("Timestamp is kept without date flags", func() {
+			std := log.New(buf, "", 0)
+			l := New("App", LogConfig{
+				Handler:        NewStdLogSink(std, nil),
+				LevelWithTrace: FATAL,
+				TimeFormat:     "15:04:05",
+			})
+			l.War("multi\nline")
+			So(buf.String()[8:], ShouldEqual, " [WARN], App - multi\nline\n")
+			l.RawWriter().WriteString("raw [text] line\n")
+			So(buf.String(), ShouldEndWith, "\nraw [text] line\n")
+		})
+
+		Convey("Panic is raised after logging", func() {
+			std := log.New(buf, "", 0)
+			l := New("App", LogConfig{Handler: NewStdLogSink(std, nil)})
+			So(func() { l.Panic("boom") }, ShouldPanicWith, "boom\n")
+			So(buf.String(), ShouldContainSubstring, "[PANIC], App >> Stacks:")
+			So(buf.String(), ShouldEndWith, " - boom\n")
+		})
+	})
+}
@@ -0,0 +1,87 @@
+//go:build !windows && !plan9
+
+package nekomimi
+
+import (
+	"errors"
+	"io"
+	"log/syslog"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrSyslogUnsupported is returned by NewSyslogHandler on the platforms
+// without syslog
+var ErrSyslogUnsupported = errors.New("nekomimi: syslog is not supported")
+
+// syslogWrite returns the write method of the syslog severity mapped from
+// the level: TRACE and DEBUG to Debug, INFO to Info, WARN to Warning, ERROR
+// to Err, PANIC to Crit and FATAL to Emerg
+func syslogWrite(w *syslog.Writer, level LogLevel) func(string) error {
+	switch {
+	case level <= DEBUG:
+		return w.Debug
+	case level == INFO:
+		return w.Info
+	case level == WARN:
+		return w.Warning
+	case level == ERROR:
+		return w.Err
+	case level == PANIC:
+		return w.Crit
+	default:
+		return w.Emerg
+	}
+}
+
+// NewSyslogHandler creates a new LogHandler which sends log messages to the
+// syslog daemon by log/syslog. network and addr are passed to syslog.Dial,
+// empty strings connect to the local daemon. tag is the syslog tag, the
+// program name if empty, facility is a syslog facility (e.g.
+// `int(syslog.LOG_LOCAL0)`), the severity is mapped from the level of
+// each message.
+//
+// the timestamp is removed from the header since syslog adds its own, the
+// message is sent as `[LEVEL], prefix - body`. like the native handler,
+// PANIC messages raise panic and FATAL messages terminate the program after
+// logging. the handler implements io.Closer to close the connection.
+//
+// returns ErrSyslogUnsupported on the platforms without syslog (Windows and
+// Plan 9), the caller could fall back to another handler then.
+func NewSyslogHandler(
+	network, addr, tag string, facility int,
+) (LogHandler, error) {
+	w, err := syslog.Dial(network, addr, syslog.Priority(facility), tag)
+	if err != nil {
+		return nil, err
+	}
+	closed := &atomic.Bool{}
+	output := func(level LogLevel, pnt func(io.StringWriter)) {
+		sb := strings.Builder{}
+		pnt(&sb)
+		line := strings.TrimSuffix(trimTimestamp(sb.String()), "\n")
+		syslogWrite(w, level)(line)
+	}
+	return &LogHandlerFunc{
+		Lock:           &sync.Mutex{},
+		RegularLogFunc: output,
+		PanicLogFunc: func(
+			pnt func(io.StringWriter), info string,
+		) func() {
+			output(PANIC, pnt)
+			return func() {
+				panic(info)
+			}
+		},
+		FatalLogFunc: func(pnt func(io.StringWriter)) func() {
+			output(FATAL, pnt)
+			return sysTerminate
+		},
+		IsShutdownFunc: closed.Load,
+		CloseFunc: func() error {
+			closed.Store(true)
+			return w.Close()
+		},
+	}, nil
+}
@@ -0,0 +1,17 @@
+//go:build windows || plan9
+
+package nekomimi
+
+import "errors"
+
+// ErrSyslogUnsupported is returned by NewSyslogHandler on the platforms
+// without syslog
+var ErrSyslogUnsupported = errors.New("nekomimi: syslog is not supported")
+
+// NewSyslogHandler is not supported on this platform, it always returns
+// ErrSyslogUnsupported
+func NewSyslogHandler(
+	network, addr, tag string, facility int,
+) (LogHandler, error) {
+	return nil, ErrSyslogUnsupported
+}
@@ -0,0 +1,74 @@
+//go:build !windows && !plan9
+
+package nekomimi
+
+import (
+	"fmt"
+	"io"
+	"log/syslog"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSyslogHandler(t *testing.T) {
+	Convey("Syslog handler tests", t, func() {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		defer conn.Close()
+		receive := func() string {
+			buf := make([]byte, 4096)
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				return err.Error()
+			}
+			return string(buf[:n])
+		}
+
+		h, err := NewSyslogHandler("udp", conn.LocalAddr().String(), "app",
+			int(syslog.LOG_LOCAL0))
+		So(err, ShouldBeNil)
+		defer h.(io.Closer).Close()
+		l := New("App", LogConfig{Handler: h, TestMode: true})
+		l.SetLevel(TRACE)
+
+		Convey("Levels map to severities without the timestamp", func() {
+			for _, c := range []struct {
+				level    LogLevel
+				severity syslog.Priority
+			}{
+				{TRACE, syslog.LOG_DEBUG},
+				{DEBUG, syslog.LOG_DEBUG},
+				{INFO, syslog.LOG_INFO},
+				{WARN, syslog.LOG_WARNING},
+				{ERROR, syslog.LOG_ERR},
+			} {
+				l.GetWriter(c.level, false).WriteString("hello")
+				msg := receive()
+				So(msg, ShouldStartWith, fmt.Sprintf("<%d>",
+					syslog.LOG_LOCAL0|c.severity))
+				So(msg, ShouldEndWith, fmt.Sprintf(
+					" app[%d]: [%s], App - hello\n", os.Getpid(), c.level))
+				So(msg, ShouldNotContainSubstring, "2000-01-01")
+			}
+
+			So(func() { l.Panic("boom") }, ShouldPanic)
+			msg := receive()
+			So(msg, ShouldStartWith,
+				fmt.Sprintf("<%d>", syslog.LOG_LOCAL0|syslog.LOG_CRIT))
+			So(msg, ShouldEndWith, "[PANIC], App - boom\n")
+			So(strings.Count(msg, "\n"), ShouldEqual, 1)
+		})
+
+		Convey("Close shuts down the handler", func() {
+			So(h.IsShutdown(), ShouldBeFalse)
+			So(h.(io.Closer).Close(), ShouldBeNil)
+			So(h.IsShutdown(), ShouldBeTrue)
+		})
+	})
+}
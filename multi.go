@@ -0,0 +1,111 @@
+package nekomimi
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// multiHandler is the LogHandler returned by NewMultiHandler
+type multiHandler struct {
+	handlers []LogHandler
+}
+
+// NewMultiHandler creates a LogHandler which forwards every message to all
+// the handlers in order, e.g. to a file, the console and a network sink at
+// once. a handler which panics doesn't prevent the following ones from
+// receiving the message, the panic is discarded.
+//
+// for panic and fatal messages, the handlers but the last one receive the
+// message by RegularWriter, then the last one receives PanicLog/FatalLog,
+// so only the last handler performs the panic or the termination. it
+// implements Flusher and io.Closer, applied to all the handlers. see
+// NewSinkMatrix for the routing by level.
+func NewMultiHandler(handlers ...LogHandler) LogHandler {
+	return &multiHandler{handlers: slices.Clone(handlers)}
+}
+
+// guardedCall calls fn, a panic raised by fn is recovered and discarded
+func guardedCall(fn func()) {
+	defer func() {
+		recover()
+	}()
+	fn()
+}
+
+func (mh *multiHandler) IsShutdown() bool {
+	for _, h := range mh.handlers {
+		if !h.IsShutdown() {
+			return false
+		}
+	}
+	return true
+}
+
+func (mh *multiHandler) RegularWriter(
+	level LogLevel, pnt func(io.StringWriter),
+) {
+	for _, h := range mh.handlers {
+		guardedCall(func() { h.RegularWriter(level, pnt) })
+	}
+}
+
+func (mh *multiHandler) RegularLog(
+	level LogLevel, header string, message ...any,
+) {
+	for _, h := range mh.handlers {
+		guardedCall(func() { h.RegularLog(level, header, message...) })
+	}
+}
+
+// terminalLog sends the panic or fatal message by RegularWriter to the
+// handlers but the last one, which is returned. returns nil if there is no
+// handler.
+func (mh *multiHandler) terminalLog(
+	level LogLevel, header string, message ...any,
+) LogHandler {
+	if len(mh.handlers) == 0 {
+		return nil
+	}
+	sp := fmt.Sprintln(message...)
+	pnt := func(w io.StringWriter) {
+		w.WriteString(header)
+		w.WriteString(sp)
+	}
+	last := len(mh.handlers) - 1
+	for _, h := range mh.handlers[:last] {
+		guardedCall(func() { h.RegularWriter(level, pnt) })
+	}
+	return mh.handlers[last]
+}
+
+func (mh *multiHandler) PanicLog(header string, message ...any) {
+	if last := mh.terminalLog(PANIC, header, message...); last != nil {
+		last.PanicLog(header, message...)
+	}
+}
+
+func (mh *multiHandler) FatalLog(header string, message ...any) {
+	if last := mh.terminalLog(FATAL, header, message...); last != nil {
+		last.FatalLog(header, message...)
+	}
+}
+
+// Flush flushes all the handlers
+func (mh *multiHandler) Flush() error {
+	var errs []error
+	for _, h := range mh.handlers {
+		errs = append(errs, flushHandler(h))
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes all the handlers
+func (mh *multiHandler) Close() error {
+	var errs []error
+	for _, h := range mh.handlers {
+		errs = append(errs, closeHandler(h))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,57 @@
+package nekomimi
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMultiHandler(t *testing.T) {
+	Convey("Multi handler tests", t, func() {
+		first, second := &captureLogHandler{}, &captureLogHandler{}
+		broken := &LogHandlerFunc{
+			RegularLogFunc: func(level LogLevel, pnt func(io.StringWriter)) {
+				panic("sink exploded")
+			},
+		}
+		stderr := &strings.Builder{}
+		native := NewNativeLogHandlerWithConfig(context.Background(),
+			NativeConfig{Stdout: &strings.Builder{}, Stderr: stderr}, nil)
+
+		Convey("Messages reach every handler in order", func() {
+			h := NewMultiHandler(first.handler(), broken, second.handler())
+			l := New("App", LogConfig{Handler: h, TestMode: true})
+			l.Inf("hello")
+			l.GetWriter(WARN, false).WriteString("writer")
+			for _, c := range []*captureLogHandler{first, second} {
+				So(c.lines, ShouldResemble, []string{
+					"2000-01-01 00:00:00.000 [INFO], App - hello\n",
+					"2000-01-01 00:00:00.000 [WARN], App - writer\n",
+				})
+			}
+			So(h.IsShutdown(), ShouldBeFalse)
+			So(h.(Flusher).Flush(), ShouldBeNil)
+		})
+
+		Convey("Only the last handler panics", func() {
+			h := NewMultiHandler(first.handler(), broken, second.handler(), native)
+			l := New("App", LogConfig{Handler: h, TestMode: true})
+			So(func() { l.Panic("boom") }, ShouldPanic)
+			So(first.last(), ShouldEqual,
+				"2000-01-01 00:00:00.000 [PANIC], App - boom\n")
+			So(second.levels, ShouldResemble, []LogLevel{PANIC})
+			So(stderr.String(), ShouldEndWith, "[PANIC], App - boom\n")
+		})
+
+		Convey("No handler", func() {
+			l := New("App", LogConfig{Handler: NewMultiHandler(), TestMode: true})
+			So(func() {
+				l.Inf("dropped")
+				l.Panic("dropped")
+			}, ShouldNotPanic)
+		})
+	})
+}
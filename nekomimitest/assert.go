@@ -0,0 +1,49 @@
+// Package nekomimitest provides helpers to check the logs emitted by the
+// code under test.
+package nekomimitest
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/fiathux/nekomimi"
+)
+
+// AssertNoAbove installs a capture logger as the nekomimi default logger
+// (see nekomimi.SetDefault) and returns it, for the code taking a Logger.
+// on the test cleanup the former default logger is restored, and the test
+// fails if any message at or above level was emitted, the offending lines
+// are reported. panic and fatal messages are captured without panicking or
+// exiting.
+func AssertNoAbove(t testing.TB, level nekomimi.LogLevel) nekomimi.Logger {
+	t.Helper()
+	mtx := sync.Mutex{}
+	var offending []string
+	handler := func(lv nekomimi.LogLevel, pnt func(io.StringWriter)) {
+		sb := strings.Builder{}
+		pnt(&sb)
+		if lv == nekomimi.TINY_DONE || lv < level {
+			return
+		}
+		mtx.Lock()
+		defer mtx.Unlock()
+		offending = append(offending, sb.String())
+	}
+	l := nekomimi.New(t.Name(), nekomimi.LogConfig{
+		Handler: nekomimi.TinyLogHandlerFunc(handler),
+	})
+	orig := nekomimi.Default()
+	nekomimi.SetDefault(l)
+	t.Cleanup(func() {
+		nekomimi.SetDefault(orig)
+		mtx.Lock()
+		defer mtx.Unlock()
+		if len(offending) > 0 {
+			t.Errorf("%d unexpected log message(s) at or above %s:\n%s",
+				len(offending), level, strings.Join(offending, ""))
+		}
+	})
+	return l
+}
@@ -0,0 +1,62 @@
+package nekomimitest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/fiathux/nekomimi"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTB records the cleanup functions and the errors instead of failing
+type fakeTB struct {
+	testing.TB
+	cleanups []func()
+	errors   []string
+}
+
+func (f *fakeTB) Helper()           {}
+func (f *fakeTB) Name() string      { return "Fake" }
+func (f *fakeTB) Cleanup(fn func()) { f.cleanups = append(f.cleanups, fn) }
+func (f *fakeTB) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeTB) cleanup() {
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+}
+
+func TestAssertNoAbove_Fails(t *testing.T) {
+	orig := nekomimi.Default()
+	ft := &fakeTB{}
+	l := AssertNoAbove(ft, nekomimi.ERROR)
+	assert.Equal(t, l, nekomimi.Default())
+
+	nekomimi.War("just a warning")
+	nekomimi.Err("something broke")
+	l.Panic("and panicked")
+	ft.cleanup()
+
+	assert.Equal(t, orig, nekomimi.Default())
+	if assert.Len(t, ft.errors, 1) {
+		assert.Contains(t, ft.errors[0], "2 unexpected log message(s) at or above ERROR")
+		assert.Contains(t, ft.errors[0], "[ERROR], Fake")
+		assert.Contains(t, ft.errors[0], "- something broke\n")
+		assert.Contains(t, ft.errors[0], "- and panicked\n")
+		assert.NotContains(t, ft.errors[0], "just a warning")
+	}
+}
+
+func TestAssertNoAbove_Passes(t *testing.T) {
+	ft := &fakeTB{}
+	l := AssertNoAbove(ft, nekomimi.ERROR)
+	nekomimi.Inf("fine")
+	l.War("still fine")
+	ft.cleanup()
+	assert.Empty(t, ft.errors)
+
+	// the real testing.TB
+	AssertNoAbove(t, nekomimi.WARN).Inf("fine")
+}
@@ -0,0 +1,23 @@
+package nekomimi
+
+import "sync/atomic"
+
+// paused is the global switch consulted by the level gate of all loggers
+var paused atomic.Bool
+
+// Pause suppresses all regular log messages (TRACE to ERROR) of all loggers,
+// e.g. while a terminal UI owns the screen. panic and fatal messages are
+// still output. the writers got from GetWriter are also suppressed.
+func Pause() {
+	paused.Store(true)
+}
+
+// Resume resumes the log output suppressed by Pause
+func Resume() {
+	paused.Store(false)
+}
+
+// IsPaused reports whether the log output is paused
+func IsPaused() bool {
+	return paused.Load()
+}
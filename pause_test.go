@@ -0,0 +1,49 @@
+package nekomimi
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPause(t *testing.T) {
+	Convey("Pause tests", t, func() {
+		rec := &captureLogHandler{}
+		l := New("Test", LogConfig{
+			Handler:        rec.handler(),
+			LevelWithTrace: ERROR,
+		})
+		tl := l.Trace("job")
+		Reset(Resume)
+
+		Convey("Regular logs are suppressed between Pause and Resume", func() {
+			w := l.GetWriter(INFO, false)
+			l.Inf("before")
+			So(rec.count(), ShouldEqual, 1)
+
+			Pause()
+			So(IsPaused(), ShouldBeTrue)
+			l.Dbg("debug")
+			l.Inf("info")
+			l.Warf("warn %d", 1)
+			l.Err("error")
+			tl.Inf("trace")
+			l.Batch(INFO).Commit()
+			w.WriteString("writer")
+			So(rec.count(), ShouldEqual, 1)
+
+			Resume()
+			So(IsPaused(), ShouldBeFalse)
+			l.Inf("after")
+			So(rec.count(), ShouldEqual, 2)
+			So(rec.last(), ShouldEndWith, "[INFO], Test - after\n")
+		})
+
+		Convey("Panic still fires while paused", func() {
+			Pause()
+			l.Panic("panic")
+			So(rec.count(), ShouldEqual, 1)
+			So(rec.last(), ShouldContainSubstring, "panic")
+		})
+	})
+}
@@ -0,0 +1,143 @@
+package nekomimi
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// rateLimitHandler is the LogHandler returned by NewRateLimitHandler
+type rateLimitHandler struct {
+	wrap  LogHandler
+	rate  float64 // tokens per second
+	burst float64
+	clock Clock
+
+	mtx        sync.Mutex
+	tokens     float64
+	last       time.Time // last refill of the bucket
+	suppressed int       // messages dropped since the last summary
+
+	dropped atomic.Uint64
+}
+
+// NewRateLimitHandler creates a LogHandler which limits the regular log
+// messages forwarded to wrapped by a token bucket: the bucket holds up to
+// burst tokens and is refilled at perSecond tokens per second, a message
+// takes a token or is dropped. panic and fatal messages always pass.
+//
+// when messages were dropped, the next forwarded message is preceded by a
+// WARN line `(suppressed N messages)`, flushing or closing the handler
+// emits the pending summary as well. the dropped messages are counted,
+// see DropCounter. see NewSamplingHandler for the sampling by count.
+func NewRateLimitHandler(wrapped LogHandler, perSecond int, burst int) LogHandler {
+	b := float64(max(burst, 1))
+	return &rateLimitHandler{
+		wrap:   wrapped,
+		rate:   float64(max(perSecond, 1)),
+		burst:  b,
+		clock:  RealClock,
+		tokens: b,
+	}
+}
+
+// allow reports whether a message should be forwarded, and the number of
+// suppressed messages to report before it
+func (rl *rateLimitHandler) allow(level LogLevel) (bool, int) {
+	if level >= PANIC {
+		return true, 0
+	}
+	rl.mtx.Lock()
+	defer rl.mtx.Unlock()
+	now := rl.clock.Now()
+	if !rl.last.IsZero() {
+		rl.tokens = min(rl.tokens+now.Sub(rl.last).Seconds()*rl.rate, rl.burst)
+	}
+	rl.last = now
+	if rl.tokens < 1 {
+		rl.suppressed++
+		rl.dropped.Add(1)
+		return false, 0
+	}
+	rl.tokens--
+	suppressed := rl.suppressed
+	rl.suppressed = 0
+	return true, suppressed
+}
+
+// summary writes the `(suppressed N messages)` line to the wrapped handler
+func (rl *rateLimitHandler) summary(n int) {
+	if n == 0 {
+		return
+	}
+	// FORMAT: time [level], ratelimit -
+	line := fmt.Sprintf("%s [%s], ratelimit - (suppressed %d messages)\n",
+		rl.clock.Now().Format("2006-01-02 15:04:05.000"), WARN, n)
+	rl.wrap.RegularWriter(WARN, func(w io.StringWriter) {
+		w.WriteString(line)
+	})
+}
+
+// pending takes the number of the suppressed messages not reported yet
+func (rl *rateLimitHandler) pending() int {
+	rl.mtx.Lock()
+	defer rl.mtx.Unlock()
+	n := rl.suppressed
+	rl.suppressed = 0
+	return n
+}
+
+// Dropped returns the number of messages dropped by the limiter
+func (rl *rateLimitHandler) Dropped() uint64 {
+	return rl.dropped.Load()
+}
+
+func (rl *rateLimitHandler) IsShutdown() bool {
+	return rl.wrap.IsShutdown()
+}
+
+func (rl *rateLimitHandler) RegularWriter(
+	level LogLevel, pnt func(io.StringWriter),
+) {
+	ok, n := rl.allow(level)
+	if !ok {
+		return
+	}
+	rl.summary(n)
+	rl.wrap.RegularWriter(level, pnt)
+}
+
+func (rl *rateLimitHandler) RegularLog(
+	level LogLevel, header string, message ...any,
+) {
+	ok, n := rl.allow(level)
+	if !ok {
+		return
+	}
+	rl.summary(n)
+	rl.wrap.RegularLog(level, header, message...)
+}
+
+func (rl *rateLimitHandler) PanicLog(header string, message ...any) {
+	rl.summary(rl.pending())
+	rl.wrap.PanicLog(header, message...)
+}
+
+func (rl *rateLimitHandler) FatalLog(header string, message ...any) {
+	rl.summary(rl.pending())
+	rl.wrap.FatalLog(header, message...)
+}
+
+// Flush emits the pending summary, then flushes the wrapped handler
+func (rl *rateLimitHandler) Flush() error {
+	rl.summary(rl.pending())
+	return flushHandler(rl.wrap)
+}
+
+// Close emits the pending summary, then closes the wrapped handler
+func (rl *rateLimitHandler) Close() error {
+	rl.summary(rl.pending())
+	return closeHandler(rl.wrap)
+}
@@ -0,0 +1,73 @@
+package nekomimi
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRateLimitHandler(t *testing.T) {
+	Convey("Rate limit handler tests", t, func() {
+		sink := &captureLogHandler{}
+		h := NewRateLimitHandler(sink.handler(), 10, 5)
+		clock := NewMockClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+		h.(*rateLimitHandler).clock = clock
+		l := New("App", LogConfig{Handler: h, TestMode: true})
+
+		Convey("Burst then refill", func() {
+			for i := range 20 {
+				l.Inff("message %d", i)
+			}
+			So(sink.count(), ShouldEqual, 5)
+			So(h.(DropCounter).Dropped(), ShouldEqual, 15)
+
+			clock.Advance(200 * time.Millisecond) // 2 tokens
+			l.Inf("after refill 1")
+			l.Inf("after refill 2")
+			l.Inf("dropped")
+			So(sink.count(), ShouldEqual, 8)
+			So(sink.lines[5], ShouldEqual, "2026-01-01 00:00:00.200 [WARN], "+
+				"ratelimit - (suppressed 15 messages)\n")
+			So(sink.levels[5], ShouldEqual, WARN)
+			So(sink.lines[6], ShouldEndWith, "- after refill 1\n")
+
+			So(h.(Flusher).Flush(), ShouldBeNil)
+			So(sink.last(), ShouldEndWith, "(suppressed 1 messages)\n")
+			So(h.(Flusher).Flush(), ShouldBeNil)
+			So(sink.count(), ShouldEqual, 9)
+
+			// the bucket is capped by burst
+			clock.Advance(time.Hour)
+			sink.reset()
+			for range 10 {
+				l.Inf("capped")
+			}
+			So(sink.count(), ShouldEqual, 5)
+		})
+
+		Convey("Panic always passes", func() {
+			for range 10 {
+				l.Panic("boom")
+			}
+			So(sink.count(), ShouldEqual, 10)
+		})
+
+		Convey("Concurrent writers share the bucket", func() {
+			wg := sync.WaitGroup{}
+			for range 16 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for range 100 {
+						l.Inf("concurrent")
+					}
+				}()
+			}
+			wg.Wait()
+			So(sink.count(), ShouldEqual, 5)
+			So(h.(DropCounter).Dropped(), ShouldEqual, 1600-5)
+		})
+	})
+}
@@ -0,0 +1,342 @@
+package nekomimi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Record is a decomposed log entry for structured sinks. It's rebuilt from
+// the log header generated by the logger and the message body, so the
+// header components can be accessed separately.
+//
+// a line without a recognizable header (e.g. written by RawWriter) produces
+// a Record which only has Level and Message.
+type Record struct {
+	// formatted timestamp, as rendered in the header
+	Time string
+	// log level of the record
+	Level LogLevel
+	// logger prefix (dotted name of derived loggers)
+	Prefix string
+	// trace name and trace ID, empty if not emitted by a TraceLogger
+	TraceName string
+	TraceID   string
+	// call trace information "file:line(func)", if present
+	Caller string
+	// stack frames of panic/fatal records "file:line(func)", if present
+	Stack []string
+	// decomposed form of Stack, in the same order (the innermost frame
+	// first)
+	Frames []StackFrame
+	// header fields, e.g. fields extracted from context
+	Fields []Field
+	// message body without trailing newline
+	Message string
+	// verbose form (%+v) of the error arguments, only assigned when the
+	// logger has VerboseErrors enabled and the message arguments are
+	// received by RecordLogHandlerFunc itself (not through RegularWriter).
+	// Message holds the plain form in this case.
+	ErrorVerbose string
+	// machine-only metadata attached by Logger.WithMeta, never rendered in
+	// the header. only assigned when the message is received through
+	// MetaLogHandler.
+	Meta []Field
+}
+
+// Field is a key-value pair attached to the log header
+type Field struct {
+	Key   string
+	Value string
+}
+
+// StackFrame is a decomposed stack frame of a panic/fatal Record
+type StackFrame struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+	Func string `json:"func"`
+}
+
+// parseStackFrame decomposes a stack frame rendered as `file:line(func)`.
+// the file keeps the whole text if the frame is not recognizable.
+func parseStackFrame(s string) StackFrame {
+	// the function name could have parentheses, e.g. `pkg.(*T).Method`,
+	// and the file a drive letter, so the colons are tried from the last
+	// one until `:line(` is found
+	for i := strings.LastIndex(s, ":"); i > 0; i = strings.LastIndex(s[:i], ":") {
+		rest := s[i+1:]
+		lp := strings.IndexByte(rest, '(')
+		if lp <= 0 || !strings.HasSuffix(rest, ")") {
+			continue
+		}
+		line, err := strconv.Atoi(rest[:lp])
+		if err != nil {
+			continue
+		}
+		return StackFrame{
+			File: s[:i],
+			Line: line,
+			Func: rest[lp+1 : len(rest)-1],
+		}
+	}
+	return StackFrame{File: s}
+}
+
+// recordJSON is the JSON representation of a Record
+type recordJSON struct {
+	Time      string            `json:"time,omitempty"`
+	Level     string            `json:"level"`
+	Prefix    string            `json:"prefix,omitempty"`
+	TraceName string            `json:"trace_name,omitempty"`
+	TraceID   string            `json:"trace,omitempty"`
+	Caller    string            `json:"caller,omitempty"`
+	Stack     []StackFrame      `json:"stack,omitempty"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	Message   string            `json:"msg"`
+	ErrorVerb string            `json:"error_verbose,omitempty"`
+	Meta      map[string]string `json:"meta,omitempty"`
+}
+
+// MarshalJSON encodes the record as a flat JSON object
+func (r Record) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.jsonValue(r.Level.String()))
+}
+
+// jsonValue returns the JSON representation of the record with the level
+// rendered as levelName
+func (r Record) jsonValue(levelName string) recordJSON {
+	fieldsMap := func(fs []Field) map[string]string {
+		if len(fs) == 0 {
+			return nil
+		}
+		m := make(map[string]string, len(fs))
+		for _, f := range fs {
+			m[f.Key] = f.Value
+		}
+		return m
+	}
+	return recordJSON{
+		Time:      r.Time,
+		Level:     levelName,
+		Prefix:    r.Prefix,
+		TraceName: r.TraceName,
+		TraceID:   r.TraceID,
+		Caller:    r.Caller,
+		Stack:     r.Frames,
+		Fields:    fieldsMap(r.Fields),
+		Message:   r.Message,
+		ErrorVerb: r.ErrorVerbose,
+		Meta:      fieldsMap(r.Meta),
+	}
+}
+
+// levelFromName returns the log level for a level name rendered in the
+// header
+func levelFromName(name string) (LogLevel, bool) {
+	switch name {
+	case "TRACE":
+		return TRACE, true
+	case "DEBUG":
+		return DEBUG, true
+	case "INFO":
+		return INFO, true
+	case "WARN":
+		return WARN, true
+	case "ERROR":
+		return ERROR, true
+	case "PANIC":
+		return PANIC, true
+	case "FATAL":
+		return FATAL, true
+	default:
+		return 0, false
+	}
+}
+
+// NewRecord decomposes a log header and message body into a Record.
+// the header is expected in the format generated by the logger:
+//
+//	time [LEVEL], prefix<trace> calltrace {fields} -
+//
+// level is used as fallback when the header is not recognizable.
+func NewRecord(level LogLevel, header string, body string) Record {
+	rec, ok := parseRecordLine(level, header+body)
+	if !ok {
+		rec.Message = strings.TrimSuffix(header+body, "\n")
+	}
+	return rec
+}
+
+// parseRecordLine parses a complete log line (header followed by body).
+// returns false if the header is not recognizable, in this case only Level
+// is assigned.
+func parseRecordLine(level LogLevel, line string) (Record, bool) {
+	rec := Record{Level: level}
+	// time [LEVEL], the level name might be padded
+	lb := strings.Index(line, " [")
+	if lb < 0 {
+		return rec, false
+	}
+	rb := strings.Index(line[lb:], "], ")
+	if rb < 0 {
+		return rec, false
+	}
+	rb += lb
+	lv, ok := levelFromName(strings.TrimRight(line[lb+2:rb], " "))
+	if !ok {
+		return rec, false
+	}
+	rest := line[rb+3:]
+	// prefix
+	end := strings.IndexAny(rest, "< ")
+	if end < 0 {
+		return rec, false
+	}
+	prefix := rest[:end]
+	rest = rest[end:]
+	// <trace>
+	tname, tid := "", ""
+	if strings.HasPrefix(rest, "<") {
+		te := strings.Index(rest, ">")
+		if te < 0 {
+			return rec, false
+		}
+		tstr := rest[1:te]
+		if idx := strings.LastIndex(tstr, ":"); idx != -1 {
+			tname, tid = tstr[:idx], tstr[idx+1:]
+		} else {
+			tid = tstr
+		}
+		rest = rest[te+1:]
+	}
+	// calltrace or stacks
+	caller := ""
+	var stack []string
+	if strings.HasPrefix(rest, " >> Stacks:\n") {
+		se := strings.Index(rest, "\n<<<<")
+		if se < 0 {
+			return rec, false
+		}
+		for _, fr := range strings.Split(rest[len(" >> Stacks:\n"):se], "\n") {
+			if fr = strings.TrimSpace(fr); fr != "" {
+				stack = append(stack, fr)
+			}
+		}
+		rest = rest[se+len("\n<<<<"):]
+	} else if !strings.HasPrefix(rest, " - ") &&
+		!strings.HasPrefix(rest, " {") {
+		ce := strings.Index(rest, " - ")
+		if fe := strings.Index(rest, " {"); fe != -1 && (ce < 0 || fe < ce) {
+			ce = fe
+		}
+		if ce < 0 {
+			return rec, false
+		}
+		caller = strings.TrimSpace(rest[:ce])
+		rest = rest[ce:]
+	}
+	// {fields}
+	var fields []Field
+	if strings.HasPrefix(rest, " {") {
+		var ok bool
+		fields, rest, ok = parseFields(rest[1:])
+		if !ok {
+			return rec, false
+		}
+	}
+	if !strings.HasPrefix(rest, " - ") {
+		return rec, false
+	}
+	rec.Time = line[:lb]
+	rec.Level = lv
+	rec.Prefix = prefix
+	rec.TraceName = tname
+	rec.TraceID = tid
+	rec.Caller = caller
+	rec.Stack = stack
+	if len(stack) > 0 {
+		rec.Frames = make([]StackFrame, len(stack))
+		for i, fr := range stack {
+			rec.Frames[i] = parseStackFrame(fr)
+		}
+	}
+	rec.Fields = fields
+	rec.Message = strings.TrimSuffix(rest[3:], "\n")
+	return rec, true
+}
+
+// MetaLogHandler is implemented by structured handlers which accept the
+// machine-only metadata of Logger.WithMeta. the logger calls RegularLogMeta
+// instead of RegularLog if the logger has metadata. other handlers (e.g.
+// the console) never receive the metadata.
+type MetaLogHandler interface {
+	RegularLogMeta(
+		level LogLevel, header string, meta []Field, message ...any)
+}
+
+// RecordLogHandlerFunc is a LogHandler implementation which receives each
+// log message as a decomposed Record. it's designed as a sink for
+// structured outputs, and usually used as Wrapper of other handlers.
+//
+// like TinyLogHandlerFunc, it never raises panic or terminates the program
+// for Panic and Fatal levels. these should be handled by the outer handler.
+type RecordLogHandlerFunc func(rec Record)
+
+// ------- implement LogHandler interface for RecordLogHandlerFunc -------
+
+// IsShutdown always returns false, the function has no lifecycle
+func (rf RecordLogHandlerFunc) IsShutdown() bool {
+	return false
+}
+
+func (rf RecordLogHandlerFunc) RegularWriter(
+	level LogLevel, pnt func(io.StringWriter),
+) {
+	sb := strings.Builder{}
+	pnt(&sb)
+	line := sb.String()
+	rec, ok := parseRecordLine(level, line)
+	if !ok {
+		rec.Message = strings.TrimSuffix(line, "\n")
+	}
+	rf(rec)
+}
+
+// record builds the Record from the message arguments, the verbose errors
+// are moved to ErrorVerbose, the binary arguments are encoded in base64
+func (rf RecordLogHandlerFunc) record(
+	level LogLevel, header string, message []any,
+) Record {
+	message, verbose := splitVerboseArgs(structuredBinaryArgs(message))
+	rec := NewRecord(level, header, fmt.Sprintln(message...))
+	rec.ErrorVerbose = verbose
+	return rec
+}
+
+func (rf RecordLogHandlerFunc) RegularLog(
+	level LogLevel, header string, message ...any,
+) {
+	rf(rf.record(level, header, message))
+}
+
+// RegularLogMeta implements MetaLogHandler, the metadata is assigned to
+// Record.Meta
+func (rf RecordLogHandlerFunc) RegularLogMeta(
+	level LogLevel, header string, meta []Field, message ...any,
+) {
+	rec := rf.record(level, header, message)
+	rec.Meta = meta
+	rf(rec)
+}
+
+func (rf RecordLogHandlerFunc) PanicLog(header string, message ...any) {
+	rf(rf.record(PANIC, header, message))
+}
+
+func (rf RecordLogHandlerFunc) FatalLog(header string, message ...any) {
+	rf(rf.record(FATAL, header, message))
+}
+
+// --------------------------------------------------------------
@@ -0,0 +1,136 @@
+package nekomimi
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRecord(t *testing.T) {
+	Convey("Record tests", t, func() {
+		var recs []Record
+		sink := RecordLogHandlerFunc(func(rec Record) {
+			recs = append(recs, rec)
+		})
+		l := New("App", LogConfig{
+			Handler:        &LogHandlerFunc{Wrapper: sink},
+			LevelWithTrace: WARN,
+			TimeFormat:     "15:04:05",
+		})
+
+		Convey("Regular record through wrapper", func() {
+			l.Inf("hello", "world")
+			So(len(recs), ShouldEqual, 1)
+			So(recs[0].Level, ShouldEqual, INFO)
+			So(recs[0].Prefix, ShouldEqual, "App")
+			So(len(recs[0].Time), ShouldEqual, 8)
+			So(recs[0].TraceID, ShouldBeEmpty)
+			So(recs[0].Caller, ShouldBeEmpty)
+			So(recs[0].Message, ShouldEqual, "hello world")
+		})
+
+		Convey("Trace and caller record", func() {
+			tl := l.Trace("REQ")
+			tl.War("slow - request")
+			So(len(recs), ShouldEqual, 1)
+			So(recs[0].Level, ShouldEqual, WARN)
+			So(recs[0].Prefix, ShouldEqual, "App")
+			So(recs[0].TraceName, ShouldEqual, "REQ")
+			So(recs[0].TraceID, ShouldEqual, tl.TraceID())
+			So(recs[0].Caller, ShouldStartWith, "record_test.go:")
+			So(recs[0].Message, ShouldEqual, "slow - request")
+		})
+
+		Convey("Panic record has stack frames", func() {
+			l.Panic("boom")
+			So(len(recs), ShouldEqual, 1)
+			So(recs[0].Level, ShouldEqual, PANIC)
+			So(len(recs[0].Stack), ShouldBeGreaterThan, 0)
+			So(len(recs[0].Frames), ShouldEqual, len(recs[0].Stack))
+			top := recs[0].Frames[0]
+			So(top.File, ShouldEndWith, "/record_test.go")
+			So(top.Line, ShouldBeGreaterThan, 0)
+			So(top.Func, ShouldStartWith, "github.com/fiathux/nekomimi.Test")
+			So(recs[0].Caller, ShouldBeEmpty)
+			So(recs[0].Message, ShouldEqual, "boom")
+		})
+
+		Convey("Raw line without header", func() {
+			l.RawWriter().WriteString("raw line\n")
+			So(len(recs), ShouldEqual, 1)
+			So(recs[0].Level, ShouldEqual, INFO)
+			So(recs[0].Prefix, ShouldBeEmpty)
+			So(recs[0].Message, ShouldEqual, "raw line")
+		})
+
+		Convey("Direct handler call", func() {
+			l.SetLogHandler(sink)
+			tl := l.Trace("")
+			tl.Err("failed")
+			So(len(recs), ShouldEqual, 1)
+			So(recs[0].TraceName, ShouldBeEmpty)
+			So(recs[0].TraceID, ShouldEqual, tl.TraceID())
+			So(recs[0].Message, ShouldEqual, "failed")
+			data, err := json.Marshal(recs[0])
+			So(err, ShouldBeNil)
+			m := map[string]any{}
+			So(json.Unmarshal(data, &m), ShouldBeNil)
+			So(m["level"], ShouldEqual, "ERROR")
+			So(m["prefix"], ShouldEqual, "App")
+			So(m["trace"], ShouldEqual, tl.TraceID())
+			So(m["msg"], ShouldEqual, "failed")
+			So(m, ShouldNotContainKey, "trace_name")
+		})
+
+		Convey("Maps render with sorted keys", func() {
+			nested := map[string]any{
+				"zeta":  1,
+				"alpha": map[string]any{"y": 2, "x": map[string]int{"b": 2, "a": 1}},
+				"mid":   "m",
+			}
+			for range 20 {
+				l.Inf(nested)
+			}
+			want := "map[alpha:map[x:map[a:1 b:2] y:2] mid:m zeta:1]"
+			for _, rec := range recs {
+				So(rec.Message, ShouldEqual, want)
+			}
+
+			rec := Record{
+				Level:   INFO,
+				Fields:  []Field{{"zeta", "1"}, {"alpha", "2"}, {"mid", "3"}},
+				Message: "fields",
+			}
+			data, err := json.Marshal(rec)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldContainSubstring,
+				`"fields":{"alpha":"2","mid":"3","zeta":"1"}`)
+		})
+
+		Convey("Stack frames are decomposed", func() {
+			So(parseStackFrame("/src/app/main.go:42(main.(*Server).run)"),
+				ShouldResemble, StackFrame{
+					File: "/src/app/main.go",
+					Line: 42,
+					Func: "main.(*Server).run",
+				})
+			So(parseStackFrame("C:/src/main.go:7(main.main)"),
+				ShouldResemble, StackFrame{
+					File: "C:/src/main.go", Line: 7, Func: "main.main",
+				})
+			So(parseStackFrame("garbage"), ShouldResemble,
+				StackFrame{File: "garbage"})
+
+			data, err := json.Marshal(Record{
+				Level:   PANIC,
+				Stack:   []string{"/a.go:1(f)"},
+				Frames:  []StackFrame{{File: "/a.go", Line: 1, Func: "f"}},
+				Message: "boom",
+			})
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, `{"level":"PANIC",`+
+				`"stack":[{"file":"/a.go","line":1,"func":"f"}],"msg":"boom"}`)
+		})
+	})
+}
@@ -0,0 +1,42 @@
+package nekomimi
+
+import (
+	"time"
+)
+
+// RetryPolicy defines the bounded retry behavior of network sinks. when a
+// sink is down, a log message is retried up to MaxAttempts times, then it's
+// dropped and counted (see DropCounter). messages are never buffered
+// unboundedly.
+//
+// the zero value means a single attempt without retry.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts for one message,
+	// including the first one. values below 1 are treated as 1.
+	MaxAttempts int
+	// Backoff is the wait duration between two attempts.
+	Backoff time.Duration
+}
+
+// DropCounter is implemented by handlers that drop log messages after
+// exhausting retries.
+type DropCounter interface {
+	// Dropped returns the number of log messages dropped so far
+	Dropped() uint64
+}
+
+// Run calls send until it succeeds or the attempts are exhausted. attempt
+// starts from 0. returns the error of the last attempt, or nil on success.
+func (p RetryPolicy) Run(send func(attempt int) error) error {
+	n := max(p.MaxAttempts, 1)
+	var err error
+	for attempt := range n {
+		if attempt > 0 && p.Backoff > 0 {
+			time.Sleep(p.Backoff)
+		}
+		if err = send(attempt); err == nil {
+			return nil
+		}
+	}
+	return err
+}
@@ -0,0 +1,62 @@
+package nekomimi
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	Convey("RetryPolicy tests", t, func() {
+		Convey("Zero value attempts once", func() {
+			calls := 0
+			err := RetryPolicy{}.Run(func(int) error {
+				calls++
+				return fmt.Errorf("failed")
+			})
+			So(err, ShouldNotBeNil)
+			So(calls, ShouldEqual, 1)
+		})
+
+		Convey("Stops on success", func() {
+			var attempts []int
+			err := RetryPolicy{MaxAttempts: 5}.Run(func(a int) error {
+				attempts = append(attempts, a)
+				if a < 2 {
+					return fmt.Errorf("failed")
+				}
+				return nil
+			})
+			So(err, ShouldBeNil)
+			So(attempts, ShouldResemble, []int{0, 1, 2})
+		})
+
+		Convey("Backoff between attempts", func() {
+			start := time.Now()
+			err := RetryPolicy{
+				MaxAttempts: 3,
+				Backoff:     10 * time.Millisecond,
+			}.Run(func(int) error {
+				return fmt.Errorf("failed")
+			})
+			So(err, ShouldNotBeNil)
+			So(time.Since(start), ShouldBeGreaterThanOrEqualTo,
+				20*time.Millisecond)
+		})
+
+		Convey("Kafka handler drops after retries", func() {
+			calls := 0
+			h := NewKafkaLogHandlerWithRetry(
+				func(key, value []byte) error {
+					calls++
+					return fmt.Errorf("broker down")
+				}, nil, RetryPolicy{MaxAttempts: 3})
+			l := New("App", LogConfig{Handler: h})
+			l.Err("lost")
+			So(calls, ShouldEqual, 3)
+			So(h.(DropCounter).Dropped(), ShouldEqual, 1)
+		})
+	})
+}
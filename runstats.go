@@ -0,0 +1,75 @@
+package nekomimi
+
+import (
+	"context"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// runtimeStatsFields collects the runtime statistics as header fields. the
+// field names are kept stable for dashboards:
+//
+//	alloc       bytes of allocated heap objects
+//	sys         bytes of memory obtained from the OS
+//	heap_inuse  bytes in in-use heap spans
+//	heap_objs   number of allocated heap objects
+//	goroutines  number of goroutines
+//	num_gc      number of completed GC cycles
+//	pause_ns    total GC pause in nanoseconds
+func runtimeStatsFields() []Field {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	u := func(v uint64) string {
+		return strconv.FormatUint(v, 10)
+	}
+	return []Field{
+		{Key: "alloc", Value: u(ms.Alloc)},
+		{Key: "sys", Value: u(ms.Sys)},
+		{Key: "heap_inuse", Value: u(ms.HeapInuse)},
+		{Key: "heap_objs", Value: u(ms.HeapObjects)},
+		{Key: "goroutines", Value: strconv.Itoa(runtime.NumGoroutine())},
+		{Key: "num_gc", Value: u(uint64(ms.NumGC))},
+		{Key: "pause_ns", Value: u(ms.PauseTotalNs)},
+	}
+}
+
+// RuntimeStats starts a goroutine emitting a "runtime stats" INFO record
+// every interval, the statistics are attached as header fields (see
+// runtimeStatsFields for the names). it stops when ctx is done or stop is
+// called, stop waits for the goroutine to exit. nothing is emitted if every
+// is not positive.
+func (l *logger) RuntimeStats(
+	ctx context.Context, every time.Duration,
+) (stop func()) {
+	if every <= 0 {
+		return func() {}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if l.enabled(INFO) {
+					fields := fieldsString(runtimeStatsFields(), nil)
+					header := l.getFmtHeader()(INFO, nil, fields)
+					l.logHandler.RegularLog(INFO, header, "runtime stats")
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			cancel()
+			<-done
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package nekomimi
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRuntimeStats(t *testing.T) {
+	Convey("Runtime stats tests", t, func() {
+		var mtx sync.Mutex
+		var recs []Record
+		count := func() int {
+			mtx.Lock()
+			defer mtx.Unlock()
+			return len(recs)
+		}
+		l := New("App", LogConfig{
+			Handler: RecordLogHandlerFunc(func(rec Record) {
+				mtx.Lock()
+				defer mtx.Unlock()
+				recs = append(recs, rec)
+			}),
+			LevelWithTrace: FATAL,
+		})
+
+		Convey("Stats records are emitted on the interval", func() {
+			stop := l.RuntimeStats(context.Background(), 10*time.Millisecond)
+			So(waitFor(func() bool { return count() >= 1 }), ShouldBeTrue)
+			stop()
+			stop() // idempotent
+			n := count()
+			time.Sleep(30 * time.Millisecond)
+			So(count(), ShouldEqual, n)
+
+			rec := recs[0]
+			So(rec.Level, ShouldEqual, INFO)
+			So(rec.Message, ShouldEqual, "runtime stats")
+			keys := []string{}
+			for _, f := range rec.Fields {
+				keys = append(keys, f.Key)
+			}
+			So(keys, ShouldResemble, []string{
+				"alloc", "sys", "heap_inuse", "heap_objs",
+				"goroutines", "num_gc", "pause_ns",
+			})
+		})
+
+		Convey("Cancelled context stops the emitter", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			stop := l.RuntimeStats(ctx, 10*time.Millisecond)
+			cancel()
+			stop()
+			time.Sleep(30 * time.Millisecond)
+			So(count(), ShouldEqual, 0)
+		})
+	})
+}
@@ -0,0 +1,104 @@
+package nekomimi
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+// samplingHandler is the LogHandler returned by NewSamplingHandler
+type samplingHandler struct {
+	wrap LogHandler
+	// per regular level from TRACE: N of the level (1 keeps all) and the
+	// messages received at the level
+	every [PANIC - TRACE]uint64
+	count [PANIC - TRACE]atomic.Uint64
+
+	skipped atomic.Uint64
+}
+
+// NewSamplingHandler creates a LogHandler which forwards only 1 of every N
+// regular log messages of a level to wrapped: the 1st, the N+1th, the
+// 2N+1th... and drops the others silently (they are counted, see
+// DropCounter). N is everyN for all levels, perLevel overrides it for the
+// given levels, e.g. `{ERROR: 1, DEBUG: 100}` keeps all errors and 1% of
+// the debug messages. N below 2 keeps all messages of the level. panic and
+// fatal messages are never sampled out.
+//
+// each level has its own atomic counter, starting at the creation of the
+// handler and never reset, so the sampling is deterministic regardless of
+// the time between the messages. see NewAdaptiveLimitLogHandler for the
+// sampling by rate.
+func NewSamplingHandler(
+	wrapped LogHandler, everyN int, perLevel map[LogLevel]int,
+) LogHandler {
+	sh := &samplingHandler{wrap: wrapped}
+	for lv := range sh.every {
+		n, ok := perLevel[TRACE+LogLevel(lv)]
+		if !ok {
+			n = everyN
+		}
+		sh.every[lv] = uint64(max(n, 1))
+	}
+	return sh
+}
+
+// allow reports whether a message of the level should be forwarded
+func (sh *samplingHandler) allow(level LogLevel) bool {
+	i := level - TRACE
+	if level >= PANIC || sh.every[i] == 1 {
+		return true
+	}
+	return (sh.count[i].Add(1)-1)%sh.every[i] == 0
+}
+
+// SamplingRatio returns the ratio of the DEBUG messages kept
+func (sh *samplingHandler) SamplingRatio() float64 {
+	return 1 / float64(sh.every[DEBUG-TRACE])
+}
+
+// Dropped returns the number of messages dropped by sampling
+func (sh *samplingHandler) Dropped() uint64 {
+	return sh.skipped.Load()
+}
+
+func (sh *samplingHandler) IsShutdown() bool {
+	return sh.wrap.IsShutdown()
+}
+
+func (sh *samplingHandler) RegularWriter(
+	level LogLevel, pnt func(io.StringWriter),
+) {
+	if !sh.allow(level) {
+		sh.skipped.Add(1)
+		return
+	}
+	sh.wrap.RegularWriter(level, pnt)
+}
+
+func (sh *samplingHandler) RegularLog(
+	level LogLevel, header string, message ...any,
+) {
+	if !sh.allow(level) {
+		sh.skipped.Add(1)
+		return
+	}
+	sh.wrap.RegularLog(level, header, message...)
+}
+
+func (sh *samplingHandler) PanicLog(header string, message ...any) {
+	sh.wrap.PanicLog(header, message...)
+}
+
+func (sh *samplingHandler) FatalLog(header string, message ...any) {
+	sh.wrap.FatalLog(header, message...)
+}
+
+// Flush flushes the wrapped handler
+func (sh *samplingHandler) Flush() error {
+	return flushHandler(sh.wrap)
+}
+
+// Close closes the wrapped handler
+func (sh *samplingHandler) Close() error {
+	return closeHandler(sh.wrap)
+}
@@ -0,0 +1,58 @@
+package nekomimi
+
+import (
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSamplingHandler(t *testing.T) {
+	Convey("Sampling handler tests", t, func() {
+		sink := &captureLogHandler{}
+		h := NewSamplingHandler(sink.handler(), 10,
+			map[LogLevel]int{ERROR: 1, DEBUG: 100})
+		l := New("App", LogConfig{Handler: h, TestMode: true})
+
+		Convey("One of every N per level", func() {
+			for i := range 25 {
+				l.Warf("warn %d", i)
+			}
+			So(sink.count(), ShouldEqual, 3)
+			So(sink.lines[0], ShouldEndWith, "- warn 0\n")
+			So(sink.lines[1], ShouldEndWith, "- warn 10\n")
+			So(sink.lines[2], ShouldEndWith, "- warn 20\n")
+
+			sink.reset()
+			for range 200 {
+				l.Dbg("debug")
+				l.Err("error")
+			}
+			So(sink.count(), ShouldEqual, 202)
+			So(h.(DropCounter).Dropped(), ShouldEqual, 22+198)
+			So(h.(SamplingReporter).SamplingRatio(), ShouldEqual, 0.01)
+		})
+
+		Convey("Panic is never sampled out", func() {
+			for range 5 {
+				l.Panic("boom")
+			}
+			So(sink.count(), ShouldEqual, 5)
+		})
+
+		Convey("Counters are safe for concurrent use", func() {
+			wg := sync.WaitGroup{}
+			for range 10 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for range 100 {
+						l.Inf("info")
+					}
+				}()
+			}
+			wg.Wait()
+			So(sink.count(), ShouldEqual, 100)
+		})
+	})
+}
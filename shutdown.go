@@ -0,0 +1,118 @@
+package nekomimi
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// flushWatchKey identifies a registration of FlushOnContextDone
+type flushWatchKey struct {
+	ctx context.Context
+	l   Logger
+}
+
+// flushWatchers holds the active registrations of FlushOnContextDone
+var flushWatchers sync.Map
+
+// FlushOnContextDone spawns a goroutine which flushes and closes the
+// handler chain of the logger when ctx is cancelled. handlers which
+// implement Flusher are flushed, then handlers which implement io.Closer
+// are closed. LogHandlerFunc walks its Wrapper for both.
+//
+// it's safe to call multiple times with the same context and logger, only
+// one goroutine is spawned. the goroutine exits after the shutdown.
+func FlushOnContextDone(ctx context.Context, l Logger) {
+	key := flushWatchKey{ctx: ctx, l: l}
+	if _, loaded := flushWatchers.LoadOrStore(key, struct{}{}); loaded {
+		return
+	}
+	go func() {
+		defer flushWatchers.Delete(key)
+		<-ctx.Done()
+		shutdownLogger(l)
+	}()
+}
+
+// exitFlushers holds the loggers registered by RegisterFlushOnExit
+var exitFlushers struct {
+	mtx     sync.Mutex
+	loggers []Logger
+}
+
+// RegisterFlushOnExit registers the logger to be flushed by FlushAll. Go
+// runs nothing at a normal exit, so the program should defer FlushAll in
+// main (and optionally call FlushOnSignal), otherwise the last buffered
+// messages might be lost. registering a logger again has no effect.
+func RegisterFlushOnExit(l Logger) {
+	exitFlushers.mtx.Lock()
+	defer exitFlushers.mtx.Unlock()
+	for _, r := range exitFlushers.loggers {
+		if r == l {
+			return
+		}
+	}
+	exitFlushers.loggers = append(exitFlushers.loggers, l)
+}
+
+// FlushAll flushes the handler chains of the loggers registered by
+// RegisterFlushOnExit, handlers which implement Flusher are flushed. the
+// handlers are not closed, the loggers are still usable.
+//
+//	func main() {
+//		nekomimi.RegisterFlushOnExit(logger)
+//		defer nekomimi.FlushAll()
+//		...
+//	}
+func FlushAll() error {
+	exitFlushers.mtx.Lock()
+	loggers := append([]Logger(nil), exitFlushers.loggers...)
+	exitFlushers.mtx.Unlock()
+	var errs []error
+	for _, l := range loggers {
+		errs = append(errs, l.Flush())
+	}
+	return errors.Join(errs...)
+}
+
+// FlushOnSignal spawns a goroutine which calls FlushAll when one of the
+// signals is received, then restores the default behavior of the signal
+// and raises it again, so the program terminates as it would without the
+// handler. the returned function stops watching the signals.
+func FlushOnSignal(sigs ...os.Signal) (stop func()) {
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, sigs...)
+	go func() {
+		select {
+		case sig := <-ch:
+			FlushAll()
+			signal.Reset(sig)
+			if p, err := os.FindProcess(os.Getpid()); err == nil {
+				p.Signal(sig)
+			}
+		case <-done:
+		}
+	}()
+	once := sync.Once{}
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
+
+// shutdownLogger flushes and closes the handler of the logger
+func shutdownLogger(l Logger) error {
+	lg, ok := l.(*logger)
+	if !ok {
+		return nil
+	}
+	lg.mtx.RLock()
+	h := lg.logHandler
+	lg.mtx.RUnlock()
+	return errors.Join(flushHandler(h), closeHandler(h))
+}
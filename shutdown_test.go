@@ -0,0 +1,117 @@
+package nekomimi
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFlushOnContextDone(t *testing.T) {
+	Convey("FlushOnContextDone tests", t, func() {
+		var flushed, closed, innerFlushed atomic.Int32
+		inner := &LogHandlerFunc{
+			RegularLogFunc: func(LogLevel, func(io.StringWriter)) {},
+			FlushFunc: func() error {
+				innerFlushed.Add(1)
+				return nil
+			},
+		}
+		h := &LogHandlerFunc{
+			RegularLogFunc: func(LogLevel, func(io.StringWriter)) {},
+			FlushFunc: func() error {
+				flushed.Add(1)
+				return nil
+			},
+			CloseFunc: func() error {
+				closed.Add(1)
+				return nil
+			},
+			Wrapper: inner,
+		}
+		l := New("Test", LogConfig{Handler: h})
+
+		Convey("Flushes and closes the chain on cancel", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			FlushOnContextDone(ctx, l)
+			FlushOnContextDone(ctx, l) // idempotent
+			So(flushed.Load(), ShouldEqual, 0)
+			cancel()
+			So(waitFor(func() bool { return closed.Load() > 0 }), ShouldBeTrue)
+			time.Sleep(20 * time.Millisecond)
+			So(flushed.Load(), ShouldEqual, 1)
+			So(innerFlushed.Load(), ShouldEqual, 1)
+			So(closed.Load(), ShouldEqual, 1)
+		})
+
+		Convey("Close takes effect once", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			FlushOnContextDone(ctx, l)
+			So(waitFor(func() bool { return closed.Load() > 0 }), ShouldBeTrue)
+			FlushOnContextDone(ctx, l.Derive("sub"))
+			So(waitFor(func() bool { return flushed.Load() == 2 }), ShouldBeTrue)
+			So(closed.Load(), ShouldEqual, 1)
+		})
+	})
+}
+
+func TestFlushAll(t *testing.T) {
+	Convey("FlushAll tests", t, func() {
+		defer func() { exitFlushers.loggers = nil }()
+		sink1, sink2 := &captureLogHandler{}, &captureLogHandler{}
+		h1, stop1 := NewAsyncLogHandler(sink1.handler(), 1000)
+		defer stop1()
+		h2, stop2 := NewAsyncLogHandler(sink2.handler(), 1000)
+		defer stop2()
+		l1 := New("One", LogConfig{Handler: h1})
+		l2 := New("Two", LogConfig{Handler: h2})
+		var flushed atomic.Int32
+		l3 := New("Three", LogConfig{Handler: &LogHandlerFunc{
+			RegularLogFunc: func(LogLevel, func(io.StringWriter)) {},
+			FlushFunc: func() error {
+				flushed.Add(1)
+				return nil
+			},
+		}})
+		RegisterFlushOnExit(l1)
+		RegisterFlushOnExit(l2)
+		RegisterFlushOnExit(l3)
+		RegisterFlushOnExit(l3) // registered once
+		So(len(exitFlushers.loggers), ShouldEqual, 3)
+
+		for i := range 200 {
+			l1.Inff("one %d", i)
+			l2.Inff("two %d", i)
+		}
+		So(FlushAll(), ShouldBeNil)
+		So(sink1.count(), ShouldEqual, 200)
+		So(sink2.count(), ShouldEqual, 200)
+		So(flushed.Load(), ShouldEqual, 1)
+
+		// loggers are still usable
+		l1.Inf("after flush")
+		So(FlushAll(), ShouldBeNil)
+		So(sink1.last(), ShouldEndWith, "- after flush\n")
+
+		stop := FlushOnSignal(os.Interrupt)
+		stop()
+		stop() // no effect
+	})
+}
+
+// waitFor polls cond until it returns true or a second elapses
+func waitFor(cond func() bool) bool {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return cond()
+}
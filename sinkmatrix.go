@@ -0,0 +1,194 @@
+package nekomimi
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// sinkRule sends the messages from minLevel to maxLevel to the handler
+type sinkRule struct {
+	minLevel LogLevel
+	maxLevel LogLevel
+	handler  LogHandler
+}
+
+// matches reports whether the rule covers the level
+func (r sinkRule) matches(level LogLevel) bool {
+	return level >= r.minLevel && level <= r.maxLevel
+}
+
+// SinkMatrix builds a LogHandler routing each message to all the sinks
+// registered for its level, see NewSinkMatrix
+type SinkMatrix struct {
+	rules    []sinkRule
+	fallback LogHandler
+}
+
+// NewSinkMatrix creates an empty SinkMatrix. register the sinks with Add,
+// then build the router by Handler:
+//
+//	handler := nekomimi.NewSinkMatrix().
+//		Add(nekomimi.TRACE, nekomimi.FATAL, fileHandler).
+//		Add(nekomimi.INFO, nekomimi.FATAL, consoleHandler).
+//		Add(nekomimi.ERROR, nekomimi.FATAL, alertHandler).
+//		Default(consoleHandler).
+//		Handler()
+func NewSinkMatrix() *SinkMatrix {
+	return &SinkMatrix{}
+}
+
+// Add registers the handler for the messages from minLevel to maxLevel
+// (both inclusive), returns the matrix for chaining. a handler registered
+// by several overlapping rules receives the message several times.
+func (m *SinkMatrix) Add(minLevel, maxLevel LogLevel, h LogHandler) *SinkMatrix {
+	m.rules = append(m.rules, sinkRule{
+		minLevel: minLevel,
+		maxLevel: maxLevel,
+		handler:  h,
+	})
+	return m
+}
+
+// Default registers the catch-all handler, which receives the messages
+// not covered by any rule, returns the matrix for chaining. the last call
+// takes effect.
+func (m *SinkMatrix) Default(h LogHandler) *SinkMatrix {
+	m.fallback = h
+	return m
+}
+
+// Handler builds the router of the registered rules. the following Add and
+// Default calls don't affect the built router.
+//
+// regular messages are sent to all the matching sinks, in the order of
+// registration, messages without a matching sink are sent to the Default
+// handler, or dropped if none. for panic and fatal messages, the last
+// matching sink receives PanicLog/FatalLog and the others receive the
+// message by RegularWriter before it, so they all get the message before
+// the program dies. without any sink for them, the router still panics or
+// terminates the program as the native handler does. the router implements
+// Flusher and io.Closer, applied to all the sinks.
+func (m *SinkMatrix) Handler() LogHandler {
+	return &sinkRouter{
+		rules:    append([]sinkRule(nil), m.rules...),
+		fallback: m.fallback,
+	}
+}
+
+// sinkRouter is the LogHandler built by SinkMatrix.Handler
+type sinkRouter struct {
+	rules    []sinkRule
+	fallback LogHandler // receives the messages without a matching rule
+}
+
+// sinks returns the handlers of the rules and the Default handler
+func (sr *sinkRouter) sinks() []LogHandler {
+	hs := make([]LogHandler, 0, len(sr.rules)+1)
+	for _, r := range sr.rules {
+		hs = append(hs, r.handler)
+	}
+	if sr.fallback != nil {
+		hs = append(hs, sr.fallback)
+	}
+	return hs
+}
+
+func (sr *sinkRouter) IsShutdown() bool {
+	for _, h := range sr.sinks() {
+		if !h.IsShutdown() {
+			return false
+		}
+	}
+	return true
+}
+
+func (sr *sinkRouter) RegularWriter(
+	level LogLevel, pnt func(io.StringWriter),
+) {
+	matched := false
+	for _, r := range sr.rules {
+		if r.matches(level) {
+			r.handler.RegularWriter(level, pnt)
+			matched = true
+		}
+	}
+	if !matched && sr.fallback != nil {
+		sr.fallback.RegularWriter(level, pnt)
+	}
+}
+
+func (sr *sinkRouter) RegularLog(
+	level LogLevel, header string, message ...any,
+) {
+	sp := fmt.Sprintln(message...)
+	sr.RegularWriter(level, func(w io.StringWriter) {
+		w.WriteString(header)
+		w.WriteString(sp)
+	})
+}
+
+// terminalLog sends the panic or fatal message by RegularWriter to the
+// matching sinks but the last one, which is returned. returns the Default
+// handler if no sink matches, nil if there is none.
+func (sr *sinkRouter) terminalLog(
+	level LogLevel, header string, message ...any,
+) LogHandler {
+	var last LogHandler
+	var pnt func(io.StringWriter)
+	for _, r := range sr.rules {
+		if !r.matches(level) {
+			continue
+		}
+		if last != nil {
+			if pnt == nil {
+				sp := fmt.Sprintln(message...)
+				pnt = func(w io.StringWriter) {
+					w.WriteString(header)
+					w.WriteString(sp)
+				}
+			}
+			last.RegularWriter(level, pnt)
+		}
+		last = r.handler
+	}
+	if last == nil {
+		return sr.fallback
+	}
+	return last
+}
+
+func (sr *sinkRouter) PanicLog(header string, message ...any) {
+	last := sr.terminalLog(PANIC, header, message...)
+	if last == nil {
+		panic(fmt.Sprintln(message...))
+	}
+	last.PanicLog(header, message...)
+}
+
+func (sr *sinkRouter) FatalLog(header string, message ...any) {
+	last := sr.terminalLog(FATAL, header, message...)
+	if last == nil {
+		sysTerminate()
+		return
+	}
+	last.FatalLog(header, message...)
+}
+
+// Flush flushes all the sinks
+func (sr *sinkRouter) Flush() error {
+	var errs []error
+	for _, h := range sr.sinks() {
+		errs = append(errs, flushHandler(h))
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes all the sinks
+func (sr *sinkRouter) Close() error {
+	var errs []error
+	for _, h := range sr.sinks() {
+		errs = append(errs, closeHandler(h))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,98 @@
+package nekomimi
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSinkMatrix(t *testing.T) {
+	Convey("Sink matrix tests", t, func() {
+		file, console, alert := &captureLogHandler{}, &captureLogHandler{}, &captureLogHandler{}
+		m := NewSinkMatrix().
+			Add(TRACE, FATAL, file.handler()).
+			Add(INFO, FATAL, console.handler()).
+			Add(ERROR, FATAL, alert.handler())
+		l := New("App", LogConfig{Handler: m.Handler(), TestMode: true})
+
+		Convey("Records hit the matching sinks", func() {
+			l.Dbg("debug")
+			So(file.count(), ShouldEqual, 1)
+			So(console.count(), ShouldEqual, 0)
+			So(alert.count(), ShouldEqual, 0)
+
+			l.War("warn")
+			So(file.count(), ShouldEqual, 2)
+			So(console.count(), ShouldEqual, 1)
+			So(alert.count(), ShouldEqual, 0)
+
+			l.Err("error")
+			for _, c := range []*captureLogHandler{file, console, alert} {
+				So(c.last(), ShouldEqual,
+					"2000-01-01 00:00:00.000 [ERROR], App - error\n")
+			}
+			So(alert.count(), ShouldEqual, 1)
+
+			l.Panic("panic")
+			So(file.levels[3], ShouldEqual, PANIC)
+			So(console.last(), ShouldEndWith, "[PANIC], App - panic\n")
+			So(alert.last(), ShouldEndWith, "[PANIC], App - panic\n")
+		})
+
+		Convey("Rules don't overlap", func() {
+			low, high := &captureLogHandler{}, &captureLogHandler{}
+			h := NewSinkMatrix().
+				Add(DEBUG, INFO, low.handler()).
+				Add(WARN, ERROR, high.handler()).
+				Handler()
+			l := New("App", LogConfig{Handler: h, TestMode: true})
+			l.Trc("dropped")
+			l.Inf("low")
+			l.Err("high")
+			So(low.levels, ShouldResemble, []LogLevel{INFO})
+			So(high.levels, ShouldResemble, []LogLevel{ERROR})
+			So(h.IsShutdown(), ShouldBeFalse)
+		})
+
+		Convey("Default receives the messages without a rule", func() {
+			low, other := &captureLogHandler{}, &captureLogHandler{}
+			h := NewSinkMatrix().
+				Add(DEBUG, INFO, low.handler()).
+				Default(other.handler()).
+				Handler()
+			l := New("App", LogConfig{Handler: h, TestMode: true})
+			l.SetLevel(TRACE)
+			l.Trc("trace")
+			l.Inf("low")
+			l.Err("error")
+			l.Panic("panic")
+			So(low.levels, ShouldResemble, []LogLevel{INFO})
+			So(other.levels, ShouldResemble, []LogLevel{TRACE, ERROR, PANIC})
+			So(other.last(), ShouldEndWith, "[PANIC], App - panic\n")
+		})
+
+		Convey("Panic and fatal without a sink still terminate", func() {
+			sink := &captureLogHandler{}
+			h := NewSinkMatrix().Add(TRACE, ERROR, sink.handler()).Handler()
+			l := New("App", LogConfig{Handler: h, TestMode: true})
+			So(func() { l.Panic("boom") }, ShouldPanicWith, "boom\n")
+
+			terminated := false
+			backupTm := sysTerminate
+			defer func() { sysTerminate = backupTm }()
+			sysTerminate = func() { terminated = true }
+			l.Fatal("fatal")
+			So(terminated, ShouldBeTrue)
+			So(sink.count(), ShouldEqual, 0)
+		})
+
+		Convey("Built router is not affected by later rules", func() {
+			h := m.Handler()
+			other := &captureLogHandler{}
+			m.Add(TRACE, FATAL, other.handler()).Default(other.handler())
+			h.RegularLog(INFO, "header - ", "message")
+			So(other.count(), ShouldEqual, 0)
+			So(console.last(), ShouldEqual, "header - message\n")
+		})
+	})
+}
@@ -0,0 +1,60 @@
+package nekomimi
+
+import (
+	"io"
+	"log"
+	"strings"
+)
+
+// lineWriter is the io.Writer returned by AsWriter
+type lineWriter struct {
+	l     Logger
+	level LogLevel
+	// frames between Write and the reported caller
+	skip int
+}
+
+// AsWriter returns an io.Writer which logs the written data with l at
+// level, one log call per line. the trailing newline is trimmed and empty
+// lines are skipped. each Write is handled on its own, a line without the
+// trailing newline is logged as well, so it suits the writers writing whole
+// lines (e.g. a standard logger). PANIC and FATAL raise panic and terminate
+// the program as the Logger methods do.
+func AsWriter(l Logger, level LogLevel) io.Writer {
+	return &lineWriter{l: l, level: level, skip: 1}
+}
+
+// StdLogger returns a standard *log.Logger which logs with l at level, for
+// the libraries taking a *log.Logger, e.g. the ErrorLog of http.Server.
+// the standard logger has no prefix and flags, the nekomimi header is used.
+// the call trace reports the caller of the standard logger.
+func StdLogger(l Logger, level LogLevel) *log.Logger {
+	// Write <- log.(*Logger).output <- log.(*Logger).Printf <- caller
+	return log.New(&lineWriter{l: l, level: level, skip: 3}, "", 0)
+}
+
+func (lw *lineWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(string(p), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			continue
+		}
+		switch {
+		case lw.level <= TRACE:
+			lw.l.TrcSkip(lw.skip, line)
+		case lw.level == DEBUG:
+			lw.l.DbgSkip(lw.skip, line)
+		case lw.level == INFO:
+			lw.l.InfSkip(lw.skip, line)
+		case lw.level == WARN:
+			lw.l.WarSkip(lw.skip, line)
+		case lw.level == ERROR:
+			lw.l.ErrSkip(lw.skip, line)
+		case lw.level == PANIC:
+			lw.l.Panic(line)
+		default:
+			lw.l.Fatal(line)
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,53 @@
+package nekomimi
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestStdLogAdapters(t *testing.T) {
+	Convey("Standard log adapter tests", t, func() {
+		rec := &captureLogHandler{}
+		l := New("Lib", LogConfig{Handler: rec.handler(), TestMode: true})
+
+		Convey("Writer logs one message per line", func() {
+			w := AsWriter(l, WARN)
+			n, err := w.Write([]byte("first\r\n\nsecond\nthird"))
+			So(err, ShouldBeNil)
+			So(n, ShouldEqual, len("first\r\n\nsecond\nthird"))
+			So(rec.levels, ShouldResemble, []LogLevel{WARN, WARN, WARN})
+			So(rec.lines, ShouldResemble, []string{
+				"2000-01-01 00:00:00.000 [WARN], Lib - first\n",
+				"2000-01-01 00:00:00.000 [WARN], Lib - second\n",
+				"2000-01-01 00:00:00.000 [WARN], Lib - third\n",
+			})
+
+			l.SetLevel(TRACE)
+			fmt.Fprintf(AsWriter(l, TRACE), "trace\n")
+			fmt.Fprintf(AsWriter(l, PANIC), "panic\n") // capture doesn't panic
+			So(rec.levels[3:], ShouldResemble, []LogLevel{TRACE, PANIC})
+
+			l.SetLevel(ERROR)
+			fmt.Fprintln(AsWriter(l, INFO), "filtered")
+			So(rec.count(), ShouldEqual, 5)
+		})
+
+		Convey("Standard logger reports its caller", func() {
+			tl := New("Lib", LogConfig{Handler: rec.handler()})
+			std := StdLogger(tl, ERROR)
+			_, _, line, _ := runtime.Caller(0)
+			std.Printf("http: TLS handshake error from %s", "1.2.3.4")
+			So(rec.levels, ShouldResemble, []LogLevel{ERROR})
+			So(rec.last(), ShouldContainSubstring,
+				fmt.Sprintf("stdlog_test.go:%d(", line+1))
+			So(rec.last(), ShouldEndWith,
+				" - http: TLS handshake error from 1.2.3.4\n")
+			std.Println("done")
+			So(rec.last(), ShouldContainSubstring,
+				fmt.Sprintf("stdlog_test.go:%d(", line+7))
+		})
+	})
+}
@@ -0,0 +1,232 @@
+package nekomimi
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// throttleState is the window state of a ThrottleSummary key
+type throttleState struct {
+	mtx    sync.Mutex
+	active bool
+	count  int
+	level  LogLevel
+}
+
+// throttleLogger is the BasicLogger returned by ThrottleSummary
+type throttleLogger struct {
+	parent *logger
+	key    string
+	every  time.Duration
+	state  *throttleState
+}
+
+// ThrottleSummary returns a BasicLogger throttling the messages of the key.
+// the first message opens a window of the interval and is output
+// immediately, the following messages within the window are counted and
+// suppressed. when the window expires, a summary is output at the level of
+// the first message if any message was suppressed:
+//
+//	db-timeout occurred 42 times in the last 1m0s
+//
+// the states of the keys are shared by the derived loggers, so the
+// returned logger doesn't have to be kept, e.g.
+// `l.ThrottleSummary("db-timeout", time.Minute).Err(err)`.
+func (l *logger) ThrottleSummary(key string, every time.Duration) BasicLogger {
+	st, _ := l.throttles.LoadOrStore(key, &throttleState{})
+	return &throttleLogger{
+		parent: l,
+		key:    key,
+		every:  every,
+		state:  st.(*throttleState),
+	}
+}
+
+// allow reports whether the message should be output, i.e. it's the first
+// one of a window
+func (tl *throttleLogger) allow(level LogLevel) bool {
+	st := tl.state
+	st.mtx.Lock()
+	defer st.mtx.Unlock()
+	st.count++
+	if st.active {
+		return false
+	}
+	st.active = true
+	st.count = 1
+	st.level = level
+	time.AfterFunc(tl.every, tl.summary)
+	return true
+}
+
+// summary closes the window and outputs the summary message
+func (tl *throttleLogger) summary() {
+	st := tl.state
+	st.mtx.Lock()
+	count, level := st.count, st.level
+	st.active = false
+	st.count = 0
+	st.mtx.Unlock()
+	if count <= 1 || !tl.parent.enabled(level) {
+		return
+	}
+	// the summary is output by the timer, the call trace is meaningless
+	tl.parent.mtx.RLock()
+	opts := tl.parent.headerOptions()
+	tl.parent.mtx.RUnlock()
+	opts.levelcalltrace = FATAL + 1
+	opts.withStack = false
+	header := getHeaderFormatter(opts, 4)(level, nil, "")
+	tl.parent.logHandler.RegularLog(level, header,
+		tl.key, "occurred", count, "times in the last", tl.every)
+}
+
+// ------- implement BasicLogger interface for throttleLogger -------
+
+func (tl *throttleLogger) Trc(message ...any) {
+	if tl.parent.enabled(TRACE) && tl.allow(TRACE) {
+		tl.parent.outputRegularLog(TRACE, message...)
+	}
+}
+
+func (tl *throttleLogger) Trcf(format string, args ...any) {
+	if tl.parent.enabled(TRACE) && tl.allow(TRACE) {
+		tl.parent.outputRegularLog(TRACE, tl.parent.sprintf(format, args...))
+	}
+}
+
+func (tl *throttleLogger) TrcP() func(message ...any) {
+	if tl.parent.enabled(TRACE) {
+		return func(message ...any) {
+			if tl.allow(TRACE) {
+				tl.parent.outputRegularLog(TRACE, message...)
+			}
+		}
+	}
+	return nil
+}
+
+func (tl *throttleLogger) Dbg(message ...any) {
+	if tl.parent.enabled(DEBUG) && tl.allow(DEBUG) {
+		tl.parent.outputRegularLog(DEBUG, message...)
+	}
+}
+
+func (tl *throttleLogger) Dbgf(format string, args ...any) {
+	if tl.parent.enabled(DEBUG) && tl.allow(DEBUG) {
+		tl.parent.outputRegularLog(DEBUG, tl.parent.sprintf(format, args...))
+	}
+}
+
+func (tl *throttleLogger) DbgP() func(message ...any) {
+	if tl.parent.enabled(DEBUG) {
+		return func(message ...any) {
+			if tl.allow(DEBUG) {
+				tl.parent.outputRegularLog(DEBUG, message...)
+			}
+		}
+	}
+	return nil
+}
+
+func (tl *throttleLogger) Inf(message ...any) {
+	if tl.parent.enabled(INFO) && tl.allow(INFO) {
+		tl.parent.outputRegularLog(INFO, message...)
+	}
+}
+
+func (tl *throttleLogger) Inff(format string, args ...any) {
+	if tl.parent.enabled(INFO) && tl.allow(INFO) {
+		tl.parent.outputRegularLog(INFO, tl.parent.sprintf(format, args...))
+	}
+}
+
+func (tl *throttleLogger) InfP() func(message ...any) {
+	if tl.parent.enabled(INFO) {
+		return func(message ...any) {
+			if tl.allow(INFO) {
+				tl.parent.outputRegularLog(INFO, message...)
+			}
+		}
+	}
+	return nil
+}
+
+func (tl *throttleLogger) War(message ...any) {
+	if tl.parent.enabled(WARN) && tl.allow(WARN) {
+		tl.parent.outputRegularLog(WARN, message...)
+	}
+}
+
+func (tl *throttleLogger) Warf(format string, args ...any) {
+	if tl.parent.enabled(WARN) && tl.allow(WARN) {
+		tl.parent.outputRegularLog(WARN, tl.parent.sprintf(format, args...))
+	}
+}
+
+func (tl *throttleLogger) WarP() func(message ...any) {
+	if tl.parent.enabled(WARN) {
+		return func(message ...any) {
+			if tl.allow(WARN) {
+				tl.parent.outputRegularLog(WARN, message...)
+			}
+		}
+	}
+	return nil
+}
+
+func (tl *throttleLogger) Err(message ...any) {
+	if tl.parent.enabled(ERROR) && tl.allow(ERROR) {
+		tl.parent.outputRegularLog(ERROR, message...)
+	}
+}
+
+func (tl *throttleLogger) Errf(format string, args ...any) {
+	if tl.parent.enabled(ERROR) && tl.allow(ERROR) {
+		tl.parent.outputRegularLog(ERROR, tl.parent.sprintf(format, args...))
+	}
+}
+
+func (tl *throttleLogger) ErrP() func(message ...any) {
+	if tl.parent.enabled(ERROR) {
+		return func(message ...any) {
+			if tl.allow(ERROR) {
+				tl.parent.outputRegularLog(ERROR, message...)
+			}
+		}
+	}
+	return nil
+}
+
+func (tl *throttleLogger) TrcCtx(ctx context.Context, message ...any) {
+	if tl.parent.enabled(TRACE) && tl.allow(TRACE) {
+		tl.parent.outputContextLog(ctx, TRACE, message...)
+	}
+}
+
+func (tl *throttleLogger) DbgCtx(ctx context.Context, message ...any) {
+	if tl.parent.enabled(DEBUG) && tl.allow(DEBUG) {
+		tl.parent.outputContextLog(ctx, DEBUG, message...)
+	}
+}
+
+func (tl *throttleLogger) InfCtx(ctx context.Context, message ...any) {
+	if tl.parent.enabled(INFO) && tl.allow(INFO) {
+		tl.parent.outputContextLog(ctx, INFO, message...)
+	}
+}
+
+func (tl *throttleLogger) WarCtx(ctx context.Context, message ...any) {
+	if tl.parent.enabled(WARN) && tl.allow(WARN) {
+		tl.parent.outputContextLog(ctx, WARN, message...)
+	}
+}
+
+func (tl *throttleLogger) ErrCtx(ctx context.Context, message ...any) {
+	if tl.parent.enabled(ERROR) && tl.allow(ERROR) {
+		tl.parent.outputContextLog(ctx, ERROR, message...)
+	}
+}
+
+// --------------------------------------------------------------
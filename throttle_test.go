@@ -0,0 +1,54 @@
+package nekomimi
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestThrottleSummary(t *testing.T) {
+	Convey("ThrottleSummary tests", t, func() {
+		rec := &captureLogHandler{}
+		l := New("Test", LogConfig{
+			Handler:        rec.handler(),
+			LevelWithTrace: FATAL,
+		})
+		window := 50 * time.Millisecond
+
+		Convey("First occurrence and summary are emitted", func() {
+			for i := range 100 {
+				l.ThrottleSummary("db-timeout", window).Err("timeout", i)
+			}
+			So(rec.count(), ShouldEqual, 1)
+			So(rec.last(), ShouldEndWith, "[ERROR], Test - timeout 0\n")
+
+			So(waitFor(func() bool { return rec.count() == 2 }), ShouldBeTrue)
+			So(rec.last(), ShouldEndWith,
+				"[ERROR], Test - db-timeout occurred 100 times in the last 50ms\n")
+			time.Sleep(2 * window)
+			So(rec.count(), ShouldEqual, 2)
+
+			// a new window starts with the next occurrence
+			l.Derive("sub").ThrottleSummary("db-timeout", window).War("again")
+			So(rec.count(), ShouldEqual, 3)
+			So(rec.last(), ShouldEndWith, "[WARN], Test.sub - again\n")
+		})
+
+		Convey("No summary for a single occurrence", func() {
+			l.ThrottleSummary("once", window).Inf("single")
+			So(rec.count(), ShouldEqual, 1)
+			time.Sleep(2 * window)
+			So(rec.count(), ShouldEqual, 1)
+		})
+
+		Convey("Keys are throttled independently", func() {
+			a := l.ThrottleSummary("a", window)
+			b := l.ThrottleSummary("b", window)
+			a.Inf("a1")
+			b.Inf("b1")
+			a.Inf("a2")
+			So(rec.count(), ShouldEqual, 2)
+		})
+	})
+}